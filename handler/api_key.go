@@ -0,0 +1,356 @@
+/*
+ * Copyright (C) HereweTech, Inc - All Rights Reserved
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+/**
+ * @file api_key.go
+ * @package handler
+ * @author Dr.NP <np@herewe.tech>
+ * @since 10/18/2026
+ */
+
+package handler
+
+import (
+	"database/sql"
+	"errors"
+	"icepay-svc/handler/request"
+	"icepay-svc/handler/response"
+	"icepay-svc/model"
+	"icepay-svc/runtime"
+	"icepay-svc/service"
+	"icepay-svc/utils"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type APIKey struct {
+	svcAPIKey *service.APIKey
+}
+
+// InitAPIKey : mounts API key routes on router of tenant, authorization applied by parent group
+func InitAPIKey(router fiber.Router) *APIKey {
+	h := new(APIKey)
+
+	router.Post("/", requireScopes(service.ScopeAPIKeyWrite), h.add).Name("APIKeyPost")
+	router.Get("/list", requireScopes(service.ScopeAPIKeyRead), h.list).Name("APIKeyGetList")
+	router.Get("/:id", requireScopes(service.ScopeAPIKeyRead), h.get).Name("APIKeyGet")
+	router.Put("/:id", requireScopes(service.ScopeAPIKeyWrite), h.update).Name("APIKeyPut")
+	router.Delete("/:id", requireScopes(service.ScopeAPIKeyWrite), h.revoke).Name("APIKeyDelete")
+
+	h.svcAPIKey = service.NewAPIKey()
+
+	return h
+}
+
+/* {{{ [Routers] - Definitions */
+
+// add: Create API key
+
+// @Tags APIKey
+// @Summary Create API key
+// @Description 创建API key，供tenant后端服务直接调用/payment接口，无需登录和刷新JWT。请求头为Authorization: ApiKey {secret}，以tenant身份调用，可创建、查看订单及退款。secret仅在创建时返回一次。allowed_ips为IP或CIDR列表，非空时仅允许列表内的来源IP调用
+// @ID APIKeyPost
+// @Produce json
+// @Param data body request.APIKeyPost true "Input information"
+// @Success 201 {object} response.APIKeyPost
+// @Failure 422 string message
+// @Failure 400 {object} nil
+// @Failure 500 {object} nil
+// @Failure 403 {object} nil 角色无此权限
+// @Router /tenant/api-keys [post]
+func (h *APIKey) add(c *fiber.Ctx) error {
+	var req request.APIKeyPost
+	err := c.BodyParser(&req)
+	if err != nil {
+		runtime.Logger.Warnf("parse request body failed : %s", err)
+		c.SendStatus(fiber.StatusBadRequest)
+
+		return err
+	}
+
+	id, _ := c.Locals("AuthID").(string)
+	t, _ := c.Locals("AuthType").(string)
+	if id == "" || t != "tenant" {
+		resp := utils.WrapResponse(nil)
+		resp.Code = response.CodeAuthInformationMissing
+		resp.Message = response.MsgAuthInformationMissing
+		resp.Status = fiber.StatusBadRequest
+
+		return c.Status(fiber.StatusBadRequest).JSON(resp)
+	}
+
+	apiKey, secret, err := h.svcAPIKey.Create(c.Context(), &model.APIKey{
+		Tenant:     id,
+		Label:      req.Label,
+		AllowedIPs: req.AllowedIPs,
+	})
+	if err != nil {
+		return apiKeyFailed(c, err, response.CodeAPIKeyCreateFailed, response.MsgAPIKeyCreateFailed)
+	}
+
+	resp := utils.WrapResponse(&response.APIKeyPost{
+		APIKeyGet: *apiKeyGet(apiKey),
+		Secret:    secret,
+	})
+	resp.Status = fiber.StatusCreated
+
+	return c.Status(fiber.StatusCreated).JSON(resp)
+}
+
+// list: List API keys
+
+// @Tags APIKey
+// @Summary List API keys
+// @Description 获取当前tenant的API key列表，包括已撤销的，不含secret
+// @ID APIKeyGetList
+// @Produce json
+// @Success 200 {object} response.APIKeyGetList
+// @Failure 400 {object} nil
+// @Failure 500 {object} nil
+// @Failure 403 {object} nil 角色无此权限
+// @Router /tenant/api-keys/list [get]
+func (h *APIKey) list(c *fiber.Ctx) error {
+	id, _ := c.Locals("AuthID").(string)
+	t, _ := c.Locals("AuthType").(string)
+	if id == "" || t != "tenant" {
+		resp := utils.WrapResponse(nil)
+		resp.Code = response.CodeAuthInformationMissing
+		resp.Message = response.MsgAuthInformationMissing
+		resp.Status = fiber.StatusBadRequest
+
+		return c.Status(fiber.StatusBadRequest).JSON(resp)
+	}
+
+	ret, err := h.svcAPIKey.List(c.Context(), &model.APIKey{Tenant: id})
+	if err != nil {
+		return apiKeyFailed(c, err, response.CodeAPIKeyListFailed, response.MsgAPIKeyListFailed)
+	}
+
+	keys := &response.APIKeyGetList{
+		Total: len(ret),
+		List:  make([]*response.APIKeyGet, len(ret)),
+	}
+	for idx, apiKey := range ret {
+		keys.List[idx] = apiKeyGet(apiKey)
+	}
+
+	return c.JSON(utils.WrapResponse(keys))
+}
+
+// get: Get API key
+
+// @Tags APIKey
+// @Summary Get API key
+// @Description 获取API key信息，包括最近使用时间（精确到分钟）
+// @ID APIKeyGet
+// @Produce json
+// @Success 200 {object} response.APIKeyGet
+// @Failure 400 {object} nil
+// @Failure 404 {object} nil
+// @Failure 500 {object} nil
+// @Failure 403 {object} nil 角色无此权限
+// @Router /tenant/api-keys/{:id} [get]
+func (h *APIKey) get(c *fiber.Ctx) error {
+	id, _ := c.Locals("AuthID").(string)
+	t, _ := c.Locals("AuthType").(string)
+	if id == "" || t != "tenant" {
+		resp := utils.WrapResponse(nil)
+		resp.Code = response.CodeAuthInformationMissing
+		resp.Message = response.MsgAuthInformationMissing
+		resp.Status = fiber.StatusBadRequest
+
+		return c.Status(fiber.StatusBadRequest).JSON(resp)
+	}
+
+	apiKey, err := h.svcAPIKey.Get(c.Context(), &model.APIKey{
+		ID:     c.Params("id"),
+		Tenant: id,
+	})
+	if err != nil {
+		return apiKeyFailed(c, err, response.CodeAPIKeyGetFailed, response.MsgAPIKeyGetFailed)
+	}
+
+	return c.JSON(utils.WrapResponse(apiKeyGet(apiKey)))
+}
+
+// update: Update API key
+
+// @Tags APIKey
+// @Summary Update API key
+// @Description 更新API key的标签或IP白名单，allowed_ips提交时整体替换，空列表表示不限来源IP。已撤销的API key不可更新
+// @ID APIKeyPut
+// @Produce json
+// @Param data body request.APIKeyPut true "Input information"
+// @Success 200 {object} response.APIKeyGet
+// @Failure 422 string message
+// @Failure 400 {object} nil
+// @Failure 404 {object} nil
+// @Failure 500 {object} nil
+// @Failure 403 {object} nil 角色无此权限
+// @Router /tenant/api-keys/{:id} [put]
+func (h *APIKey) update(c *fiber.Ctx) error {
+	var req request.APIKeyPut
+	err := c.BodyParser(&req)
+	if err != nil {
+		runtime.Logger.Warnf("parse request body failed : %s", err)
+		c.SendStatus(fiber.StatusBadRequest)
+
+		return err
+	}
+
+	id, _ := c.Locals("AuthID").(string)
+	t, _ := c.Locals("AuthType").(string)
+	if id == "" || t != "tenant" {
+		resp := utils.WrapResponse(nil)
+		resp.Code = response.CodeAuthInformationMissing
+		resp.Message = response.MsgAuthInformationMissing
+		resp.Status = fiber.StatusBadRequest
+
+		return c.Status(fiber.StatusBadRequest).JSON(resp)
+	}
+
+	apiKey, err := h.svcAPIKey.Update(c.Context(), &model.APIKey{
+		ID:         c.Params("id"),
+		Tenant:     id,
+		Label:      req.Label,
+		AllowedIPs: req.AllowedIPs,
+	})
+	if err != nil {
+		return apiKeyFailed(c, err, response.CodeAPIKeyUpdateFailed, response.MsgAPIKeyUpdateFailed)
+	}
+
+	return c.JSON(utils.WrapResponse(apiKeyGet(apiKey)))
+}
+
+// revoke: Revoke API key
+
+// @Tags APIKey
+// @Summary Revoke API key
+// @Description 撤销API key，立即失效，不可恢复
+// @ID APIKeyDelete
+// @Produce json
+// @Success 200 {object} response.APIKeyGet
+// @Failure 400 {object} nil
+// @Failure 404 {object} nil
+// @Failure 500 {object} nil
+// @Failure 403 {object} nil 角色无此权限
+// @Router /tenant/api-keys/{:id} [delete]
+func (h *APIKey) revoke(c *fiber.Ctx) error {
+	id, _ := c.Locals("AuthID").(string)
+	t, _ := c.Locals("AuthType").(string)
+	if id == "" || t != "tenant" {
+		resp := utils.WrapResponse(nil)
+		resp.Code = response.CodeAuthInformationMissing
+		resp.Message = response.MsgAuthInformationMissing
+		resp.Status = fiber.StatusBadRequest
+
+		return c.Status(fiber.StatusBadRequest).JSON(resp)
+	}
+
+	apiKey, err := h.svcAPIKey.Revoke(c.Context(), &model.APIKey{
+		ID:     c.Params("id"),
+		Tenant: id,
+	})
+	if err != nil {
+		return apiKeyFailed(c, err, response.CodeAPIKeyRevokeFailed, response.MsgAPIKeyRevokeFailed)
+	}
+
+	return c.JSON(utils.WrapResponse(apiKeyGet(apiKey)))
+}
+
+/* }}} */
+
+/* {{{ *Internal handlers* */
+
+// apiKeyAuth : authorizes request carrying `Authorization: ApiKey <secret>` as the tenant of key, locals set as jwtSuccessHandler does.
+// Requests of other schemes passed to the JWT middleware following, which skips authorized ones by apiKeyAuthorized
+func apiKeyAuth(svcAPIKey *service.APIKey) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		auth := c.Get(fiber.HeaderAuthorization)
+		if len(auth) < 8 || strings.ToLower(auth[0:7]) != "apikey " {
+			return c.Next()
+		}
+
+		apiKey, err := svcAPIKey.Authenticate(c.Context(), strings.TrimSpace(auth[7:]), runtime.ClientIP(c))
+		if err != nil {
+			return apiKeyFailed(c, err, response.CodeAPIKeyAuthFailed, response.MsgAPIKeyAuthFailed)
+		}
+
+		c.Locals("AuthAPIKey", apiKey.ID)
+		c.Locals("AuthType", "tenant")
+		c.Locals("AuthID", apiKey.Tenant)
+		c.Locals("AuthRole", service.RoleTenantAPI)
+		c.Locals("AuthScopes", service.RoleScopes(service.RoleTenantAPI))
+
+		return c.Next()
+	}
+}
+
+// apiKeyAuthorized tells whether request authorized by API key
+func apiKeyAuthorized(c *fiber.Ctx) bool {
+	id, _ := c.Locals("AuthAPIKey").(string)
+
+	return id != ""
+}
+
+/* }}} */
+
+func apiKeyFailed(c *fiber.Ctx, err error, code int, msg string) error {
+	resp := utils.WrapResponse(nil)
+	switch {
+	case errors.Is(err, service.ErrAPIKeyInvalidIP):
+		resp.Code = response.CodeAPIKeyInvalidIP
+		resp.Message = response.MsgAPIKeyInvalidIP
+		resp.Status = fiber.StatusBadRequest
+	case errors.Is(err, service.ErrAPIKeyInvalid):
+		resp.Code = response.CodeAPIKeyInvalid
+		resp.Message = response.MsgAPIKeyInvalid
+		resp.Status = fiber.StatusUnauthorized
+	case errors.Is(err, service.ErrAPIKeyIPDenied):
+		runtime.Logger.Warnf("API key called from IP [%s] not allowed", runtime.ClientIP(c))
+		resp.Code = response.CodeAPIKeyIPDenied
+		resp.Message = response.MsgAPIKeyIPDenied
+		resp.Status = fiber.StatusForbidden
+	case errors.Is(err, sql.ErrNoRows), errors.Is(err, model.ErrAPIKeyDoesNotExists):
+		resp.Code = response.CodeAPIKeyDoesNotExists
+		resp.Message = response.MsgAPIKeyDoesNotExists
+		resp.Status = fiber.StatusNotFound
+	default:
+		runtime.Logger.Errorf("API key operation failed : %s", err)
+		resp.Code = code
+		resp.Message = msg
+		resp.Status = fiber.StatusInternalServerError
+	}
+
+	return c.Status(resp.Status).JSON(resp)
+}
+
+func apiKeyGet(apiKey *model.APIKey) *response.APIKeyGet {
+	allowed := apiKey.AllowedIPs
+	if allowed == nil {
+		allowed = []string{}
+	}
+
+	return &response.APIKeyGet{
+		ID:         apiKey.ID,
+		Label:      apiKey.Label,
+		KeyPrefix:  apiKey.KeyPrefix,
+		AllowedIPs: allowed,
+		Revoked:    !apiKey.RevokedAt.IsZero(),
+		LastUsedAt: apiKey.LastUsedAt,
+		CreatedAt:  apiKey.CreatedAt,
+	}
+}
+
+/*
+ * Local variables:
+ * tab-width: 4
+ * c-basic-offset: 4
+ * End:
+ * vim600: sw=4 ts=4 fdm=marker
+ * vim<600: sw=4 ts=4
+ */
@@ -28,23 +28,26 @@ import (
 )
 
 type Card struct {
+	svcAuth *service.Auth
 	svcCard *service.Card
 }
 
 func InitCreditCard() *Card {
 	h := new(Card)
 
+	h.svcAuth = service.NewAuth()
+
 	CardG := runtime.Server.Group("/card")
 	CardG.Use(jwtware.New(jwtware.Config{
-		SigningKey:     []byte(runtime.Config.Auth.JWTAccessSecret),
+		KeyFunc:        h.svcAuth.KeyFunc,
 		SuccessHandler: jwtSuccessHandler,
 		ErrorHandler:   jwtErrorHandler,
 	}))
-	CardG.Post("/", h.add).Name("CardPost")
-	CardG.Delete("/:id", h.delete).Name("CardDelete")
-	CardG.Get("/list", h.list).Name("CardGetList")
-	CardG.Get("/:id", h.get).Name("CardGet")
-	CardG.Put("/:id", h.update).Name("CardUpdate")
+	CardG.Post("/", requireScopes(service.ScopeCardWrite), h.add).Name("CardPost")
+	CardG.Delete("/:id", requireScopes(service.ScopeCardWrite), h.delete).Name("CardDelete")
+	CardG.Get("/list", requireScopes(service.ScopeCardRead), h.list).Name("CardGetList")
+	CardG.Get("/:id", requireScopes(service.ScopeCardRead), h.get).Name("CardGet")
+	CardG.Put("/:id", requireScopes(service.ScopeCardWrite), h.update).Name("CardUpdate")
 
 	h.svcCard = service.NewCard()
 
@@ -57,7 +60,7 @@ func InitCreditCard() *Card {
 
 // @Tags Card
 // @Summary Add bank card
-// @Description 添加银行卡，自动识别信用卡或借记卡(debit)。当返回的卡片类型不是借记卡时，可进一步调用update设置信用卡信息（持卡人、有效期、CVV）。
+// @Description 添加银行卡，自动识别信用卡或借记卡(debit)。currency为卡片结算币种（ISO 4217），缺省为默认币种，客户只能支付其卡片可结算币种的订单。当返回的卡片类型不是借记卡时，可进一步调用update设置信用卡信息（持卡人、有效期、CVV）。卡号加密保存，不再返回，仅返回token、BIN（前6位）和末4位
 // @ID CardPost
 // @Produce json
 // @Param data body request.CardPost true "Input information"
@@ -65,6 +68,8 @@ func InitCreditCard() *Card {
 // @Failure 422 string message
 // @Failure 400 {object} nil
 // @Failure 500 {object} nil
+// @Failure 409 {object} nil 卡片已添加
+// @Failure 403 {object} nil 角色无此权限
 // @Router /card [post]
 func (h *Card) add(c *fiber.Ctx) error {
 	var req request.CardPost
@@ -90,8 +95,35 @@ func (h *Card) add(c *fiber.Ctx) error {
 	card, err := h.svcCard.Create(c.Context(), &model.Card{
 		OwnerID:   id,
 		OwnerType: t,
-		Number:    req.Number,
-	})
+		Currency:  req.Currency,
+	}, req.Number)
+	if errors.Is(err, service.ErrCurrencyInvalid) {
+		resp := utils.WrapResponse(nil)
+		resp.Code = response.CodeCardInvalidCurrency
+		resp.Message = response.MsgCardInvalidCurrency
+		resp.Status = fiber.StatusBadRequest
+
+		return c.Status(fiber.StatusBadRequest).JSON(resp)
+	}
+
+	if errors.Is(err, service.ErrCardInvalidNumber) {
+		resp := utils.WrapResponse(nil)
+		resp.Code = response.CodeCardInvalidNumber
+		resp.Message = response.MsgCardInvalidNumber
+		resp.Status = fiber.StatusBadRequest
+
+		return c.Status(fiber.StatusBadRequest).JSON(resp)
+	}
+
+	if errors.Is(err, service.ErrCardExists) {
+		resp := utils.WrapResponse(nil)
+		resp.Code = response.CodeCardExists
+		resp.Message = response.MsgCardExists
+		resp.Status = fiber.StatusConflict
+
+		return c.Status(fiber.StatusConflict).JSON(resp)
+	}
+
 	if err != nil {
 		runtime.Logger.Warnf("create card failed : %s", err)
 		resp := utils.WrapResponse(nil)
@@ -104,8 +136,11 @@ func (h *Card) add(c *fiber.Ctx) error {
 
 	resp := utils.WrapResponse(&response.CardPost{
 		ID:       card.ID,
+		Token:    card.Token,
+		BIN:      card.BIN,
+		Last4:    card.Last4,
 		CardType: card.CardType,
-		Number:   card.Number,
+		Currency: card.Currency,
 	})
 	resp.Status = fiber.StatusCreated
 
@@ -123,6 +158,7 @@ func (h *Card) add(c *fiber.Ctx) error {
 // @Failure 400 {object} nil
 // @Failure 500 {object} nil
 // @Failure 404 {object} nil 卡片不存在
+// @Failure 403 {object} nil 角色无此权限
 // @Router /card/{:id} [delete]
 func (h *Card) delete(c *fiber.Ctx) error {
 	cardID := c.Params("id")
@@ -177,6 +213,7 @@ func (h *Card) delete(c *fiber.Ctx) error {
 // @Failure 400 {object} nil
 // @Failure 500 {object} nil
 // @Failure 404 {object} nil 卡片不存在
+// @Failure 403 {object} nil 角色无此权限
 // @Router /card/{:id} [get]
 func (h *Card) get(c *fiber.Ctx) error {
 	cardID := c.Params("id")
@@ -215,11 +252,7 @@ func (h *Card) get(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusInternalServerError).JSON(resp)
 	}
 
-	resp := utils.WrapResponse(&response.CardGet{
-		ID:       cardID,
-		Number:   ret.Number,
-		CardType: ret.CardType,
-	})
+	resp := utils.WrapResponse(cardGet(ret))
 
 	return c.JSON(resp)
 }
@@ -234,6 +267,7 @@ func (h *Card) get(c *fiber.Ctx) error {
 // @Success 200 {object} []response.CardGet
 // @Failure 400 {object} nil
 // @Failure 500 {object} nil
+// @Failure 403 {object} nil 角色无此权限
 // @Router /card/list [get]
 func (h *Card) list(c *fiber.Ctx) error {
 	id, _ := c.Locals("AuthID").(string)
@@ -266,11 +300,7 @@ func (h *Card) list(c *fiber.Ctx) error {
 		List:  make([]*response.CardGet, len(ret)),
 	}
 	for idx, card := range ret {
-		cards.List[idx] = &response.CardGet{
-			ID:       card.ID,
-			Number:   card.Number,
-			CardType: card.CardType,
-		}
+		cards.List[idx] = cardGet(card)
 	}
 
 	return c.JSON(utils.WrapResponse(cards))
@@ -280,22 +310,110 @@ func (h *Card) list(c *fiber.Ctx) error {
 
 // @Tags Card
 // @Summary Update bank (credit) card
-// @Description 更新银行卡（信用卡）信息
+// @Description 更新银行卡（信用卡）信息，有效期格式为MM/YY。CVV仅用于验证，不会保存
 // @ID CardUpdate
 // @Produce json
 // @Param data body request.CardUpdate true "Input information"
-// @Success 200 {object} nil
+// @Success 200 {object} response.CardGet
 // @Failure 422 string message
 // @Failure 400 {object} nil
 // @Failure 500 {object} nil
 // @Failure 404 {object} nil 卡片不存在
+// @Failure 403 {object} nil 角色无此权限
 // @Router /card/{:id} [put]
 func (h *Card) update(c *fiber.Ctx) error {
-	return nil
+	var req request.CardUpdate
+	err := c.BodyParser(&req)
+	if err != nil {
+		runtime.Logger.Warnf("parse request body failed : %s", err)
+		c.SendStatus(fiber.StatusBadRequest)
+
+		return err
+	}
+
+	id, _ := c.Locals("AuthID").(string)
+	t, _ := c.Locals("AuthType").(string)
+	if id == "" || (t != "client" && t != "tenant") {
+		resp := utils.WrapResponse(nil)
+		resp.Code = response.CodeAuthInformationMissing
+		resp.Message = response.MsgAuthInformationMissing
+		resp.Status = fiber.StatusBadRequest
+
+		return c.Status(fiber.StatusBadRequest).JSON(resp)
+	}
+
+	card, err := h.svcCard.Update(c.Context(), &model.Card{
+		ID:         c.Params("id"),
+		OwnerID:    id,
+		OwnerType:  t,
+		Holder:     req.Holder,
+		Expiration: req.Expiration,
+	}, req.CVV)
+	if errors.Is(err, sql.ErrNoRows) || errors.Is(err, model.ErrCardDoesNotExists) {
+		resp := utils.WrapResponse(nil)
+		resp.Code = response.CodeTargetNotFound
+		resp.Message = response.MsgTargetNotFound
+		resp.Status = fiber.StatusNotFound
+
+		return c.Status(fiber.StatusNotFound).JSON(resp)
+	}
+
+	if errors.Is(err, service.ErrCardInvalidExpiration) {
+		resp := utils.WrapResponse(nil)
+		resp.Code = response.CodeCardInvalidExpiration
+		resp.Message = response.MsgCardInvalidExpiration
+		resp.Status = fiber.StatusBadRequest
+
+		return c.Status(fiber.StatusBadRequest).JSON(resp)
+	}
+
+	if errors.Is(err, service.ErrCardInvalidCVV) {
+		resp := utils.WrapResponse(nil)
+		resp.Code = response.CodeCardInvalidCVV
+		resp.Message = response.MsgCardInvalidCVV
+		resp.Status = fiber.StatusBadRequest
+
+		return c.Status(fiber.StatusBadRequest).JSON(resp)
+	}
+
+	if err != nil {
+		runtime.Logger.Warnf("update card failed : %s", err)
+		resp := utils.WrapResponse(nil)
+		resp.Code = response.CodeCardUpdateFailed
+		resp.Message = response.MsgCardUpdateFailed
+		resp.Status = fiber.StatusInternalServerError
+
+		return c.Status(fiber.StatusInternalServerError).JSON(resp)
+	}
+
+	return c.JSON(utils.WrapResponse(cardGet(card)))
 }
 
 /* }}} */
 
+func cardGet(card *model.Card) *response.CardGet {
+	return &response.CardGet{
+		ID:         card.ID,
+		Token:      card.Token,
+		BIN:        card.BIN,
+		Last4:      card.Last4,
+		CardType:   card.CardType,
+		Holder:     card.Holder,
+		Expiration: card.Expiration,
+		Currency:   cardCurrency(card),
+		VerifiedAt: card.VerifiedAt,
+	}
+}
+
+// Cards added before currencies settle the default one
+func cardCurrency(card *model.Card) string {
+	if card.Currency == "" {
+		return runtime.Config.Payment.DefaultCurrency
+	}
+
+	return card.Currency
+}
+
 /*
  * Local variables:
  * tab-width: 4
@@ -14,6 +14,9 @@
 package handler
 
 import (
+	"context"
+	"database/sql"
+	"encoding/base32"
 	"errors"
 	"icepay-svc/handler/request"
 	"icepay-svc/handler/response"
@@ -21,40 +24,53 @@ import (
 	"icepay-svc/runtime"
 	"icepay-svc/service"
 	"icepay-svc/utils"
-	"strings"
+	"net/url"
+	"strconv"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 	jwtware "github.com/gofiber/jwt/v3"
-	"github.com/golang-jwt/jwt/v4"
 	"github.com/skip2/go-qrcode"
 )
 
 type Client struct {
 	svcAuth       *service.Auth
+	svcClient     *service.Client
 	svcCredential *service.Credential
 }
 
 func InitClient() *Client {
 	h := new(Client)
 
+	h.svcAuth = service.NewAuth()
+
 	clientG := runtime.Server.Group("/client")
 	clientG.Post("/token", h.token).Name("ClientPostToken")
 	clientG.Post("/refresh", h.refresh).Name("ClientPostRefresh")
+	clientG.Post("/logout", h.logout).Name("ClientPostLogout")
 	clientG.Use(jwtware.New(jwtware.Config{
-		SigningKey:     []byte(runtime.Config.Auth.JWTAccessSecret),
+		KeyFunc:        h.svcAuth.KeyFunc,
 		SuccessHandler: jwtSuccessHandler,
 		ErrorHandler:   jwtErrorHandler,
 	}))
-	clientG.Put("/", h.update).Name("ClientPut")
-	//clientG.Put("/payment-password", h.changePaymentPassword).Name("ClientPutPaymentPassword")
-	clientG.Get("/me", h.me).Name("ClientGetMe")
+	clientG.Put("/", requireScopes(service.ScopeAccountWrite), h.update).Name("ClientPut")
+	clientG.Put("/password", requireScopes(service.ScopeAccountWrite), h.changePassword).Name("ClientPutPassword")
+	clientG.Put("/payment-password", requireScopes(service.ScopeAccountWrite), h.changePaymentPassword).Name("ClientPutPaymentPassword")
+	clientG.Get("/me", requireScopes(service.ScopeAccountRead), h.me).Name("ClientGetMe")
 
-	clientG.Get("/credential", h.credential).Name("ClientGetCredential")
+	clientG.Get("/credential", requireScopes(service.ScopeCredentialIssue), h.credential).Name("ClientGetCredential")
+	clientG.Post("/totp", requireScopes(service.ScopeAccountWrite), h.provisionTOTP).Name("ClientPostTOTP")
+	clientG.Delete("/totp", requireScopes(service.ScopeAccountWrite), h.revokeTOTP).Name("ClientDeleteTOTP")
 
-	h.svcAuth = service.NewAuth()
+	h.svcClient = service.NewClient()
 	h.svcCredential = service.NewCredential()
 
+	runtime.RegisterWorker(&runtime.Worker{
+		Name:     "session-purge",
+		Interval: time.Duration(runtime.Config.Payment.SweepInterval) * time.Second,
+		Run:      h.svcAuth.Purge,
+	})
+
 	return h
 }
 
@@ -92,21 +108,13 @@ func (h *Client) token(c *fiber.Ctx) error {
 		return c.Status(errResp.Status).JSON(errResp)
 	}
 
-	// AccessToken
-	jwtAccess, errAccess := h.svcAuth.JWTSign(&service.Sign{
-		Sub:       clt.ID,
-		Name:      clt.Email,
-		Type:      "client",
-		ExpiresIn: time.Duration(runtime.Config.Auth.JWTAccessExpiry) * time.Minute,
-	})
-	// RefreshToken
-	jwtRefresh, errRefresh := h.svcAuth.JWTSign(&service.Sign{
-		Sub:       clt.ID,
-		Name:      clt.Email,
-		Type:      "client::refresh",
-		ExpiresIn: time.Duration(runtime.Config.Auth.JWTRefreshExpiry) * time.Minute,
-	})
-	if errAccess != nil || errRefresh != nil {
+	tokens, err := h.svcAuth.Login(c.Context(), &model.Session{
+		Subject:     clt.ID,
+		SubjectType: "client",
+		Role:        service.RoleClient,
+	}, clt.Email)
+	if err != nil {
+		runtime.Logger.Errorf("open session of client [%s] failed : %s", clt.ID, err)
 		resp := utils.WrapResponse(nil)
 		resp.Status = fiber.StatusInternalServerError
 		resp.Code = response.CodeAuthInternal
@@ -116,10 +124,10 @@ func (h *Client) token(c *fiber.Ctx) error {
 	}
 
 	resp := utils.WrapResponse(&response.ClientPostToken{
-		AccessToken:   jwtAccess.Token,
-		RefreshToken:  jwtRefresh.Token,
-		AccessExpiry:  jwtAccess.Expiry,
-		RefreshExpiry: jwtRefresh.Expiry,
+		AccessToken:   tokens.Access.Token,
+		RefreshToken:  tokens.Refresh.Token,
+		AccessExpiry:  tokens.Access.Expiry,
+		RefreshExpiry: tokens.Refresh.Expiry,
 		TokenType:     "bearer",
 	})
 
@@ -239,7 +247,7 @@ func (h *Client) tokenFirebase(c *fiber.Ctx, idToken string) (*utils.Envelope, *
 
 // @Tags Client
 // @Summary Refresh access_token via refresh_token
-// @Description 使用refresh_token获取新的access_token，避免客户端重复登录
+// @Description 使用refresh_token（Authorization: Bearer）获取新的access_token，避免客户端重复登录。refresh_token每次使用后轮换，须保存本次返回的新refresh_token，旧的随即失效；再次使用已轮换的refresh_token视为泄露，整个会话被撤销，需重新登录
 // @ID ClientPostRefresh
 // @Produce json
 // @Success 201 {object} response.ClientPostRefresh
@@ -247,8 +255,8 @@ func (h *Client) tokenFirebase(c *fiber.Ctx, idToken string) (*utils.Envelope, *
 // @Failure 500 {object} nil
 // @Router /client/refresh [post]
 func (h *Client) refresh(c *fiber.Ctx) error {
-	auth := c.Get("Authorization")
-	if len(auth) < 8 || strings.ToLower(auth[0:7]) != "bearer " {
+	claims, err := h.svcAuth.JWTValid(bearerToken(c), "client"+service.TokenTypeRefreshSuffix)
+	if err != nil {
 		resp := utils.WrapResponse(nil)
 		resp.Code = response.CodeClientInvalidAuthorization
 		resp.Message = response.MsgClientInvalidAuthorization
@@ -257,50 +265,63 @@ func (h *Client) refresh(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusUnauthorized).JSON(resp)
 	}
 
-	refreshToken := auth[7:]
-	claims, err := h.svcAuth.JWTValid(refreshToken)
+	// Rotate, sessions are revoked on password change
+	tokens, err := h.svcAuth.Refresh(c.Context(), claims)
+	if err != nil {
+		return h.sessionFailed(c, err)
+	}
+
+	resp := utils.WrapResponse(&response.ClientPostRefresh{
+		AccessToken:   tokens.Access.Token,
+		RefreshToken:  tokens.Refresh.Token,
+		AccessExpiry:  tokens.Access.Expiry,
+		RefreshExpiry: tokens.Refresh.Expiry,
+		TokenType:     "bearer",
+	})
+	resp.Status = fiber.StatusCreated
+
+	return c.Status(fiber.StatusCreated).JSON(resp)
+}
+
+// logout: Revoke session
+
+// @Tags Client
+// @Summary Logout
+// @Description 撤销refresh_token（Authorization: Bearer）所属的会话，该会话的refresh_token与已签发的access_token立即失效
+// @ID ClientPostLogout
+// @Produce json
+// @Success 200 {object} response.ClientPostLogout
+// @Failure 401 {object} nil
+// @Failure 500 {object} nil
+// @Router /client/logout [post]
+func (h *Client) logout(c *fiber.Ctx) error {
+	claims, err := h.svcAuth.JWTValid(bearerToken(c), "client"+service.TokenTypeRefreshSuffix)
 	if err != nil {
 		resp := utils.WrapResponse(nil)
-		resp.Code = response.CodeAuthInternal
-		resp.Message = response.MsgAuthInternal
+		resp.Code = response.CodeClientInvalidAuthorization
+		resp.Message = response.MsgClientInvalidAuthorization
 		resp.Status = fiber.StatusUnauthorized
 
 		return c.Status(fiber.StatusUnauthorized).JSON(resp)
 	}
 
-	// Re-sign token
-	id, _ := claims["sub"].(string)
-	name, _ := claims["name"].(string)
-	jwt, err := h.svcAuth.JWTSign(&service.Sign{
-		Sub:       id,
-		Name:      name,
-		Type:      "client",
-		ExpiresIn: time.Duration(runtime.Config.Auth.JWTAccessExpiry) * time.Minute,
-	})
+	err = h.svcAuth.Logout(c.Context(), claims)
 	if err != nil {
-		resp := utils.WrapResponse(nil)
-		resp.Status = fiber.StatusInternalServerError
-		resp.Code = response.CodeAuthInternal
-		resp.Message = response.MsgAuthInternal
-
-		return c.Status(fiber.StatusInternalServerError).JSON(resp)
+		return h.sessionFailed(c, err)
 	}
 
-	resp := utils.WrapResponse(&response.ClientPostRefresh{
-		AccessToken:  jwt.Token,
-		AccessExpiry: jwt.Expiry,
-		TokenType:    "bearer",
+	resp := utils.WrapResponse(&response.ClientPostLogout{
+		Revoked: true,
 	})
-	resp.Status = fiber.StatusCreated
 
-	return c.Status(fiber.StatusCreated).JSON(resp)
+	return c.JSON(resp)
 }
 
 // update: Update client
 
 // @Tags Client
 // @Summary Update client
-// @Description 更新client
+// @Description 更新client资料（姓名、电话），同时给出新旧密码时一并修改登录密码或支付密码
 // @ID ClientPut
 // @Produce json
 // @Param data body request.ClientPut true "input information"
@@ -309,17 +330,132 @@ func (h *Client) refresh(c *fiber.Ctx) error {
 // @Failure 400 {object} nil
 // @Failure 401 {object} nil
 // @Failure 500 {object} nil
-// @Router /client/password [put]
+// @Failure 403 {object} nil 角色无此权限
+// @Router /client [put]
 func (h *Client) update(c *fiber.Ctx) error {
-	return nil
+	var req request.ClientPut
+	err := c.BodyParser(&req)
+	if err != nil {
+		runtime.Logger.Warnf("parse request body failed : %s", err)
+		c.SendStatus(fiber.StatusBadRequest)
+
+		return err
+	}
+
+	id, _ := c.Locals("AuthID").(string)
+	t, _ := c.Locals("AuthType").(string)
+	if id == "" || t != "client" {
+		resp := utils.WrapResponse(nil)
+		resp.Code = response.CodeAuthInformationMissing
+		resp.Message = response.MsgAuthInformationMissing
+		resp.Status = fiber.StatusBadRequest
+
+		return c.Status(fiber.StatusBadRequest).JSON(resp)
+	}
+
+	// Profile and passwords updated together or not at all
+	var clt *model.Client
+	err = runtime.RunInTx(c.Context(), func(ctx context.Context) error {
+		if req.NewPassword != "" {
+			err := h.svcClient.ChangePassword(ctx, id, req.OldPassword, req.NewPassword)
+			if err != nil {
+				return err
+			}
+		}
+
+		if req.NewPaymentPassword != "" {
+			err := h.svcClient.ChangePaymentPassword(ctx, id, req.OldPaymentPassword, req.NewPaymentPassword)
+			if err != nil {
+				return err
+			}
+		}
+
+		var err error
+		clt, err = h.svcClient.Update(ctx, &model.Client{
+			ID:    id,
+			Name:  req.Name,
+			Phone: req.Phone,
+		})
+
+		return err
+	})
+	if errors.Is(err, service.ErrClientWrongPassword) || errors.Is(err, service.ErrClientInvalidPassword) || errors.Is(err, service.ErrClientInvalidPaymentPassword) {
+		return h.passwordFailed(c, err)
+	}
+
+	if err != nil {
+		runtime.Logger.Errorf("update client failed : %s", err)
+		resp := utils.WrapResponse(nil)
+		resp.Code = response.CodeClientUpdateError
+		resp.Message = response.MsgClientUpdateError
+		resp.Status = fiber.StatusInternalServerError
+
+		return c.Status(fiber.StatusInternalServerError).JSON(resp)
+	}
+
+	resp := utils.WrapResponse(&response.ClientPut{
+		ID:    clt.ID,
+		Email: clt.Email,
+		Name:  clt.Name,
+		Phone: clt.Phone,
+	})
+
+	return c.JSON(resp)
+}
+
+// changePassword: Change login password
+
+// @Tags Client
+// @Summary Change password
+// @Description 修改登录密码，修改后之前的会话全部撤销，已签发的refresh_token与access_token立即失效
+// @ID ClientPutPassword
+// @Produce json
+// @Param data body request.ClientPutPassword true "input information"
+// @Success 200 {object} response.ClientPutPassword
+// @Failure 422 string message
+// @Failure 400 {object} nil
+// @Failure 401 {object} nil
+// @Failure 500 {object} nil
+// @Failure 403 {object} nil 角色无此权限
+// @Router /client/password [put]
+func (h *Client) changePassword(c *fiber.Ctx) error {
+	var req request.ClientPutPassword
+	err := c.BodyParser(&req)
+	if err != nil {
+		runtime.Logger.Warnf("parse request body failed : %s", err)
+		c.SendStatus(fiber.StatusBadRequest)
+
+		return err
+	}
+
+	id, _ := c.Locals("AuthID").(string)
+	t, _ := c.Locals("AuthType").(string)
+	if id == "" || t != "client" {
+		resp := utils.WrapResponse(nil)
+		resp.Code = response.CodeAuthInformationMissing
+		resp.Message = response.MsgAuthInformationMissing
+		resp.Status = fiber.StatusBadRequest
+
+		return c.Status(fiber.StatusBadRequest).JSON(resp)
+	}
+
+	err = h.svcClient.ChangePassword(c.Context(), id, req.OldPassword, req.NewPassword)
+	if err != nil {
+		return h.passwordFailed(c, err)
+	}
+
+	resp := utils.WrapResponse(&response.ClientPutPassword{
+		Changed: true,
+	})
+
+	return c.JSON(resp)
 }
 
-/*
 // changePaymentPassword: Change payment password
 
 // @Tags Client
 // @Summary Change payment password
-// @Description 修改支付密码（6位数字）
+// @Description 修改支付密码（6位数字），首次设置时无需旧密码，修改后之前的会话全部撤销，已签发的refresh_token与access_token立即失效
 // @ID ClientPutPaymentPassword
 // @Produce json
 // @Param data body request.ClientPutPaymentPassword true "input information"
@@ -328,36 +464,112 @@ func (h *Client) update(c *fiber.Ctx) error {
 // @Failure 400 {object} nil
 // @Failure 401 {object} nil
 // @Failure 500 {object} nil
+// @Failure 403 {object} nil 角色无此权限
 // @Router /client/payment-password [put]
 func (h *Client) changePaymentPassword(c *fiber.Ctx) error {
-	return nil
+	var req request.ClientPutPaymentPassword
+	err := c.BodyParser(&req)
+	if err != nil {
+		runtime.Logger.Warnf("parse request body failed : %s", err)
+		c.SendStatus(fiber.StatusBadRequest)
+
+		return err
+	}
+
+	id, _ := c.Locals("AuthID").(string)
+	t, _ := c.Locals("AuthType").(string)
+	if id == "" || t != "client" {
+		resp := utils.WrapResponse(nil)
+		resp.Code = response.CodeAuthInformationMissing
+		resp.Message = response.MsgAuthInformationMissing
+		resp.Status = fiber.StatusBadRequest
+
+		return c.Status(fiber.StatusBadRequest).JSON(resp)
+	}
+
+	err = h.svcClient.ChangePaymentPassword(c.Context(), id, req.OldPassword, req.NewPassword)
+	if err != nil {
+		return h.passwordFailed(c, err)
+	}
+
+	resp := utils.WrapResponse(&response.ClientPutPaymentPassword{
+		Changed: true,
+	})
+
+	return c.JSON(resp)
+}
+
+// passwordFailed: responses password change error
+func (h *Client) passwordFailed(c *fiber.Ctx, err error) error {
+	resp := utils.WrapResponse(nil)
+	switch {
+	case errors.Is(err, service.ErrClientWrongPassword):
+		resp.Code = response.CodeClientWrongPassword
+		resp.Message = response.MsgClientWrongPassword
+		resp.Status = fiber.StatusUnauthorized
+	case errors.Is(err, service.ErrClientInvalidPassword), errors.Is(err, service.ErrClientInvalidPaymentPassword):
+		resp.Code = response.CodeClientInvalidPassword
+		resp.Message = err.Error()
+		resp.Status = fiber.StatusBadRequest
+	default:
+		runtime.Logger.Errorf("change client password failed : %s", err)
+		resp.Code = response.CodeClientUpdateError
+		resp.Message = response.MsgClientUpdateError
+		resp.Status = fiber.StatusInternalServerError
+	}
+
+	return c.Status(resp.Status).JSON(resp)
 }
-*/
 
 // me: Get myself
 
 // @Tags Client
 // @Summary Show me
-// @Description 解析access_token，返回当前验证者信息（脱敏）
+// @Description 返回当前验证者信息（脱敏），包括支付是否因支付密码连续错误被锁定（payment_locked）及解锁时间
 // @ID ClientGetMe
 // @Produce json
-// @Success 200 {object} nil
+// @Success 200 {object} response.ClientGetMe
+// @Failure 400 {object} nil
 // @Failure 500 {object} nil
+// @Failure 403 {object} nil 角色无此权限
 // @Router /client/me [get]
 func (h *Client) me(c *fiber.Ctx) error {
-	user, ok := c.Locals("user").(*jwt.Token)
-	if !ok {
-		return errors.New("parse userdata from context failed")
+	id, _ := c.Locals("AuthID").(string)
+	t, _ := c.Locals("AuthType").(string)
+	if id == "" || t != "client" {
+		resp := utils.WrapResponse(nil)
+		resp.Code = response.CodeAuthInformationMissing
+		resp.Message = response.MsgAuthInformationMissing
+		resp.Status = fiber.StatusBadRequest
+
+		return c.Status(fiber.StatusBadRequest).JSON(resp)
 	}
 
-	claims, ok := user.Claims.(jwt.MapClaims)
-	if !ok {
-		return errors.New("wrong userdata format")
+	clt := &model.Client{
+		ID: id,
 	}
+	err := clt.Get(c.Context())
+	if err != nil {
+		resp := utils.WrapResponse(nil)
+		resp.Code = response.CodeClientGetError
+		resp.Message = response.MsgClientGetError
+		resp.Status = fiber.StatusInternalServerError
 
-	resp := utils.WrapResponse(claims)
+		return c.Status(fiber.StatusInternalServerError).JSON(resp)
+	}
 
-	return c.JSON(resp)
+	me := &response.ClientGetMe{
+		ID:            clt.ID,
+		Email:         clt.Email,
+		Name:          clt.Name,
+		Phone:         clt.Phone,
+		PaymentLocked: service.PaymentLocked(clt),
+	}
+	if me.PaymentLocked {
+		me.PaymentLockedUntil = clt.PaymentLockedUntil.Unix()
+	}
+
+	return c.JSON(utils.WrapResponse(me))
 }
 
 // credential: Get information for QR render
@@ -371,6 +583,7 @@ func (h *Client) me(c *fiber.Ctx) error {
 // @Success 200 string png
 // @Failure 400 {object} nil
 // @Failure 500 {object} nil
+// @Failure 403 {object} nil 角色无此权限
 // @Router /client/credential [get]
 func (h *Client) credential(c *fiber.Ctx) error {
 	id, _ := c.Locals("AuthID").(string)
@@ -416,8 +629,132 @@ func (h *Client) credential(c *fiber.Ctx) error {
 	return c.JSON(resp)
 }
 
+// provisionTOTP: Provision seed of offline payment codes
+
+// @Tags Client
+// @Summary Provision offline payment code
+// @Description 生成离线付款码种子（仅返回一次，重复调用将替换旧种子）。客户端离线按TOTP（RFC 6238，HMAC-SHA1）生成付款码：prefix + index + TOTP，共15位数字，可渲染为二维码或由收银员手工输入
+// @ID ClientPostTOTP
+// @Produce json
+// @Success 201 {object} response.ClientPostTOTP
+// @Failure 400 {object} nil
+// @Failure 500 {object} nil
+// @Failure 403 {object} nil 角色无此权限
+// @Router /client/totp [post]
+func (h *Client) provisionTOTP(c *fiber.Ctx) error {
+	id, _ := c.Locals("AuthID").(string)
+	t, _ := c.Locals("AuthType").(string)
+	if id == "" || t != "client" {
+		resp := utils.WrapResponse(nil)
+		resp.Code = response.CodeAuthInformationMissing
+		resp.Message = response.MsgAuthInformationMissing
+		resp.Status = fiber.StatusBadRequest
+
+		return c.Status(fiber.StatusBadRequest).JSON(resp)
+	}
+
+	provision, err := h.svcCredential.Provision(c.Context(), id)
+	if err != nil {
+		runtime.Logger.Errorf("provision TOTP of client [%s] failed : %s", id, err)
+		resp := utils.WrapResponse(nil)
+		resp.Code = response.CodeClientTOTPError
+		resp.Message = response.MsgClientTOTPError
+		resp.Status = fiber.StatusInternalServerError
+
+		return c.Status(fiber.StatusInternalServerError).JSON(resp)
+	}
+
+	seed := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(provision.Seed)
+	params := url.Values{}
+	params.Set("secret", seed)
+	params.Set("issuer", runtime.EnvPrefix)
+	params.Set("digits", strconv.Itoa(provision.Digits))
+	params.Set("period", strconv.FormatInt(provision.Step, 10))
+	resp := utils.WrapResponse(&response.ClientPostTOTP{
+		Seed:   seed,
+		Index:  provision.Index,
+		Prefix: provision.Prefix,
+		Digits: provision.Digits,
+		Period: provision.Step,
+		URI:    "otpauth://totp/" + runtime.EnvPrefix + ":" + provision.Index + "?" + params.Encode(),
+	})
+	resp.Status = fiber.StatusCreated
+
+	return c.Status(fiber.StatusCreated).JSON(resp)
+}
+
+// revokeTOTP: Revoke seed of offline payment codes
+
+// @Tags Client
+// @Summary Revoke offline payment code
+// @Description 撤销离线付款码种子，此后离线付款码不再有效
+// @ID ClientDeleteTOTP
+// @Produce json
+// @Success 200 {object} response.ClientDeleteTOTP
+// @Failure 400 {object} nil
+// @Failure 404 {object} nil 未开通离线付款码
+// @Failure 500 {object} nil
+// @Failure 403 {object} nil 角色无此权限
+// @Router /client/totp [delete]
+func (h *Client) revokeTOTP(c *fiber.Ctx) error {
+	id, _ := c.Locals("AuthID").(string)
+	t, _ := c.Locals("AuthType").(string)
+	if id == "" || t != "client" {
+		resp := utils.WrapResponse(nil)
+		resp.Code = response.CodeAuthInformationMissing
+		resp.Message = response.MsgAuthInformationMissing
+		resp.Status = fiber.StatusBadRequest
+
+		return c.Status(fiber.StatusBadRequest).JSON(resp)
+	}
+
+	err := h.svcCredential.Revoke(c.Context(), id)
+	if errors.Is(err, model.ErrTOTPSeedDoesNotExists) {
+		resp := utils.WrapResponse(nil)
+		resp.Code = response.CodeClientTOTPNotProvisioned
+		resp.Message = response.MsgClientTOTPNotProvisioned
+		resp.Status = fiber.StatusNotFound
+
+		return c.Status(fiber.StatusNotFound).JSON(resp)
+	}
+
+	if err != nil {
+		resp := utils.WrapResponse(nil)
+		resp.Code = response.CodeClientTOTPError
+		resp.Message = response.MsgClientTOTPError
+		resp.Status = fiber.StatusInternalServerError
+
+		return c.Status(fiber.StatusInternalServerError).JSON(resp)
+	}
+
+	return c.JSON(utils.WrapResponse(&response.ClientDeleteTOTP{
+		Revoked: true,
+	}))
+}
+
 /* }}} */
 
+func (h *Client) sessionFailed(c *fiber.Ctx, err error) error {
+	resp := utils.WrapResponse(nil)
+	switch {
+	case errors.Is(err, service.ErrSessionReused):
+		resp.Code = response.CodeClientRefreshReused
+		resp.Message = response.MsgClientRefreshReused
+		resp.Status = fiber.StatusUnauthorized
+	case errors.Is(err, service.ErrSessionInvalid), errors.Is(err, sql.ErrNoRows):
+		resp.Code = response.CodeClientInvalidAuthorization
+		resp.Message = response.MsgClientInvalidAuthorization
+		resp.Status = fiber.StatusUnauthorized
+	default:
+		runtime.Logger.Errorf("client session operation failed : %s", err)
+		resp.Code = response.CodeAuthInternal
+		resp.Message = response.MsgAuthInternal
+		resp.Status = fiber.StatusInternalServerError
+	}
+
+	return c.Status(resp.Status).JSON(resp)
+}
+
 /*
  * Local variables:
  * tab-width: 4
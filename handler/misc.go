@@ -18,7 +18,9 @@ import (
 	_ "icepay-svc/docs"
 	"icepay-svc/handler/response"
 	"icepay-svc/runtime"
+	"icepay-svc/service"
 	"icepay-svc/utils"
+	"strings"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/swagger"
@@ -26,6 +28,7 @@ import (
 )
 
 type Misc struct {
+	svcAuth *service.Auth
 }
 
 func InitMisc() *Misc {
@@ -35,6 +38,9 @@ func InitMisc() *Misc {
 	runtime.Server.All("/", h.index).Name("Index")
 	runtime.Server.Get("/routers", h.routers).Name("GetRouters")
 	runtime.Server.Get("/docs/*", swagger.HandlerDefault)
+	runtime.Server.Get("/.well-known/jwks.json", h.jwks).Name("GetJWKS")
+
+	h.svcAuth = service.NewAuth()
 
 	return h
 }
@@ -65,6 +71,21 @@ func (h *Misc) routers(c *fiber.Ctx) error {
 	return c.JSON(utils.WrapResponse(runtime.Server.Stack()))
 }
 
+// jwks
+
+// @Tags Misc
+// @Summary Get JSON Web Key Set
+// @Description 返回验证access token的公钥集合（RFC 7517），按JWT头部kid选择公钥，RSA密钥为RS256，Ed25519密钥为EdDSA。密钥轮换后旧公钥仍保留，直至其签发的token全部过期。未配置非对称密钥（HS256）时keys为空。返回原始JWKS，不使用JSON envelope
+// @ID GetJWKS
+// @Produce json
+// @Success 200 {object} utils.JWKS
+// @Router /.well-known/jwks.json [get]
+func (h *Misc) jwks(c *fiber.Ctx) error {
+	c.Set(fiber.HeaderCacheControl, "public, max-age=300")
+
+	return c.JSON(h.svcAuth.JWKS())
+}
+
 /* {{{ *Internal handlers* */
 func jwtSuccessHandler(c *fiber.Ctx) error {
 	u, ok := c.Locals("user").(*jwt.Token)
@@ -77,6 +98,28 @@ func jwtSuccessHandler(c *fiber.Ctx) error {
 		return errors.New("JWT claims type error")
 	}
 
+	// Refresh tokens share claims with access ones, and even the key if secrets are the same
+	if err := service.AccessValid(claims); err != nil {
+		return jwtErrorHandler(c, err)
+	}
+
+	// Revoked sessions (logout, password changed, staff disabled) invalidate their access tokens at once
+	sid, _ := claims["sid"].(string)
+	live, err := service.SessionLive(c.Context(), sid)
+	if err != nil {
+		runtime.Logger.Errorf("check session [%s] failed : %s", sid, err)
+		resp := utils.WrapResponse(nil)
+		resp.Code = response.CodeAuthInternal
+		resp.Message = response.MsgAuthInternal
+		resp.Status = fiber.StatusInternalServerError
+
+		return c.Status(fiber.StatusInternalServerError).JSON(resp)
+	}
+
+	if !live {
+		return jwtErrorHandler(c, service.ErrSessionInvalid)
+	}
+
 	authType, ok := claims["type"].(string)
 	if ok {
 		c.Locals("AuthType", authType)
@@ -92,9 +135,58 @@ func jwtSuccessHandler(c *fiber.Ctx) error {
 		c.Locals("AuthID", authID)
 	}
 
+	// Staff or terminal of tenant signed in
+	if staff, ok := claims["staff"].(string); ok {
+		c.Locals("AuthStaff", staff)
+	}
+
+	if terminal, ok := claims["terminal"].(string); ok {
+		c.Locals("AuthTerminal", terminal)
+	}
+
+	// Tokens signed before roles act as the owner of account
+	role, _ := claims["role"].(string)
+	if role == "" {
+		role = service.DefaultRole(authType)
+	}
+
+	c.Locals("AuthRole", role)
+	if scope, ok := claims["scope"].(string); ok {
+		c.Locals("AuthScopes", service.ParseScope(scope))
+	} else {
+		c.Locals("AuthScopes", service.RoleScopes(role))
+	}
+
 	return c.Next()
 }
 
+// requireScopes : middleware declaring scopes of route, all of them must be granted to the authorized role
+func requireScopes(scopes ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		granted, _ := c.Locals("AuthScopes").([]string)
+		if !service.HasScopes(granted, scopes...) {
+			resp := utils.WrapResponse(nil)
+			resp.Code = response.CodeScopeInsufficient
+			resp.Message = response.MsgScopeInsufficient
+			resp.Status = fiber.StatusForbidden
+
+			return c.Status(fiber.StatusForbidden).JSON(resp)
+		}
+
+		return c.Next()
+	}
+}
+
+// bearerToken : token of Authorization header, empty if not a bearer one
+func bearerToken(c *fiber.Ctx) string {
+	auth := c.Get("Authorization")
+	if len(auth) < 8 || strings.ToLower(auth[0:7]) != "bearer " {
+		return ""
+	}
+
+	return auth[7:]
+}
+
 func jwtErrorHandler(c *fiber.Ctx, err error) error {
 	resp := utils.WrapResponse(nil)
 	resp.Code = response.CodeAuthFailed
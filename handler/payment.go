@@ -14,48 +14,88 @@
 package handler
 
 import (
+	"bufio"
+	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"icepay-svc/handler/request"
 	"icepay-svc/handler/response"
 	"icepay-svc/model"
 	"icepay-svc/runtime"
 	"icepay-svc/service"
 	"icepay-svc/utils"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	jwtware "github.com/gofiber/jwt/v3"
+	"github.com/gofiber/websocket/v2"
 	"github.com/nats-io/nats.go"
+	"github.com/skip2/go-qrcode"
 )
 
+// Cursor of /payment/status responses, to wait since next time
+const headerEventCursor = "X-Icepay-Cursor"
+
 type Payment struct {
 	svcTransaction *service.Transaction
 	svcCredential  *service.Credential
 	svcAuth        *service.Auth
 	svcCard        *service.Card
+	svcIdempotency *service.Idempotency
 }
 
 func InitPayment() *Payment {
 	h := new(Payment)
 
+	h.svcAuth = service.NewAuth()
+
 	paymentG := runtime.Server.Group("/payment")
-	paymentG.Use(jwtware.New(jwtware.Config{
-		SigningKey:     []byte(runtime.Config.Auth.JWTAccessSecret),
+	paymentG.Use(apiKeyAuth(service.NewAPIKey()))
+	auth := jwtware.New(jwtware.Config{
+		// Authorized by API key of tenant already
+		Filter:         apiKeyAuthorized,
+		KeyFunc:        h.svcAuth.KeyFunc,
+		SuccessHandler: jwtSuccessHandler,
+		ErrorHandler:   jwtErrorHandler,
+	})
+	streamAuth := jwtware.New(jwtware.Config{
+		Filter:  apiKeyAuthorized,
+		KeyFunc: h.svcAuth.KeyFunc,
+		// EventSource and browser WebSocket can not set headers, token in query of streams only
+		TokenLookup:    "header:Authorization,query:access_token",
 		SuccessHandler: jwtSuccessHandler,
 		ErrorHandler:   jwtErrorHandler,
-	}))
-	paymentG.Post("/", h.add).Name("PaymentPost")
-	paymentG.Put("/:id", h.update).Name("PaymentPut")
-	paymentG.Get("/list", h.list).Name("PaymentGetList")
-	paymentG.Get("/status", h.status).Name("PaymentGetStatus")
-	paymentG.Get("/:id", h.get).Name("PaymentGet")
-	paymentG.Put("/:id", h.update).Name("PaymentPut")
+	})
+	paymentG.Post("/", auth, requireScopes(service.ScopePaymentCreate), h.idempotent, h.add).Name("PaymentPost")
+	paymentG.Post("/request", auth, requireScopes(service.ScopePaymentCreate), h.idempotent, h.request).Name("PaymentPostRequest")
+	paymentG.Post("/claim", auth, requireScopes(service.ScopePaymentPay), h.claim).Name("PaymentPostClaim")
+	paymentG.Put("/:id", auth, requireScopes(service.ScopePaymentPay), h.idempotent, h.update).Name("PaymentPut")
+	paymentG.Post("/:id/refund", auth, requireScopes(service.ScopePaymentRefund), h.idempotent, h.refund).Name("PaymentPostRefund")
+	paymentG.Get("/list", auth, requireScopes(service.ScopePaymentRead), h.list).Name("PaymentGetList")
+	paymentG.Get("/status", auth, requireScopes(service.ScopePaymentRead), h.status).Name("PaymentGetStatus")
+	paymentG.Get("/events", streamAuth, requireScopes(service.ScopePaymentRead), h.events).Name("PaymentGetEvents")
+	paymentG.Get("/ws", streamAuth, requireScopes(service.ScopePaymentRead), h.upgrade, websocket.New(h.ws)).Name("PaymentGetWS")
+	paymentG.Get("/:id", auth, requireScopes(service.ScopePaymentRead), h.get).Name("PaymentGet")
 
 	h.svcTransaction = service.NewTransaction()
 	h.svcCredential = service.NewCredential()
-	h.svcAuth = service.NewAuth()
 	h.svcCard = service.NewCard()
+	h.svcIdempotency = service.NewIdempotency()
+
+	runtime.RegisterWorker(&runtime.Worker{
+		Name:     "transaction-expiry",
+		Interval: time.Duration(runtime.Config.Payment.SweepInterval) * time.Second,
+		Run:      h.svcTransaction.Expire,
+	})
+	runtime.RegisterWorker(&runtime.Worker{
+		Name:     "credential-nonce-purge",
+		Interval: time.Duration(runtime.Config.Payment.SweepInterval) * time.Second,
+		Run:      h.svcCredential.Purge,
+	})
 
 	return h
 }
@@ -66,14 +106,19 @@ func InitPayment() *Payment {
 
 // @Tags Payment
 // @Summary Create payment flow
-// @Description 创建支付订单
+// @Description 创建支付订单，credential为客户付款码（icepay://）或15位离线付款码（可含空格或-分隔）。amount为最小货币单位的整数（如JPY为日元，USD为美分），currency为ISO 4217代码，缺省为默认币种，须为商户接受的币种且客户有可结算该币种的卡片
 // @ID PaymentPost
 // @Produce json
 // @Param data body request.PaymentPost true "Input information"
 // @Success 201 {object} response.PaymentPost
 // @Failure 422 string message
-// @Failure 400 {object} nil
+// @Failure 400 {object} nil 付款码无效
+// @Failure 401 {object} nil 付款码已过期
+// @Failure 409 {object} nil 付款码已被使用
+// @Failure 422 {object} nil 客户没有可结算该币种的卡片
+// @Failure 423 {object} nil 离线付款码错误次数过多，暂时锁定
 // @Failure 500 {object} nil
+// @Failure 403 {object} nil 角色无此权限
 // @Router /payment [post]
 func (h *Payment) add(c *fiber.Ctx) error {
 	var req request.PaymentPost
@@ -96,27 +141,171 @@ func (h *Payment) add(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(resp)
 	}
 
-	clientID, err := h.svcCredential.Decode(req.Credential)
+	if req.Amount <= 0 {
+		resp := utils.WrapResponse(nil)
+		resp.Code = response.CodeInvalidParameter
+		resp.Message = response.MsgInvalidParameter
+		resp.Status = fiber.StatusBadRequest
+
+		return c.Status(fiber.StatusBadRequest).JSON(resp)
+	}
+
+	// Credential consumed along with the transaction, not burnt if creation failed
+	staff, _ := c.Locals("AuthStaff").(string)
+	terminal, _ := c.Locals("AuthTerminal").(string)
+	var transaction *model.Transaction
+	err = runtime.RunInTx(c.Context(), func(ctx context.Context) error {
+		clientID, err := h.svcCredential.Consume(ctx, req.Credential, id)
+		if err != nil {
+			return err
+		}
+
+		transaction, err = h.svcTransaction.Create(ctx, &model.Transaction{
+			Client:   clientID,
+			Tenant:   id,
+			Amount:   req.Amount,
+			Currency: req.Currency,
+			Detail:   req.Detail,
+			Staff:    staff,
+			Terminal: terminal,
+		})
+
+		return err
+	})
+	if errors.Is(err, service.ErrCredentialUsed) {
+		runtime.Logger.Warnf("used credential given by tenant [%s]", id)
+		resp := utils.WrapResponse(nil)
+		resp.Code = response.CodePaymentCredentialUsed
+		resp.Message = response.MsgPaymentCredentialUsed
+		resp.Status = fiber.StatusConflict
+
+		return c.Status(fiber.StatusConflict).JSON(resp)
+	}
+
+	if errors.Is(err, service.ErrCredentialExpired) {
+		resp := utils.WrapResponse(nil)
+		resp.Code = response.CodePaymentCredentialExpired
+		resp.Message = response.MsgPaymentCredentialExpired
+		resp.Status = fiber.StatusUnauthorized
+
+		return c.Status(fiber.StatusUnauthorized).JSON(resp)
+	}
+
+	if errors.Is(err, service.ErrTOTPLocked) {
+		runtime.Logger.Warnf("locked offline code given by tenant [%s]", id)
+		resp := utils.WrapResponse(nil)
+		resp.Code = response.CodePaymentCodeLocked
+		resp.Message = response.MsgPaymentCodeLocked
+		resp.Status = fiber.StatusLocked
+
+		return c.Status(fiber.StatusLocked).JSON(resp)
+	}
+
+	if errors.Is(err, service.ErrCredentialFormat) || errors.Is(err, service.ErrCredentialInvalid) || errors.Is(err, service.ErrCredentialV1) {
+		runtime.Logger.Warnf("invalid credential given by tenant [%s] : %s", id, err)
+		resp := utils.WrapResponse(nil)
+		resp.Code = response.CodePaymentInvalidCredential
+		resp.Message = response.MsgPaymentInvalidCredential
+		resp.Status = fiber.StatusBadRequest
+
+		return c.Status(fiber.StatusBadRequest).JSON(resp)
+	}
+
+	if resp := currencyFailed(err); resp != nil {
+		return c.Status(resp.Status).JSON(resp)
+	}
+
 	if err != nil {
+		runtime.Logger.Warnf("create payment failed : %s", err)
 		resp := utils.WrapResponse(nil)
-		resp.Code = response.CodeDecodeFailed
-		resp.Message = response.MsgDecodeFailed
+		resp.Code = response.CodePaymentCreateFailed
+		resp.Message = response.MsgPaymentCreateFailed
 		resp.Status = fiber.StatusInternalServerError
 
-		runtime.Logger.Warnf("AES decrypt failed : %s", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(resp)
+	}
+
+	// Create notification
+	err = h.svcTransaction.Notify(c.Context(), transaction)
+	if err != nil {
+		runtime.Logger.Errorf("payment notify failed : %s", err)
+		resp := utils.WrapResponse(nil)
+		resp.Code = response.CodePaymentNotifyFailed
+		resp.Message = response.MsgPaymentNotifyFailed
+		resp.Status = fiber.StatusInternalServerError
 
 		return c.Status(fiber.StatusInternalServerError).JSON(resp)
 	}
 
-	transaction, err := h.svcTransaction.Create(c.Context(), &model.Transaction{
-		Client:   clientID,
+	resp := utils.WrapResponse(&response.PaymentPost{
+		TransactionID: transaction.ID,
+	})
+	resp.Status = fiber.StatusCreated
+
+	return c.Status(fiber.StatusCreated).JSON(resp)
+}
+
+// request: Create payment request presented by tenant
+
+// @Tags Payment
+// @Summary Create payment request
+// @Description 商户创建收款请求（PRE状态订单），amount为最小货币单位的整数，currency须为商户接受的币种（ISO 4217），返回签名的收款码，客户扫码认领后通过PUT /payment/{:id}确认支付。有效期同订单超时时间，过期未认领的订单将被关闭。URL后缀?img=true，生成512x512像素的png图片（二维码）
+// @ID PaymentPostRequest
+// @Produce json
+// @Param data body request.PaymentPostRequest true "Input information"
+// @Success 201 {object} response.PaymentPostRequest
+// @Success 201 string png
+// @Failure 422 string message
+// @Failure 400 {object} nil
+// @Failure 500 {object} nil
+// @Failure 403 {object} nil 角色无此权限
+// @Router /payment/request [post]
+func (h *Payment) request(c *fiber.Ctx) error {
+	var req request.PaymentPostRequest
+	err := c.BodyParser(&req)
+	if err != nil {
+		runtime.Logger.Warnf("parse request body failed : %s", err)
+		c.SendStatus(fiber.StatusBadRequest)
+
+		return err
+	}
+
+	id, _ := c.Locals("AuthID").(string)
+	t, _ := c.Locals("AuthType").(string)
+	if id == "" || t != "tenant" {
+		resp := utils.WrapResponse(nil)
+		resp.Code = response.CodeAuthInformationMissing
+		resp.Message = response.MsgAuthInformationMissing
+		resp.Status = fiber.StatusBadRequest
+
+		return c.Status(fiber.StatusBadRequest).JSON(resp)
+	}
+
+	if req.Amount <= 0 {
+		resp := utils.WrapResponse(nil)
+		resp.Code = response.CodeInvalidParameter
+		resp.Message = response.MsgInvalidParameter
+		resp.Status = fiber.StatusBadRequest
+
+		return c.Status(fiber.StatusBadRequest).JSON(resp)
+	}
+
+	staff, _ := c.Locals("AuthStaff").(string)
+	terminal, _ := c.Locals("AuthTerminal").(string)
+	transaction, err := h.svcTransaction.Request(c.Context(), &model.Transaction{
 		Tenant:   id,
 		Amount:   req.Amount,
 		Currency: req.Currency,
 		Detail:   req.Detail,
+		Staff:    staff,
+		Terminal: terminal,
 	})
+	if resp := currencyFailed(err); resp != nil {
+		return c.Status(resp.Status).JSON(resp)
+	}
+
 	if err != nil {
-		runtime.Logger.Warnf("create payment failed : %s", err)
+		runtime.Logger.Warnf("create payment request failed : %s", err)
 		resp := utils.WrapResponse(nil)
 		resp.Code = response.CodePaymentCreateFailed
 		resp.Message = response.MsgPaymentCreateFailed
@@ -125,6 +314,137 @@ func (h *Payment) add(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusInternalServerError).JSON(resp)
 	}
 
+	// Closed by the expiry worker at the same time
+	expiry := time.Now().Add(time.Duration(runtime.Config.Payment.TransactionTTL) * time.Minute)
+	code, err := h.svcCredential.EncodeRequest(transaction, expiry)
+	if err != nil {
+		resp := utils.WrapResponse(nil)
+		resp.Code = response.CodeEncodeFailed
+		resp.Message = response.MsgEncodeFailed
+		resp.Status = fiber.StatusInternalServerError
+
+		runtime.Logger.Errorf("encode payment request failed : %s", err)
+
+		return c.Status(fiber.StatusInternalServerError).JSON(resp)
+	}
+
+	if c.Query("img") != "" {
+		// Render image
+		png, err := qrcode.Encode(code, qrcode.High, 512)
+		if err != nil {
+			return err
+		}
+
+		c.Set("Content-Type", "image/png")
+		c.Set("X-Transaction-ID", transaction.ID)
+		c.Status(fiber.StatusCreated).Write(png)
+
+		return nil
+	}
+
+	resp := utils.WrapResponse(&response.PaymentPostRequest{
+		TransactionID: transaction.ID,
+		Request:       code,
+		ExpiresAt:     expiry.Unix(),
+	})
+	resp.Status = fiber.StatusCreated
+
+	return c.Status(fiber.StatusCreated).JSON(resp)
+}
+
+// claim: Claim payment request scanned by client
+
+// @Tags Payment
+// @Summary Claim payment request
+// @Description 客户扫描商户收款码认领订单，订单状态变为CREATED并返回金额等信息，随后通过PUT /payment/{:id}确认或放弃。已被他人认领或已关闭的订单返回HTTP 409
+// @ID PaymentPostClaim
+// @Produce json
+// @Param data body request.PaymentPostClaim true "Input information"
+// @Success 200 {object} response.PaymentGet
+// @Failure 422 string message
+// @Failure 400 {object} nil 收款码无效
+// @Failure 401 {object} nil 收款码已过期
+// @Failure 404 {object} nil 订单不存在
+// @Failure 409 {object} nil 订单已被认领或已关闭
+// @Failure 422 {object} nil 没有可结算该币种的卡片
+// @Failure 500 {object} nil
+// @Failure 403 {object} nil 角色无此权限
+// @Router /payment/claim [post]
+func (h *Payment) claim(c *fiber.Ctx) error {
+	var req request.PaymentPostClaim
+	err := c.BodyParser(&req)
+	if err != nil {
+		runtime.Logger.Warnf("parse request body failed : %s", err)
+		c.SendStatus(fiber.StatusBadRequest)
+
+		return err
+	}
+
+	id, _ := c.Locals("AuthID").(string)
+	t, _ := c.Locals("AuthType").(string)
+	if id == "" || t != "client" {
+		resp := utils.WrapResponse(nil)
+		resp.Code = response.CodeAuthInformationMissing
+		resp.Message = response.MsgAuthInformationMissing
+		resp.Status = fiber.StatusBadRequest
+
+		return c.Status(fiber.StatusBadRequest).JSON(resp)
+	}
+
+	hint, err := h.svcCredential.DecodeRequest(req.Request)
+	if errors.Is(err, service.ErrCredentialExpired) {
+		resp := utils.WrapResponse(nil)
+		resp.Code = response.CodePaymentRequestExpired
+		resp.Message = response.MsgPaymentRequestExpired
+		resp.Status = fiber.StatusUnauthorized
+
+		return c.Status(fiber.StatusUnauthorized).JSON(resp)
+	}
+
+	if err != nil {
+		runtime.Logger.Warnf("invalid payment request given by client [%s] : %s", id, err)
+		resp := utils.WrapResponse(nil)
+		resp.Code = response.CodePaymentInvalidRequest
+		resp.Message = response.MsgPaymentInvalidRequest
+		resp.Status = fiber.StatusBadRequest
+
+		return c.Status(fiber.StatusBadRequest).JSON(resp)
+	}
+
+	hint.Client = id
+	transaction, err := h.svcTransaction.Claim(c.Context(), hint)
+	if resp := currencyFailed(err); resp != nil {
+		return c.Status(resp.Status).JSON(resp)
+	}
+
+	if err != nil {
+		resp := utils.WrapResponse(nil)
+		if errors.Is(err, sql.ErrNoRows) {
+			resp.Code = response.CodeTargetNotFound
+			resp.Message = response.MsgTargetNotFound
+			resp.Status = fiber.StatusNotFound
+
+			return c.Status(fiber.StatusNotFound).JSON(resp)
+		}
+
+		var te *service.TransitionError
+		if errors.As(err, &te) {
+			runtime.Logger.Warnf("claim payment rejected : %s", err)
+			resp.Code = response.CodePaymentStatusConflict
+			resp.Message = response.MsgPaymentStatusConflict
+			resp.Status = fiber.StatusConflict
+
+			return c.Status(fiber.StatusConflict).JSON(resp)
+		}
+
+		runtime.Logger.Errorf("claim payment failed : %s", err)
+		resp.Code = response.CodePaymentUpdateFailed
+		resp.Message = response.MsgPaymentUpdateFailed
+		resp.Status = fiber.StatusInternalServerError
+
+		return c.Status(fiber.StatusInternalServerError).JSON(resp)
+	}
+
 	// Create notification
 	err = h.svcTransaction.Notify(c.Context(), transaction)
 	if err != nil {
@@ -137,19 +457,28 @@ func (h *Payment) add(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusInternalServerError).JSON(resp)
 	}
 
-	resp := utils.WrapResponse(&response.PaymentPost{
-		TransactionID: transaction.ID,
+	resp := utils.WrapResponse(&response.PaymentGet{
+		ID:              transaction.ID,
+		Client:          transaction.Client,
+		Tenant:          transaction.Tenant,
+		Amount:          transaction.Amount,
+		Refunded:        transaction.Refunded,
+		Currency:        transaction.Currency,
+		FormattedAmount: utils.FormatAmount(transaction.Amount, transaction.Currency),
+		Status:          transaction.Status,
+		Detail:          transaction.Detail,
+		Staff:           transaction.Staff,
+		Terminal:        transaction.Terminal,
 	})
-	resp.Status = fiber.StatusCreated
 
-	return c.Status(fiber.StatusCreated).JSON(resp)
+	return c.JSON(resp)
 }
 
 // update: Update payment status
 
 // @Tags Payment
 // @Summary Update payment status
-// @Description 更新支付订单状态（确认支付或放弃）
+// @Description 更新支付订单状态（确认支付或放弃），仅CREATED状态的订单可被确认或放弃，已完成的订单返回HTTP 409
 // @ID PaymentPut
 // @Produce json
 // @Param data body request.PaymentPut true "input information"
@@ -157,6 +486,12 @@ func (h *Payment) add(c *fiber.Ctx) error {
 // @Failure 422 string message
 // @Failure 400 {object} nil
 // @Failure 500 {object} nil
+// @Failure 404 {object} nil 订单不存在
+// @Failure 401 {object} nil 支付密码错误
+// @Failure 409 {object} nil 订单状态不允许此操作
+// @Failure 422 {object} nil 卡片不能结算订单币种
+// @Failure 423 {object} nil 支付密码连续错误，暂时锁定
+// @Failure 403 {object} nil 角色无此权限
 // @Router /payment/{:id} [put]
 func (h *Payment) update(c *fiber.Ctx) error {
 	var req request.PaymentPut
@@ -191,17 +526,38 @@ func (h *Payment) update(c *fiber.Ctx) error {
 
 	hint := &model.Transaction{
 		ID:     c.Params("id"),
+		Client: id,
 		Status: req.Status,
 	}
 	if req.Status == service.TransactionStatusComfirmed {
 		// Check payment password
-		checked, _ := h.svcAuth.CheckPaymentPassword(id, req.PaymentPassword)
+		checked, err := h.svcAuth.CheckPaymentPassword(c.Context(), id, req.PaymentPassword)
+		if errors.Is(err, service.ErrPaymentPasswordLocked) {
+			runtime.Logger.Warnf("client [%s] try to confirm transaction [%s] while payment locked", id, hint.ID)
+			resp := utils.WrapResponse(nil)
+			resp.Code = response.CodePaymentPasswordLocked
+			resp.Message = response.MsgPaymentPasswordLocked
+			resp.Status = fiber.StatusLocked
+
+			return c.Status(fiber.StatusLocked).JSON(resp)
+		}
+
+		if err != nil {
+			runtime.Logger.Errorf("check payment password failed : %s", err)
+			resp := utils.WrapResponse(nil)
+			resp.Code = response.CodePaymentUpdateFailed
+			resp.Message = response.MsgPaymentUpdateFailed
+			resp.Status = fiber.StatusInternalServerError
+
+			return c.Status(fiber.StatusInternalServerError).JSON(resp)
+		}
+
 		if !checked {
 			// Password mismatch
 			runtime.Logger.Warnf("client [%s] try to confirm transaction [%s] with wrong password", id, hint.ID)
 			resp := utils.WrapResponse(nil)
-			resp.Code = response.CodePaymentUpdateFailed
-			resp.Message = response.MsgPaymentUpdateFailed
+			resp.Code = response.CodePaymentWrongPassword
+			resp.Message = response.MsgPaymentWrongPassword
 			resp.Status = fiber.StatusUnauthorized
 
 			return c.Status(fiber.StatusUnauthorized).JSON(resp)
@@ -228,9 +584,31 @@ func (h *Payment) update(c *fiber.Ctx) error {
 	}
 
 	transaction, err := h.svcTransaction.Update(c.Context(), hint)
+	if resp := currencyFailed(err); resp != nil {
+		return c.Status(resp.Status).JSON(resp)
+	}
+
 	if err != nil {
-		runtime.Logger.Errorf("update payment failed : %s", err)
 		resp := utils.WrapResponse(nil)
+		if errors.Is(err, sql.ErrNoRows) {
+			resp.Code = response.CodeTargetNotFound
+			resp.Message = response.MsgTargetNotFound
+			resp.Status = fiber.StatusNotFound
+
+			return c.Status(fiber.StatusNotFound).JSON(resp)
+		}
+
+		var te *service.TransitionError
+		if errors.As(err, &te) {
+			runtime.Logger.Warnf("update payment rejected : %s", err)
+			resp.Code = response.CodePaymentStatusConflict
+			resp.Message = response.MsgPaymentStatusConflict
+			resp.Status = fiber.StatusConflict
+
+			return c.Status(fiber.StatusConflict).JSON(resp)
+		}
+
+		runtime.Logger.Errorf("update payment failed : %s", err)
 		resp.Code = response.CodePaymentUpdateFailed
 		resp.Message = response.MsgPaymentUpdateFailed
 		resp.Status = fiber.StatusInternalServerError
@@ -258,6 +636,97 @@ func (h *Payment) update(c *fiber.Ctx) error {
 	return c.JSON(resp)
 }
 
+// refund: Refund confirmed payment
+
+// @Tags Payment
+// @Summary Refund payment
+// @Description 商户（tenant）对已确认的订单发起退款，可多次部分退款，累计不超过订单金额。amount为0时退还剩余全部金额
+// @ID PaymentPostRefund
+// @Produce json
+// @Param data body request.PaymentPostRefund true "Input information"
+// @Success 201 {object} response.PaymentPostRefund
+// @Failure 422 string message
+// @Failure 400 {object} nil 退款金额无效
+// @Failure 404 {object} nil 订单不存在
+// @Failure 409 {object} nil 订单状态不允许退款
+// @Failure 500 {object} nil
+// @Failure 403 {object} nil 角色无此权限
+// @Router /payment/{:id}/refund [post]
+func (h *Payment) refund(c *fiber.Ctx) error {
+	var req request.PaymentPostRefund
+	err := c.BodyParser(&req)
+	if err != nil {
+		runtime.Logger.Warnf("parse request body failed : %s", err)
+		c.SendStatus(fiber.StatusBadRequest)
+
+		return err
+	}
+
+	id, _ := c.Locals("AuthID").(string)
+	t, _ := c.Locals("AuthType").(string)
+	if id == "" || t != "tenant" {
+		resp := utils.WrapResponse(nil)
+		resp.Code = response.CodeAuthInformationMissing
+		resp.Message = response.MsgAuthInformationMissing
+		resp.Status = fiber.StatusBadRequest
+
+		return c.Status(fiber.StatusBadRequest).JSON(resp)
+	}
+
+	refund, transaction, err := h.svcTransaction.Refund(c.Context(), &model.Refund{
+		Transaction: c.Params("id"),
+		Tenant:      id,
+		Amount:      req.Amount,
+		Reason:      req.Reason,
+	})
+	if err != nil {
+		resp := utils.WrapResponse(nil)
+		var te *service.TransitionError
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			resp.Code = response.CodeTargetNotFound
+			resp.Message = response.MsgTargetNotFound
+			resp.Status = fiber.StatusNotFound
+		case errors.Is(err, service.ErrRefundInvalidAmount):
+			resp.Code = response.CodePaymentInvalidRefund
+			resp.Message = response.MsgPaymentInvalidRefund
+			resp.Status = fiber.StatusBadRequest
+		case errors.As(err, &te), errors.Is(err, model.ErrTransactionStatusMismatch):
+			runtime.Logger.Warnf("refund payment rejected : %s", err)
+			resp.Code = response.CodePaymentStatusConflict
+			resp.Message = response.MsgPaymentStatusConflict
+			resp.Status = fiber.StatusConflict
+		default:
+			runtime.Logger.Errorf("refund payment failed : %s", err)
+			resp.Code = response.CodePaymentRefundFailed
+			resp.Message = response.MsgPaymentRefundFailed
+			resp.Status = fiber.StatusInternalServerError
+		}
+
+		return c.Status(resp.Status).JSON(resp)
+	}
+
+	// Refund notification
+	err = h.svcTransaction.Notify(c.Context(), transaction)
+	if err != nil {
+		// Refund already done, just log it
+		runtime.Logger.Errorf("payment notify failed : %s", err)
+	}
+
+	resp := utils.WrapResponse(&response.PaymentPostRefund{
+		RefundID:          refund.ID,
+		TransactionID:     transaction.ID,
+		TransactionStatus: transaction.Status,
+		Amount:            refund.Amount,
+		Refunded:          transaction.Refunded,
+		Currency:          refund.Currency,
+		FormattedAmount:   utils.FormatAmount(refund.Amount, refund.Currency),
+	})
+	resp.Status = fiber.StatusCreated
+
+	return c.Status(fiber.StatusCreated).JSON(resp)
+}
+
 // get: Get payment by id
 
 // @Tags Payment
@@ -269,6 +738,7 @@ func (h *Payment) update(c *fiber.Ctx) error {
 // @Failure 400 {object} nil
 // @Failure 500 {object} nil
 // @Failure 404 {object} nil 订单不存在
+// @Failure 403 {object} nil 角色无此权限
 // @Router /payment/{:id} [get]
 func (h *Payment) get(c *fiber.Ctx) error {
 	id, _ := c.Locals("AuthID").(string)
@@ -311,13 +781,17 @@ func (h *Payment) get(c *fiber.Ctx) error {
 	}
 
 	resp := utils.WrapResponse(&response.PaymentGet{
-		ID:       transaction.ID,
-		Client:   transaction.Client,
-		Tenant:   transaction.Tenant,
-		Amount:   transaction.Amount,
-		Currency: transaction.Currency,
-		Status:   transaction.Status,
-		Detail:   transaction.Detail,
+		ID:              transaction.ID,
+		Client:          transaction.Client,
+		Tenant:          transaction.Tenant,
+		Amount:          transaction.Amount,
+		Refunded:        transaction.Refunded,
+		Currency:        transaction.Currency,
+		FormattedAmount: utils.FormatAmount(transaction.Amount, transaction.Currency),
+		Status:          transaction.Status,
+		Detail:          transaction.Detail,
+		Staff:           transaction.Staff,
+		Terminal:        transaction.Terminal,
 	})
 
 	return c.JSON(resp)
@@ -327,12 +801,23 @@ func (h *Payment) get(c *fiber.Ctx) error {
 
 // @Tags Payment
 // @Summary Get payment list
-// @Description 获取支付订单列表，范围为当前认证者
+// @Description 获取支付订单列表，范围为当前认证者。按游标分页，next_cursor为空表示最后一页，total为符合条件的总数
 // @ID PaymentGetList
 // @Produce json
-// @Success 200 {array} response.PaymentGet
+// @Param status query string false "状态，多个以逗号分隔"
+// @Param currency query string false "币种"
+// @Param min_amount query int false "最小金额"
+// @Param max_amount query int false "最大金额"
+// @Param created_from query string false "创建时间起（含），RFC3339"
+// @Param created_to query string false "创建时间止（不含），RFC3339"
+// @Param q query string false "订单详情关键字"
+// @Param sort query string false "排序：created_at、-created_at（默认）、amount、-amount"
+// @Param limit query int false "每页数量，默认20，最大100"
+// @Param cursor query string false "上一页返回的next_cursor，须使用相同的sort，否则返回400"
+// @Success 200 {object} response.PaymentGetList
 // @Failure 400 {object} nil
 // @Failure 500 {object} nil
+// @Failure 403 {object} nil 角色无此权限
 // @Router /payment/list [get]
 func (h *Payment) list(c *fiber.Ctx) error {
 	id, _ := c.Locals("AuthID").(string)
@@ -346,16 +831,34 @@ func (h *Payment) list(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(resp)
 	}
 
-	input := &model.Transaction{
-		ID: c.Params("id"),
-	}
+	input := &model.Transaction{}
 	if t == "client" {
 		input.Client = id
 	} else {
 		input.Tenant = id
 	}
 
-	ret, err := h.svcTransaction.List(c.Context(), input)
+	filter, err := transactionFilter(c)
+	if err != nil {
+		runtime.Logger.Warnf("invalid payment list filter : %s", err)
+		resp := utils.WrapResponse(nil)
+		resp.Code = response.CodeInvalidParameter
+		resp.Message = response.MsgInvalidParameter
+		resp.Status = fiber.StatusBadRequest
+
+		return c.Status(fiber.StatusBadRequest).JSON(resp)
+	}
+
+	page, err := h.svcTransaction.List(c.Context(), input, filter, c.Query("cursor"))
+	if errors.Is(err, service.ErrInvalidCursor) {
+		resp := utils.WrapResponse(nil)
+		resp.Code = response.CodeInvalidParameter
+		resp.Message = response.MsgInvalidParameter
+		resp.Status = fiber.StatusBadRequest
+
+		return c.Status(fiber.StatusBadRequest).JSON(resp)
+	}
+
 	if err != nil {
 		runtime.Logger.Errorf("get payment list failed : %s", err)
 		resp := utils.WrapResponse(nil)
@@ -367,18 +870,23 @@ func (h *Payment) list(c *fiber.Ctx) error {
 	}
 
 	payments := &response.PaymentGetList{
-		Total: len(ret),
-		List:  make([]*response.PaymentGet, len(ret)),
+		Total:      page.Total,
+		NextCursor: page.NextCursor,
+		List:       make([]*response.PaymentGet, len(page.List)),
 	}
-	for idx, payment := range ret {
+	for idx, payment := range page.List {
 		payments.List[idx] = &response.PaymentGet{
-			ID:       payment.ID,
-			Client:   payment.Client,
-			Tenant:   payment.Tenant,
-			Amount:   payment.Amount,
-			Currency: payment.Currency,
-			Status:   payment.Status,
-			Detail:   payment.Detail,
+			ID:              payment.ID,
+			Client:          payment.Client,
+			Tenant:          payment.Tenant,
+			Amount:          payment.Amount,
+			Refunded:        payment.Refunded,
+			Currency:        payment.Currency,
+			FormattedAmount: utils.FormatAmount(payment.Amount, payment.Currency),
+			Status:          payment.Status,
+			Detail:          payment.Detail,
+			Staff:           payment.Staff,
+			Terminal:        payment.Terminal,
 		}
 	}
 
@@ -389,13 +897,18 @@ func (h *Payment) list(c *fiber.Ctx) error {
 
 // @Tags Payment
 // @Summary Get (wait) payment status event
-// @Description 获取（等待）订单状态变化。该请求为延时请求，挂起等待与当前验证者相关的订单，如果发生状态改变，返回订单信息，如果等待超时（默认30秒）则返回一个HTTP 408错误，客户端可选择重新发起一个新请求
+// @Description 获取（等待）订单状态变化。该请求为延时请求，挂起等待与当前验证者相关的订单状态事件，如果发生状态改变，返回订单信息（seq为该事件的cursor），如果等待超时（默认30秒）则返回一个HTTP 408错误，客户端可选择重新发起一个新请求。list=true时按发生顺序批量返回事件列表。每个响应（包括408）均通过X-Icepay-Cursor头返回cursor。同一验证者的多个设备各自收到全部事件。device为设备标识（1-64位字母、数字、-或_，POS终端登录时缺省为终端ID），服务端为每个设备保留投递进度，两次请求之间发生的事件不会丢失，将在下次请求时返回，每个验证者的设备数有上限，长期不活动的设备进度将被清除；since为上次收到的cursor，服务端据此从该事件之后重新投递，用于客户端未收到响应时恢复。未指定设备时从since之后投递，缺省为当前位置，客户端须每次携带上次响应的cursor，否则两次请求之间发生的事件将丢失
 // @ID PaymentGetStatus
 // @Produce json
-// @Success 200 {object} response.PaymentGet
+// @Param since query int false "Cursor of last received event, required by waiters without device"
+// @Param device query string false "Device of subscriber, keeps its own delivery progress"
+// @Param list query bool false "Events in a list, the first one only by default"
+// @Success 200 {object} response.PaymentEvent
+// @Success 200 {object} response.PaymentGetStatus "list=true"
 // @Failure 400 {object} nil
 // @Failure 500 {object} nil
 // @Failure 408 {object} nil
+// @Failure 403 {object} nil 角色无此权限
 // @Router /payment/status [get]
 func (h *Payment) status(c *fiber.Ctx) error {
 	id, _ := c.Locals("AuthID").(string)
@@ -409,9 +922,45 @@ func (h *Payment) status(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusBadRequest).JSON(resp)
 	}
 
-	transaction, err := h.svcTransaction.Wait(c.Context(), id, t)
+	var since uint64
+	if c.Query("since") != "" {
+		var err error
+		since, err = strconv.ParseUint(c.Query("since"), 10, 64)
+		if err != nil {
+			resp := utils.WrapResponse(nil)
+			resp.Code = response.CodeInvalidParameter
+			resp.Message = response.MsgInvalidParameter
+			resp.Status = fiber.StatusBadRequest
+
+			return c.Status(fiber.StatusBadRequest).JSON(resp)
+		}
+	}
+
+	// POS terminals signed in are devices themselves
+	device := c.Query("device")
+	if device == "" {
+		device, _ = c.Locals("AuthTerminal").(string)
+	}
+
+	// One event by default, as the route always responded
+	list, _ := strconv.ParseBool(c.Query("list"))
+	limit := 1
+	if list {
+		limit = 0
+	}
+
+	events, cursor, err := h.svcTransaction.Wait(c.Context(), id, t, device, since, limit)
+	c.Set(headerEventCursor, strconv.FormatUint(cursor, 10))
 	if err != nil {
 		resp := utils.WrapResponse(nil)
+		if errors.Is(err, service.ErrInvalidDevice) || errors.Is(err, service.ErrTooManyDevices) {
+			resp.Code = response.CodeInvalidParameter
+			resp.Message = err.Error()
+			resp.Status = fiber.StatusBadRequest
+
+			return c.Status(fiber.StatusBadRequest).JSON(resp)
+		}
+
 		if errors.Is(err, nats.ErrTimeout) {
 			runtime.Logger.Debugf("wait payment status timeout")
 			resp.Code = response.CodeTimeout
@@ -429,21 +978,411 @@ func (h *Payment) status(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusInternalServerError).JSON(resp)
 	}
 
-	resp := utils.WrapResponse(&response.PaymentGet{
-		ID:       transaction.ID,
-		Client:   transaction.Client,
-		Tenant:   transaction.Tenant,
-		Amount:   transaction.Amount,
-		Currency: transaction.Currency,
-		Status:   transaction.Status,
-		Detail:   transaction.Detail,
+	if !list {
+		return c.JSON(utils.WrapResponse(paymentEvent(events[0])))
+	}
+
+	ret := &response.PaymentGetStatus{
+		Total:  len(events),
+		List:   make([]*response.PaymentEvent, len(events)),
+		Cursor: cursor,
+	}
+	for idx, event := range events {
+		ret.List[idx] = paymentEvent(event)
+	}
+
+	return c.JSON(utils.WrapResponse(ret))
+}
+
+// events: Server-Sent Events of payment status
+
+// @Tags Payment
+// @Summary Stream payment status events (SSE)
+// @Description 以Server-Sent Events推送与当前验证者相关的订单状态事件，事件id为cursor，无事件时定期发送心跳注释。断线重连时浏览器自动携带Last-Event-ID头，从该事件之后继续推送；也可通过last_event_id参数指定。EventSource无法设置请求头，可通过access_token参数传递token
+// @ID PaymentGetEvents
+// @Produce text/event-stream
+// @Param last_event_id query int false "Cursor of last received event"
+// @Param access_token query string false "Access token, if Authorization header not available"
+// @Success 200 {object} response.PaymentEvent
+// @Failure 400 {object} nil
+// @Failure 500 {object} nil
+// @Failure 403 {object} nil 角色无此权限
+// @Router /payment/events [get]
+func (h *Payment) events(c *fiber.Ctx) error {
+	id, _ := c.Locals("AuthID").(string)
+	t, _ := c.Locals("AuthType").(string)
+	if id == "" || (t != "client" && t != "tenant") {
+		resp := utils.WrapResponse(nil)
+		resp.Code = response.CodeAuthInformationMissing
+		resp.Message = response.MsgAuthInformationMissing
+		resp.Status = fiber.StatusBadRequest
+
+		return c.Status(fiber.StatusBadRequest).JSON(resp)
+	}
+
+	since, err := lastEventID(c)
+	if err != nil {
+		resp := utils.WrapResponse(nil)
+		resp.Code = response.CodeInvalidParameter
+		resp.Message = response.MsgInvalidParameter
+		resp.Status = fiber.StatusBadRequest
+
+		return c.Status(fiber.StatusBadRequest).JSON(resp)
+	}
+
+	stream, err := h.svcTransaction.Subscribe(id, t, since)
+	if err != nil {
+		runtime.Logger.Errorf("subscribe payment events failed : %s", err)
+		resp := utils.WrapResponse(nil)
+		resp.Code = response.CodePaymentWaitFailed
+		resp.Message = response.MsgPaymentWaitFailed
+		resp.Status = fiber.StatusInternalServerError
+
+		return c.Status(fiber.StatusInternalServerError).JSON(resp)
+	}
+
+	c.Set(fiber.HeaderContentType, "text/event-stream")
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+	c.Set(fiber.HeaderConnection, "keep-alive")
+	c.Set("X-Accel-Buffering", "no")
+
+	heartbeat := time.Duration(runtime.Config.HTTP.EventHeartbeat) * time.Second
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer stream.Close()
+
+		fmt.Fprintf(w, "retry: %d\n\n", heartbeat.Milliseconds())
+		for {
+			err := w.Flush()
+			if err != nil {
+				// Client gone
+				return
+			}
+
+			event, err := stream.Next(heartbeat)
+			if errors.Is(err, nats.ErrTimeout) {
+				fmt.Fprint(w, ": heartbeat\n\n")
+
+				continue
+			}
+
+			if err != nil {
+				runtime.Logger.Warnf("read payment events failed : %s", err)
+
+				return
+			}
+
+			b, _ := json.Marshal(paymentEvent(event))
+			fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.Seq, event.Event, b)
+		}
 	})
 
-	return c.JSON(resp)
+	return nil
+}
+
+// ws: WebSocket of payment status
+
+// @Tags Payment
+// @Summary Stream payment status events (WebSocket)
+// @Description 以WebSocket推送与当前验证者相关的订单状态事件，每条消息为一个JSON格式的事件，seq为cursor，无事件时定期发送ping。重连时通过last_event_id参数从该事件之后继续推送。浏览器无法设置请求头，可通过access_token参数传递token
+// @ID PaymentGetWS
+// @Param last_event_id query int false "Cursor of last received event"
+// @Param access_token query string false "Access token, if Authorization header not available"
+// @Success 101 {object} response.PaymentEvent
+// @Failure 400 {object} nil
+// @Failure 426 {object} nil
+// @Failure 403 {object} nil 角色无此权限
+// @Router /payment/ws [get]
+func (h *Payment) ws(conn *websocket.Conn) {
+	id, _ := conn.Locals("AuthID").(string)
+	t, _ := conn.Locals("AuthType").(string)
+	since, _ := conn.Locals("EventSince").(uint64)
+	stream, err := h.svcTransaction.Subscribe(id, t, since)
+	if err != nil {
+		runtime.Logger.Errorf("subscribe payment events failed : %s", err)
+		conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseInternalServerErr, response.MsgPaymentWaitFailed))
+
+		return
+	}
+
+	defer stream.Close()
+
+	// Messages from client are discarded, reader handles control frames and detects close
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			_, _, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	heartbeat := time.Duration(runtime.Config.HTTP.EventHeartbeat) * time.Second
+	for {
+		select {
+		case <-closed:
+			return
+		default:
+		}
+
+		event, err := stream.Next(heartbeat)
+		if errors.Is(err, nats.ErrTimeout) {
+			err = conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(heartbeat))
+		} else if err == nil {
+			err = conn.WriteJSON(paymentEvent(event))
+		} else {
+			runtime.Logger.Warnf("read payment events failed : %s", err)
+		}
+
+		if err != nil {
+			return
+		}
+	}
+}
+
+/* }}} */
+
+/* {{{ [Middlewares] */
+
+// upgrade: checks authorization and cursor before switching to WebSocket
+func (h *Payment) upgrade(c *fiber.Ctx) error {
+	if !websocket.IsWebSocketUpgrade(c) {
+		return fiber.ErrUpgradeRequired
+	}
+
+	id, _ := c.Locals("AuthID").(string)
+	t, _ := c.Locals("AuthType").(string)
+	if id == "" || (t != "client" && t != "tenant") {
+		resp := utils.WrapResponse(nil)
+		resp.Code = response.CodeAuthInformationMissing
+		resp.Message = response.MsgAuthInformationMissing
+		resp.Status = fiber.StatusBadRequest
+
+		return c.Status(fiber.StatusBadRequest).JSON(resp)
+	}
+
+	since, err := lastEventID(c)
+	if err != nil {
+		resp := utils.WrapResponse(nil)
+		resp.Code = response.CodeInvalidParameter
+		resp.Message = response.MsgInvalidParameter
+		resp.Status = fiber.StatusBadRequest
+
+		return c.Status(fiber.StatusBadRequest).JSON(resp)
+	}
+
+	c.Locals("EventSince", since)
+
+	return c.Next()
+}
+
+// idempotent: replays the stored response of request carrying a used Idempotency-Key header
+func (h *Payment) idempotent(c *fiber.Ctx) error {
+	key := c.Get("Idempotency-Key")
+	id, _ := c.Locals("AuthID").(string)
+	t, _ := c.Locals("AuthType").(string)
+	if key == "" || id == "" {
+		return c.Next()
+	}
+
+	if len(key) > 255 {
+		resp := utils.WrapResponse(nil)
+		resp.Code = response.CodeInvalidParameter
+		resp.Message = response.MsgInvalidParameter
+		resp.Status = fiber.StatusBadRequest
+
+		return c.Status(fiber.StatusBadRequest).JSON(resp)
+	}
+
+	rec, replay, err := h.svcIdempotency.Begin(c.Context(), id, t, key, []byte(c.Method()), []byte(c.OriginalURL()), c.Body())
+	if err != nil {
+		resp := utils.WrapResponse(nil)
+		switch {
+		case errors.Is(err, service.ErrIdempotencyKeyMismatch):
+			resp.Code = response.CodeIdempotencyKeyMismatch
+			resp.Message = response.MsgIdempotencyKeyMismatch
+			resp.Status = fiber.StatusUnprocessableEntity
+		case errors.Is(err, service.ErrIdempotencyInProgress):
+			resp.Code = response.CodeIdempotencyInProgress
+			resp.Message = response.MsgIdempotencyInProgress
+			resp.Status = fiber.StatusConflict
+		default:
+			runtime.Logger.Errorf("check idempotency key failed : %s", err)
+			resp.Code = response.CodePaymentUpdateFailed
+			resp.Message = response.MsgPaymentUpdateFailed
+			resp.Status = fiber.StatusInternalServerError
+		}
+
+		return c.Status(resp.Status).JSON(resp)
+	}
+
+	if replay {
+		runtime.Logger.Debugf("replay response of idempotency key [%s] for %s [%s]", key, t, id)
+		c.Set("Idempotent-Replayed", "true")
+		c.Set(fiber.HeaderContentType, rec.ContentType)
+
+		return c.Status(rec.StatusCode).Send(rec.Response)
+	}
+
+	err = c.Next()
+	status := c.Response().StatusCode()
+	if err != nil || status >= fiber.StatusInternalServerError {
+		// Not finished, let the client retry
+		h.svcIdempotency.Abort(c.Context(), rec)
+
+		return err
+	}
+
+	body := make([]byte, len(c.Response().Body()))
+	copy(body, c.Response().Body())
+	// PNG of payment request e.g.
+	contentType := string(c.Response().Header.ContentType())
+	err = h.svcIdempotency.Finish(c.Context(), rec, status, contentType, body)
+	if err != nil {
+		runtime.Logger.Errorf("store response of idempotency key [%s] failed : %s", key, err)
+	}
+
+	return nil
 }
 
 /* }}} */
 
+// transactionFilter : conditions and order of list from query
+func transactionFilter(c *fiber.Ctx) (*model.TransactionFilter, error) {
+	var err error
+	filter := &model.TransactionFilter{
+		Currency: strings.ToUpper(c.Query("currency")),
+		Search:   strings.TrimSpace(c.Query("q")),
+		SortBy:   model.TransactionSortCreatedAt,
+		Desc:     true,
+	}
+	if v := c.Query("status"); v != "" {
+		for _, status := range strings.Split(v, ",") {
+			filter.Statuses = append(filter.Statuses, strings.ToUpper(strings.TrimSpace(status)))
+		}
+	}
+
+	if v := c.Query("min_amount"); v != "" {
+		filter.MinAmount, err = strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if v := c.Query("max_amount"); v != "" {
+		filter.MaxAmount, err = strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if v := c.Query("created_from"); v != "" {
+		filter.CreatedFrom, err = time.Parse(time.RFC3339, v)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if v := c.Query("created_to"); v != "" {
+		filter.CreatedTo, err = time.Parse(time.RFC3339, v)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if v := c.Query("limit"); v != "" {
+		filter.Limit, err = strconv.Atoi(v)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	switch c.Query("sort") {
+	case "", "-created_at":
+	case "created_at":
+		filter.Desc = false
+	case "amount":
+		filter.SortBy = model.TransactionSortAmount
+		filter.Desc = false
+	case "-amount":
+		filter.SortBy = model.TransactionSortAmount
+	default:
+		return nil, fmt.Errorf("unsupported sort [%s]", c.Query("sort"))
+	}
+
+	return filter, nil
+}
+
+// lastEventID : resume cursor of event streams, from Last-Event-ID header sent by EventSource on reconnect, or query
+func lastEventID(c *fiber.Ctx) (uint64, error) {
+	v := c.Get("Last-Event-ID")
+	if v == "" {
+		v = c.Query("last_event_id")
+	}
+
+	if v == "" {
+		return 0, nil
+	}
+
+	return strconv.ParseUint(v, 10, 64)
+}
+
+func paymentEvent(event *service.PaymentEvent) *response.PaymentEvent {
+	output := &response.PaymentEvent{
+		Seq:   event.Seq,
+		Event: event.Event,
+	}
+	if event.Credential != nil {
+		output.Credential = &response.CredentialUsed{
+			Tenant:    event.Credential.Tenant,
+			ExpiresAt: event.Credential.ExpiresAt,
+			At:        event.Credential.At,
+		}
+	}
+
+	if transaction := event.Transaction; transaction != nil {
+		output.PaymentGet = &response.PaymentGet{
+			ID:              transaction.ID,
+			Client:          transaction.Client,
+			Tenant:          transaction.Tenant,
+			Amount:          transaction.Amount,
+			Refunded:        transaction.Refunded,
+			Currency:        transaction.Currency,
+			FormattedAmount: utils.FormatAmount(transaction.Amount, transaction.Currency),
+			Status:          transaction.Status,
+			Detail:          transaction.Detail,
+			Staff:           transaction.Staff,
+			Terminal:        transaction.Terminal,
+		}
+	}
+
+	return output
+}
+
+// currencyFailed : response of currency errors, nil for others
+func currencyFailed(err error) *utils.Envelope {
+	resp := utils.WrapResponse(nil)
+	switch {
+	case errors.Is(err, service.ErrCurrencyInvalid):
+		resp.Code = response.CodePaymentInvalidCurrency
+		resp.Message = response.MsgPaymentInvalidCurrency
+		resp.Status = fiber.StatusBadRequest
+	case errors.Is(err, service.ErrCurrencyNotAccepted):
+		resp.Code = response.CodePaymentCurrencyRejected
+		resp.Message = response.MsgPaymentCurrencyRejected
+		resp.Status = fiber.StatusBadRequest
+	case errors.Is(err, service.ErrCurrencyUnsettled):
+		resp.Code = response.CodePaymentCurrencyUnsettled
+		resp.Message = response.MsgPaymentCurrencyUnsettled
+		resp.Status = fiber.StatusUnprocessableEntity
+	default:
+		return nil
+	}
+
+	return resp
+}
+
 /*
  * Local variables:
  * tab-width: 4
@@ -0,0 +1,33 @@
+/*
+ * Copyright (C) HereweTech, Inc - All Rights Reserved
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+/**
+ * @file api_key.go
+ * @package request
+ * @author Dr.NP <np@herewe.tech>
+ * @since 10/18/2026
+ */
+
+package request
+
+type APIKeyPost struct {
+	Label      string   `json:"label" xml:"label"`
+	AllowedIPs []string `json:"allowed_ips" xml:"allowed_ips"` // IPs or CIDRs, any IP allowed if empty
+}
+
+type APIKeyPut struct {
+	Label      string   `json:"label" xml:"label"`
+	AllowedIPs []string `json:"allowed_ips" xml:"allowed_ips"` // Replaced if given, empty one allows any IP
+}
+
+/*
+ * Local variables:
+ * tab-width: 4
+ * c-basic-offset: 4
+ * End:
+ * vim600: sw=4 ts=4 fdm=marker
+ * vim<600: sw=4 ts=4
+ */
@@ -14,7 +14,8 @@
 package request
 
 type CardPost struct {
-	Number string `json:"number" xml:"number"`
+	Number   string `json:"number" xml:"number"`
+	Currency string `json:"currency" xml:"currency"`
 }
 
 type CardDelete struct{}
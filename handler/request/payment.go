@@ -20,6 +20,21 @@ type PaymentPost struct {
 	Detail     string `json:"detail" xml:"detail"`
 }
 
+type PaymentPostRequest struct {
+	Amount   int64  `json:"amount" xml:"amount"`
+	Currency string `json:"currency" xml:"currency"`
+	Detail   string `json:"detail" xml:"detail"`
+}
+
+type PaymentPostClaim struct {
+	Request string `json:"request" xml:"request"`
+}
+
+type PaymentPostRefund struct {
+	Amount int64  `json:"amount" xml:"amount"`
+	Reason string `json:"reason" xml:"reason"`
+}
+
 type PaymentPut struct {
 	PaymentPassword string `json:"payment_password" xml:"payment_password"`
 	Card            string `json:"card" xml:"card"`
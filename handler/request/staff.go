@@ -0,0 +1,39 @@
+/*
+ * Copyright (C) HereweTech, Inc - All Rights Reserved
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+/**
+ * @file staff.go
+ * @package request
+ * @author Dr.NP <np@herewe.tech>
+ * @since 10/18/2026
+ */
+
+package request
+
+type StaffPost struct {
+	Email string `json:"email" xml:"email"`
+	Name  string `json:"name" xml:"name"`
+	Role  string `json:"role" xml:"role"` // tenant-cashier or tenant-readonly
+}
+
+type StaffPostAccept struct {
+	Code     string `json:"code" xml:"code"`
+	Password string `json:"password" xml:"password"`
+}
+
+type StaffPostToken struct {
+	Email    string `json:"email" xml:"email"`
+	Password string `json:"password" xml:"password"`
+}
+
+/*
+ * Local variables:
+ * tab-width: 4
+ * c-basic-offset: 4
+ * End:
+ * vim600: sw=4 ts=4 fdm=marker
+ * vim<600: sw=4 ts=4
+ */
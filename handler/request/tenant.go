@@ -20,6 +20,12 @@ type TenantPostToken struct {
 
 type TenantPostRefresh struct{}
 
+type TenantPut struct {
+	Name       string   `json:"name" xml:"name"`
+	Phone      string   `json:"phone" xml:"phone"`
+	Currencies []string `json:"currencies" xml:"currencies"` // Accepted, replaced if given
+}
+
 type TenantPutPassword struct {
 	OldPassword string `json:"old_password" xml:"old_password"`
 	NewPassword string `json:"new_password" xml:"new_password"`
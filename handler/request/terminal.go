@@ -0,0 +1,31 @@
+/*
+ * Copyright (C) HereweTech, Inc - All Rights Reserved
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+/**
+ * @file terminal.go
+ * @package request
+ * @author Dr.NP <np@herewe.tech>
+ * @since 10/18/2026
+ */
+
+package request
+
+type TerminalPost struct {
+	Label string `json:"label" xml:"label"`
+}
+
+type TerminalPostToken struct {
+	DeviceKey string `json:"device_key" xml:"device_key"`
+}
+
+/*
+ * Local variables:
+ * tab-width: 4
+ * c-basic-offset: 4
+ * End:
+ * vim600: sw=4 ts=4 fdm=marker
+ * vim<600: sw=4 ts=4
+ */
@@ -0,0 +1,35 @@
+/*
+ * Copyright (C) HereweTech, Inc - All Rights Reserved
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+/**
+ * @file webhook.go
+ * @package request
+ * @author Dr.NP <np@herewe.tech>
+ * @since 10/18/2026
+ */
+
+package request
+
+type WebhookPost struct {
+	URL         string `json:"url" xml:"url"`
+	Description string `json:"description" xml:"description"`
+}
+
+type WebhookPut struct {
+	URL          string `json:"url" xml:"url"`
+	Description  string `json:"description" xml:"description"`
+	Enabled      *bool  `json:"enabled" xml:"enabled"`
+	RotateSecret bool   `json:"rotate_secret" xml:"rotate_secret"`
+}
+
+/*
+ * Local variables:
+ * tab-width: 4
+ * c-basic-offset: 4
+ * End:
+ * vim600: sw=4 ts=4 fdm=marker
+ * vim<600: sw=4 ts=4
+ */
@@ -0,0 +1,75 @@
+/*
+ * Copyright (C) HereweTech, Inc - All Rights Reserved
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+/**
+ * @file api_key.go
+ * @package response
+ * @author Dr.NP <np@herewe.tech>
+ * @since 10/18/2026
+ */
+
+package response
+
+import "time"
+
+/* {{{ [Response codes && messages] */
+const (
+	CodeAPIKeyInvalidIP     = 18400001
+	CodeAPIKeyInvalid       = 18401001
+	CodeAPIKeyIPDenied      = 18403001
+	CodeAPIKeyDoesNotExists = 18404001
+	CodeAPIKeyCreateFailed  = 18500001
+	CodeAPIKeyGetFailed     = 18500002
+	CodeAPIKeyListFailed    = 18500003
+	CodeAPIKeyUpdateFailed  = 18500004
+	CodeAPIKeyRevokeFailed  = 18500005
+	CodeAPIKeyAuthFailed    = 18500006
+)
+
+const (
+	MsgAPIKeyInvalidIP     = "Invalid IP or CIDR of allow-list"
+	MsgAPIKeyInvalid       = "Invalid or revoked API key"
+	MsgAPIKeyIPDenied      = "IP not allowed for API key"
+	MsgAPIKeyDoesNotExists = "API key does not exists"
+	MsgAPIKeyCreateFailed  = "Create API key failed"
+	MsgAPIKeyGetFailed     = "Get API key failed"
+	MsgAPIKeyListFailed    = "List API keys failed"
+	MsgAPIKeyUpdateFailed  = "Update API key failed"
+	MsgAPIKeyRevokeFailed  = "Revoke API key failed"
+	MsgAPIKeyAuthFailed    = "Authorize API key failed"
+)
+
+/* }}} */
+
+type APIKeyGet struct {
+	ID         string    `json:"id" xml:"id"`
+	Label      string    `json:"label" xml:"label"`
+	KeyPrefix  string    `json:"key_prefix" xml:"key_prefix"`
+	AllowedIPs []string  `json:"allowed_ips" xml:"allowed_ips"`
+	Revoked    bool      `json:"revoked" xml:"revoked"`
+	LastUsedAt time.Time `json:"last_used_at" xml:"last_used_at"`
+	CreatedAt  time.Time `json:"created_at" xml:"created_at"`
+}
+
+type APIKeyGetList struct {
+	Total int          `json:"total" xml:"total"`
+	List  []*APIKeyGet `json:"list" xml:"list"`
+}
+
+// APIKeyPost : secret only returned on creation
+type APIKeyPost struct {
+	APIKeyGet
+	Secret string `json:"secret" xml:"secret"`
+}
+
+/*
+ * Local variables:
+ * tab-width: 4
+ * c-basic-offset: 4
+ * End:
+ * vim600: sw=4 ts=4 fdm=marker
+ * vim<600: sw=4 ts=4
+ */
@@ -13,33 +13,56 @@
 
 package response
 
+import "time"
+
 /* {{{ [Response codes && messages] */
 const (
-	CodeCardCreateFailed = 12500001
-	CodeCardDeleteFailed = 12500002
-	CodeCardGetFailed    = 12500003
+	CodeCardInvalidNumber     = 12400001
+	CodeCardInvalidExpiration = 12400002
+	CodeCardInvalidCVV        = 12400003
+	CodeCardInvalidCurrency   = 12400004
+	CodeCardExists            = 12409001
+	CodeCardCreateFailed      = 12500001
+	CodeCardDeleteFailed      = 12500002
+	CodeCardGetFailed         = 12500003
+	CodeCardUpdateFailed      = 12500004
 )
 
 const (
-	MsgCardCreateFailed = "Create card failed"
-	MsgCardDeleteFailed = "Delete card failed"
-	MsgCardGetFailed    = "Get card failed"
+	MsgCardInvalidNumber     = "Invalid card number"
+	MsgCardInvalidExpiration = "Invalid or past card expiration, MM/YY required"
+	MsgCardInvalidCVV        = "Invalid card CVV"
+	MsgCardInvalidCurrency   = "Invalid currency, ISO 4217 code required"
+	MsgCardExists            = "Card already added"
+	MsgCardCreateFailed      = "Create card failed"
+	MsgCardDeleteFailed      = "Delete card failed"
+	MsgCardGetFailed         = "Get card failed"
+	MsgCardUpdateFailed      = "Update card failed"
 )
 
 /* }}} */
 
 type CardPost struct {
 	ID       string `json:"id" xml:"id"`
-	Number   string `json:"number" xml:"number"`
+	Token    string `json:"token" xml:"token"`
+	BIN      string `json:"bin" xml:"bin"`
+	Last4    string `json:"last4" xml:"last4"`
 	CardType string `json:"card_type" xml:"card_type"`
+	Currency string `json:"currency" xml:"currency"`
 }
 
 type CardDelete struct{}
 
 type CardGet struct {
-	ID       string `json:"id" xml:"id"`
-	Number   string `json:"number" xml:"number"`
-	CardType string `json:"card_type" xml:"card_type"`
+	ID         string    `json:"id" xml:"id"`
+	Token      string    `json:"token" xml:"token"`
+	BIN        string    `json:"bin" xml:"bin"`
+	Last4      string    `json:"last4" xml:"last4"`
+	CardType   string    `json:"card_type" xml:"card_type"`
+	Holder     string    `json:"holder" xml:"holder"`
+	Expiration string    `json:"expiration" xml:"expiration"`
+	Currency   string    `json:"currency" xml:"currency"`
+	VerifiedAt time.Time `json:"verified_at" xml:"verified_at"`
 }
 
 type CardGetList struct {
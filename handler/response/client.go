@@ -15,19 +15,29 @@ package response
 
 /* {{{ [Response codes && messages] */
 const (
+	CodeClientInvalidPassword      = 10400001
 	CodeClientDoesNotExists        = 10401001
 	CodeClientWrongPassword        = 10401002
 	CodeClientInvalidAuthorization = 10401010
+	CodeClientRefreshReused        = 10401011
+	CodeClientTOTPNotProvisioned   = 10404001
 	CodeClientGetError             = 10500001
 	CodeClientCreateError          = 10500002
+	CodeClientUpdateError          = 10500003
+	CodeClientTOTPError            = 10500004
 )
 
 const (
+	MsgClientInvalidPassword      = "Invalid password format"
 	MsgClientDoesNotExists        = "Client does not exists"
 	MsgClientWrongPassword        = "Wrong client password"
 	MsgClientInvalidAuthorization = "Invalid authorization information"
+	MsgClientRefreshReused        = "Refresh token reused, session revoked"
+	MsgClientTOTPNotProvisioned   = "Offline payment code not provisioned"
 	MsgClientGetError             = "Get client from database error"
 	MsgClientCreateError          = "Create client error"
+	MsgClientUpdateError          = "Update client error"
+	MsgClientTOTPError            = "Provision offline payment code error"
 )
 
 /* }}} */
@@ -40,10 +50,17 @@ type ClientPostToken struct {
 	TokenType     string `json:"token_type" xml:"token_type"`
 }
 
+// ClientPostRefresh : refresh token rotated, the given one not usable anymore
 type ClientPostRefresh struct {
-	AccessToken  string `json:"access_token" xml:"access_token"`
-	AccessExpiry int64  `json:"access_expiry" xml:"access_exipry"`
-	TokenType    string `json:"token_type" xml:"token_type"`
+	AccessToken   string `json:"access_token" xml:"access_token"`
+	RefreshToken  string `json:"refresh_token" xml:"refresh_token"`
+	AccessExpiry  int64  `json:"access_expiry" xml:"access_exipry"`
+	RefreshExpiry int64  `json:"refresh_expiry" xml:"refresh_expiry"`
+	TokenType     string `json:"token_type" xml:"token_type"`
+}
+
+type ClientPostLogout struct {
+	Revoked bool `json:"revoked" xml:"revoked"`
 }
 
 type ClientPutPassword struct {
@@ -54,17 +71,40 @@ type ClientPutPaymentPassword struct {
 	Changed bool `json:"changed" xml:"changed"`
 }
 
-type ClientPut struct{}
-
-type ClientGetMe struct {
+type ClientPut struct {
 	ID    string `json:"id" xml:"id"`
 	Email string `json:"email" xml:"email"`
+	Name  string `json:"name" xml:"name"`
+	Phone string `json:"phone" xml:"phone"`
+}
+
+type ClientGetMe struct {
+	ID                 string `json:"id" xml:"id"`
+	Email              string `json:"email" xml:"email"`
+	Name               string `json:"name" xml:"name"`
+	Phone              string `json:"phone" xml:"phone"`
+	PaymentLocked      bool   `json:"payment_locked" xml:"payment_locked"`
+	PaymentLockedUntil int64  `json:"payment_locked_until,omitempty" xml:"payment_locked_until"`
 }
 
 type ClientGetCredential struct {
 	Credential string `json:"credential" xml:"credential"`
 }
 
+// ClientPostTOTP : offline code is prefix + index + TOTP(seed) of digits, seed in base32 without padding
+type ClientPostTOTP struct {
+	Seed   string `json:"seed" xml:"seed"`
+	Index  string `json:"index" xml:"index"`
+	Prefix string `json:"prefix" xml:"prefix"`
+	Digits int    `json:"digits" xml:"digits"`
+	Period int64  `json:"period" xml:"period"`
+	URI    string `json:"uri" xml:"uri"`
+}
+
+type ClientDeleteTOTP struct {
+	Revoked bool `json:"revoked" xml:"revoked"`
+}
+
 /*
  * Local variables:
  * tab-width: 4
@@ -20,11 +20,14 @@ const (
 	CodeAuthFailed             = 20401001
 	CodeAuthInternal           = 20401500
 	CodeAuthInformationMissing = 20401404
+	CodeScopeInsufficient      = 20403001
 	CodeEncodeFailed           = 20500001
 	CodeDecodeFailed           = 20500002
 	CodeFirebaseFailed         = 20500010
 	CodeTargetNotFound         = 20404001
 	CodeTimeout                = 20408001
+	CodeIdempotencyInProgress  = 20409001
+	CodeIdempotencyKeyMismatch = 20422001
 )
 
 const (
@@ -33,11 +36,14 @@ const (
 	MsgAuthFailed             = "Authorization failed"
 	MsgAuthInternal           = "Authorization internal error"
 	MsgAuthInformationMissing = "Authorization information missing"
+	MsgScopeInsufficient      = "Insufficient scope of role"
 	MsgEncodeFailed           = "Encode failed"
 	MsgDecodeFailed           = "Decode failed"
 	MsgFirebaseFailed         = "Firebase failed"
 	MsgTargetNotFound         = "Target not found"
 	MsgTimeout                = "Timeout"
+	MsgIdempotencyInProgress  = "Request with the same idempotency key in progress"
+	MsgIdempotencyKeyMismatch = "Idempotency key reused with a different request"
 )
 
 /* }}} */
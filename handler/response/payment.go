@@ -13,25 +13,55 @@
 
 package response
 
+import "time"
+
 /* {{{ [Response codes && messages] */
 const (
-	CodePaymentCreateFailed = 13500001
-	CodePaymentDeleteFailed = 13500002
-	CodePaymentUpdateFailed = 13500003
-	CodePaymentGetFailed    = 13500004
-	CodePaymentListFailed   = 13500005
-	CodePaymentNotifyFailed = 13500098
-	CodePaymentWaitFailed   = 13500099
+	CodePaymentInvalidRefund     = 13400001
+	CodePaymentInvalidCredential = 13400002
+	CodePaymentInvalidRequest    = 13400003
+	CodePaymentInvalidCurrency   = 13400004
+	CodePaymentCurrencyRejected  = 13400005
+	CodePaymentWrongPassword     = 13401001
+	CodePaymentCredentialExpired = 13401002
+	CodePaymentRequestExpired    = 13401003
+	CodePaymentStatusConflict    = 13409001
+	CodePaymentCredentialUsed    = 13409002
+	CodePaymentCurrencyUnsettled = 13422001
+	CodePaymentPasswordLocked    = 13423001
+	CodePaymentCodeLocked        = 13423002
+	CodePaymentCreateFailed      = 13500001
+	CodePaymentDeleteFailed      = 13500002
+	CodePaymentUpdateFailed      = 13500003
+	CodePaymentGetFailed         = 13500004
+	CodePaymentListFailed        = 13500005
+	CodePaymentRefundFailed      = 13500006
+	CodePaymentNotifyFailed      = 13500098
+	CodePaymentWaitFailed        = 13500099
 )
 
 const (
-	MsgPaymentCreateFailed = "Create payment failed"
-	MsgPaymentDeleteFailed = "Delete payment failed"
-	MsgPaymentUpdateFailed = "Update payment failed"
-	MsgPaymentGetFailed    = "Get payment failed"
-	MsgPaymentListFailed   = "List payment failed"
-	MsgPaymentNotifyFailed = "Notify payment failed"
-	MsgPaymentWaitFailed   = "Wait payment failed"
+	MsgPaymentInvalidRefund     = "Invalid refund amount"
+	MsgPaymentInvalidCredential = "Invalid payment credential"
+	MsgPaymentInvalidRequest    = "Invalid payment request"
+	MsgPaymentInvalidCurrency   = "Invalid currency, ISO 4217 code required"
+	MsgPaymentCurrencyRejected  = "Currency not accepted by tenant"
+	MsgPaymentWrongPassword     = "Wrong payment password"
+	MsgPaymentCredentialExpired = "Payment credential expired"
+	MsgPaymentRequestExpired    = "Payment request expired"
+	MsgPaymentStatusConflict    = "Payment status conflict"
+	MsgPaymentCredentialUsed    = "Payment credential already used"
+	MsgPaymentCurrencyUnsettled = "No card of client settles the currency"
+	MsgPaymentPasswordLocked    = "Payment password locked"
+	MsgPaymentCodeLocked        = "Offline payment code locked"
+	MsgPaymentCreateFailed      = "Create payment failed"
+	MsgPaymentDeleteFailed      = "Delete payment failed"
+	MsgPaymentUpdateFailed      = "Update payment failed"
+	MsgPaymentGetFailed         = "Get payment failed"
+	MsgPaymentListFailed        = "List payment failed"
+	MsgPaymentRefundFailed      = "Refund payment failed"
+	MsgPaymentNotifyFailed      = "Notify payment failed"
+	MsgPaymentWaitFailed        = "Wait payment failed"
 )
 
 /* }}} */
@@ -40,24 +70,69 @@ type PaymentPost struct {
 	TransactionID string `json:"transaction_id" xml:"transaction_id"`
 }
 
+type PaymentPostRequest struct {
+	TransactionID string `json:"transaction_id" xml:"transaction_id"`
+	Request       string `json:"request" xml:"request"`
+	ExpiresAt     int64  `json:"expires_at" xml:"expires_at"`
+}
+
 type PaymentPut struct {
 	TransactionID     string `json:"transaction_id" xml:"transaction_id"`
 	TransactionStatus string `json:"transaction_status" xml:"transaction_status"`
 }
 
+type PaymentPostRefund struct {
+	RefundID          string `json:"refund_id" xml:"refund_id"`
+	TransactionID     string `json:"transaction_id" xml:"transaction_id"`
+	TransactionStatus string `json:"transaction_status" xml:"transaction_status"`
+	Amount            int64  `json:"amount" xml:"amount"`
+	Refunded          int64  `json:"refunded" xml:"refunded"`
+	Currency          string `json:"currency" xml:"currency"`
+	FormattedAmount   string `json:"formatted_amount" xml:"formatted_amount"`
+}
+
+// PaymentGet : amounts in minor unit of currency, formatted one in major unit
 type PaymentGet struct {
-	ID       string `json:"id" xml:"id"`
-	Client   string `json:"client" xml:"client"`
-	Tenant   string `json:"tenant" xml:"tenant"`
-	Amount   int64  `json:"amount" xml:"amount"`
-	Currency string `json:"currency" xml:"currency"`
-	Status   string `json:"status" xml:"status"`
-	Detail   string `json:"detail" xml:"detail"`
+	ID              string `json:"id" xml:"id"`
+	Client          string `json:"client" xml:"client"`
+	Tenant          string `json:"tenant" xml:"tenant"`
+	Amount          int64  `json:"amount" xml:"amount"`
+	Refunded        int64  `json:"refunded" xml:"refunded"`
+	Currency        string `json:"currency" xml:"currency"`
+	FormattedAmount string `json:"formatted_amount" xml:"formatted_amount"`
+	Status          string `json:"status" xml:"status"`
+	Detail          string `json:"detail" xml:"detail"`
+	Staff           string `json:"staff,omitempty" xml:"staff,omitempty"`       // Staff of tenant created it
+	Terminal        string `json:"terminal,omitempty" xml:"terminal,omitempty"` // Terminal created on
 }
 
+// PaymentGetList : one page, total counts all pages
 type PaymentGetList struct {
-	List  []*PaymentGet `json:"list" xml:"list"`
-	Total int           `json:"total" xml:"total"`
+	List       []*PaymentGet `json:"list" xml:"list"`
+	Total      int           `json:"total" xml:"total"`
+	NextCursor string        `json:"next_cursor" xml:"next_cursor"`
+}
+
+// PaymentEvent : payment.status carries the payment, credential.used carries the credential
+type PaymentEvent struct {
+	*PaymentGet
+	Seq        uint64          `json:"seq" xml:"seq"`
+	Event      string          `json:"event" xml:"event"`
+	Credential *CredentialUsed `json:"credential,omitempty" xml:"credential,omitempty"`
+}
+
+// CredentialUsed : consumed payment credential presented again by tenant
+type CredentialUsed struct {
+	Tenant    string    `json:"tenant" xml:"tenant"`
+	ExpiresAt time.Time `json:"expires_at" xml:"expires_at"`
+	At        time.Time `json:"at" xml:"at"`
+}
+
+// PaymentGetStatus : events in order, cursor is the seq of the last one
+type PaymentGetStatus struct {
+	List   []*PaymentEvent `json:"list" xml:"list"`
+	Total  int             `json:"total" xml:"total"`
+	Cursor uint64          `json:"cursor" xml:"cursor"`
 }
 
 /*
@@ -0,0 +1,79 @@
+/*
+ * Copyright (C) HereweTech, Inc - All Rights Reserved
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+/**
+ * @file settlement.go
+ * @package response
+ * @author Dr.NP <np@herewe.tech>
+ * @since 10/18/2026
+ */
+
+package response
+
+import "time"
+
+/* {{{ [Response codes && messages] */
+const (
+	CodeSettlementInvalidDate   = 15400001
+	CodeSettlementInvalidFormat = 15400002
+	CodeSettlementDoesNotExists = 15404001
+	CodeSettlementListFailed    = 15500001
+	CodeSettlementGetFailed     = 15500002
+)
+
+const (
+	MsgSettlementInvalidDate   = "Invalid date, YYYY-MM-DD required"
+	MsgSettlementInvalidFormat = "Invalid statement format, csv or json required"
+	MsgSettlementDoesNotExists = "Settlement does not exists"
+	MsgSettlementListFailed    = "List settlements failed"
+	MsgSettlementGetFailed     = "Get settlement failed"
+)
+
+/* }}} */
+
+// SettlementGet : amounts in minor unit, net = gross - refunds - fees
+type SettlementGet struct {
+	ID          string    `json:"id" xml:"id"`
+	Currency    string    `json:"currency" xml:"currency"`
+	Date        string    `json:"date" xml:"date"`
+	Payments    int       `json:"payments" xml:"payments"`
+	RefundCount int       `json:"refund_count" xml:"refund_count"`
+	Gross       int64     `json:"gross" xml:"gross"`
+	Refunds     int64     `json:"refunds" xml:"refunds"`
+	Fees        int64     `json:"fees" xml:"fees"`
+	Net         int64     `json:"net" xml:"net"`
+	CreatedAt   time.Time `json:"created_at" xml:"created_at"`
+}
+
+type SettlementGetList struct {
+	Total int              `json:"total" xml:"total"`
+	List  []*SettlementGet `json:"list" xml:"list"`
+}
+
+// SettlementItem : refund lines carry negative amount and net
+type SettlementItem struct {
+	Kind        string    `json:"kind" xml:"kind"`
+	Reference   string    `json:"reference" xml:"reference"`
+	Transaction string    `json:"transaction" xml:"transaction"`
+	Amount      int64     `json:"amount" xml:"amount"`
+	Fee         int64     `json:"fee" xml:"fee"`
+	Net         int64     `json:"net" xml:"net"`
+	OccurredAt  time.Time `json:"occurred_at" xml:"occurred_at"`
+}
+
+type SettlementStatement struct {
+	SettlementGet
+	Items []*SettlementItem `json:"items" xml:"items"`
+}
+
+/*
+ * Local variables:
+ * tab-width: 4
+ * c-basic-offset: 4
+ * End:
+ * vim600: sw=4 ts=4 fdm=marker
+ * vim<600: sw=4 ts=4
+ */
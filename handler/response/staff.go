@@ -0,0 +1,83 @@
+/*
+ * Copyright (C) HereweTech, Inc - All Rights Reserved
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+/**
+ * @file staff.go
+ * @package response
+ * @author Dr.NP <np@herewe.tech>
+ * @since 10/18/2026
+ */
+
+package response
+
+import "time"
+
+/* {{{ [Response codes && messages] */
+const (
+	CodeStaffInvalidEmail      = 16400001
+	CodeStaffInvalidRole       = 16400002
+	CodeStaffInvalidPassword   = 16400003
+	CodeStaffInvitationInvalid = 16401001
+	CodeStaffWrongPassword     = 16401002
+	CodeStaffDoesNotExists     = 16404001
+	CodeStaffExists            = 16409001
+	CodeStaffCreateFailed      = 16500001
+	CodeStaffGetFailed         = 16500002
+	CodeStaffListFailed        = 16500003
+	CodeStaffDisableFailed     = 16500004
+)
+
+const (
+	MsgStaffInvalidEmail      = "Invalid email of staff"
+	MsgStaffInvalidRole       = "Role not grantable to staff, tenant-cashier or tenant-readonly required"
+	MsgStaffInvalidPassword   = "Invalid password format"
+	MsgStaffInvitationInvalid = "Invitation invalid or expired"
+	MsgStaffWrongPassword     = "Wrong email or password of staff"
+	MsgStaffDoesNotExists     = "Staff does not exists"
+	MsgStaffExists            = "Staff of email exists"
+	MsgStaffCreateFailed      = "Invite staff failed"
+	MsgStaffGetFailed         = "Get staff failed"
+	MsgStaffListFailed        = "List staff failed"
+	MsgStaffDisableFailed     = "Disable staff failed"
+)
+
+/* }}} */
+
+type StaffGet struct {
+	ID              string    `json:"id" xml:"id"`
+	Email           string    `json:"email" xml:"email"`
+	Name            string    `json:"name" xml:"name"`
+	Role            string    `json:"role" xml:"role"`
+	Status          string    `json:"status" xml:"status"`
+	InviteExpiresAt time.Time `json:"invite_expires_at" xml:"invite_expires_at"`
+	CreatedAt       time.Time `json:"created_at" xml:"created_at"`
+}
+
+type StaffGetList struct {
+	Total int         `json:"total" xml:"total"`
+	List  []*StaffGet `json:"list" xml:"list"`
+}
+
+// StaffPost : invitation code only returned on invitation
+type StaffPost struct {
+	StaffGet
+	InviteCode string `json:"invite_code" xml:"invite_code"`
+}
+
+type StaffPostAccept struct {
+	ID     string `json:"id" xml:"id"`
+	Email  string `json:"email" xml:"email"`
+	Status string `json:"status" xml:"status"`
+}
+
+/*
+ * Local variables:
+ * tab-width: 4
+ * c-basic-offset: 4
+ * End:
+ * vim600: sw=4 ts=4 fdm=marker
+ * vim<600: sw=4 ts=4
+ */
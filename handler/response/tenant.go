@@ -15,17 +15,25 @@ package response
 
 /* {{{ [Response codes && messages] */
 const (
+	CodeTenantInvalidPassword      = 11400001
+	CodeTenantInvalidCurrency      = 11400002
 	CodeTenantDoesNotExists        = 11401001
 	CodeTenantWrongPassword        = 11401002
 	CodeTenantInvalidAuthorization = 11401010
+	CodeTenantRefreshReused        = 11401011
 	CodeTenantGetError             = 11500001
+	CodeTenantUpdateError          = 11500002
 )
 
 const (
+	MsgTenantInvalidPassword      = "Invalid password format"
+	MsgTenantInvalidCurrency      = "Invalid currency, ISO 4217 code required"
 	MsgTenantDoesNotExists        = "Tenant does not exists"
 	MsgTenantWrongPassword        = "Wrong tenant password"
 	MsgTenantInvalidAuthorization = "Invalid authorization information"
+	MsgTenantRefreshReused        = "Refresh token reused, session revoked"
 	MsgTenantGetError             = "Get tenant from database error"
+	MsgTenantUpdateError          = "Update tenant error"
 )
 
 /* }}} */
@@ -38,10 +46,25 @@ type TenantPostToken struct {
 	TokenType     string `json:"token_type" xml:"token_type"`
 }
 
+// TenantPostRefresh : refresh token rotated, the given one not usable anymore
 type TenantPostRefresh struct {
-	AccessToken  string `json:"access_token" xml:"access_token"`
-	AccessExpiry int64  `json:"access_expiry" xml:"access_exipry"`
-	TokenType    string `json:"token_type" xml:"token_type"`
+	AccessToken   string `json:"access_token" xml:"access_token"`
+	RefreshToken  string `json:"refresh_token" xml:"refresh_token"`
+	AccessExpiry  int64  `json:"access_expiry" xml:"access_exipry"`
+	RefreshExpiry int64  `json:"refresh_expiry" xml:"refresh_expiry"`
+	TokenType     string `json:"token_type" xml:"token_type"`
+}
+
+type TenantPostLogout struct {
+	Revoked bool `json:"revoked" xml:"revoked"`
+}
+
+type TenantPut struct {
+	ID         string   `json:"id" xml:"id"`
+	Email      string   `json:"email" xml:"email"`
+	Name       string   `json:"name" xml:"name"`
+	Phone      string   `json:"phone" xml:"phone"`
+	Currencies []string `json:"currencies" xml:"currencies"`
 }
 
 type TenantPutPassword struct {
@@ -0,0 +1,66 @@
+/*
+ * Copyright (C) HereweTech, Inc - All Rights Reserved
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+/**
+ * @file terminal.go
+ * @package response
+ * @author Dr.NP <np@herewe.tech>
+ * @since 10/18/2026
+ */
+
+package response
+
+import "time"
+
+/* {{{ [Response codes && messages] */
+const (
+	CodeTerminalKeyInvalid    = 17401001
+	CodeTerminalDoesNotExists = 17404001
+	CodeTerminalCreateFailed  = 17500001
+	CodeTerminalGetFailed     = 17500002
+	CodeTerminalListFailed    = 17500003
+	CodeTerminalDisableFailed = 17500004
+)
+
+const (
+	MsgTerminalKeyInvalid    = "Invalid or disabled device key"
+	MsgTerminalDoesNotExists = "Terminal does not exists"
+	MsgTerminalCreateFailed  = "Register terminal failed"
+	MsgTerminalGetFailed     = "Get terminal failed"
+	MsgTerminalListFailed    = "List terminals failed"
+	MsgTerminalDisableFailed = "Disable terminal failed"
+)
+
+/* }}} */
+
+type TerminalGet struct {
+	ID         string    `json:"id" xml:"id"`
+	Label      string    `json:"label" xml:"label"`
+	KeyPrefix  string    `json:"key_prefix" xml:"key_prefix"`
+	Enabled    bool      `json:"enabled" xml:"enabled"`
+	LastUsedAt time.Time `json:"last_used_at" xml:"last_used_at"`
+	CreatedAt  time.Time `json:"created_at" xml:"created_at"`
+}
+
+type TerminalGetList struct {
+	Total int            `json:"total" xml:"total"`
+	List  []*TerminalGet `json:"list" xml:"list"`
+}
+
+// TerminalPost : device key only returned on registration
+type TerminalPost struct {
+	TerminalGet
+	DeviceKey string `json:"device_key" xml:"device_key"`
+}
+
+/*
+ * Local variables:
+ * tab-width: 4
+ * c-basic-offset: 4
+ * End:
+ * vim600: sw=4 ts=4 fdm=marker
+ * vim<600: sw=4 ts=4
+ */
@@ -0,0 +1,101 @@
+/*
+ * Copyright (C) HereweTech, Inc - All Rights Reserved
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+/**
+ * @file webhook.go
+ * @package response
+ * @author Dr.NP <np@herewe.tech>
+ * @since 10/18/2026
+ */
+
+package response
+
+import "time"
+
+/* {{{ [Response codes && messages] */
+const (
+	CodeWebhookInvalidURL       = 14400001
+	CodeWebhookForbiddenHost    = 14400002
+	CodeWebhookDoesNotExists    = 14404001
+	CodeWebhookDeliveryNotFound = 14404002
+	CodeWebhookDeliveryBusy     = 14409001
+	CodeWebhookCreateFailed     = 14500001
+	CodeWebhookDeleteFailed     = 14500002
+	CodeWebhookGetFailed        = 14500003
+	CodeWebhookUpdateFailed     = 14500004
+	CodeWebhookListFailed       = 14500005
+	CodeWebhookRedeliverFailed  = 14500006
+)
+
+const (
+	MsgWebhookInvalidURL       = "Invalid webhook URL, http or https required"
+	MsgWebhookForbiddenHost    = "Webhook host not allowed, loopback, link-local or private address"
+	MsgWebhookDoesNotExists    = "Webhook does not exists"
+	MsgWebhookDeliveryNotFound = "Webhook delivery does not exists"
+	MsgWebhookDeliveryBusy     = "Webhook delivery in progress"
+	MsgWebhookCreateFailed     = "Create webhook failed"
+	MsgWebhookDeleteFailed     = "Delete webhook failed"
+	MsgWebhookGetFailed        = "Get webhook failed"
+	MsgWebhookUpdateFailed     = "Update webhook failed"
+	MsgWebhookListFailed       = "List webhooks failed"
+	MsgWebhookRedeliverFailed  = "Redeliver webhook failed"
+)
+
+/* }}} */
+
+type WebhookGet struct {
+	ID          string    `json:"id" xml:"id"`
+	URL         string    `json:"url" xml:"url"`
+	Description string    `json:"description" xml:"description"`
+	Enabled     bool      `json:"enabled" xml:"enabled"`
+	CreatedAt   time.Time `json:"created_at" xml:"created_at"`
+}
+
+type WebhookGetList struct {
+	Total int           `json:"total" xml:"total"`
+	List  []*WebhookGet `json:"list" xml:"list"`
+}
+
+// WebhookPost : secret only returned on creation and rotation
+type WebhookPost struct {
+	WebhookGet
+	Secret string `json:"secret" xml:"secret"`
+}
+
+type WebhookPut struct {
+	WebhookGet
+	Secret string `json:"secret,omitempty" xml:"secret,omitempty"`
+}
+
+type WebhookDelete struct {
+	ID string `json:"id" xml:"id"`
+}
+
+type WebhookDelivery struct {
+	ID             string    `json:"id" xml:"id"`
+	Transaction    string    `json:"transaction" xml:"transaction"`
+	Event          string    `json:"event" xml:"event"`
+	Status         string    `json:"status" xml:"status"`
+	Attempts       int       `json:"attempts" xml:"attempts"`
+	NextAttemptAt  time.Time `json:"next_attempt_at" xml:"next_attempt_at"`
+	LastStatusCode int       `json:"last_status_code" xml:"last_status_code"`
+	LastError      string    `json:"last_error" xml:"last_error"`
+	CreatedAt      time.Time `json:"created_at" xml:"created_at"`
+}
+
+type WebhookGetDeliveries struct {
+	Total int                `json:"total" xml:"total"`
+	List  []*WebhookDelivery `json:"list" xml:"list"`
+}
+
+/*
+ * Local variables:
+ * tab-width: 4
+ * c-basic-offset: 4
+ * End:
+ * vim600: sw=4 ts=4 fdm=marker
+ * vim<600: sw=4 ts=4
+ */
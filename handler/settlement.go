@@ -0,0 +1,305 @@
+/*
+ * Copyright (C) HereweTech, Inc - All Rights Reserved
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+/**
+ * @file settlement.go
+ * @package handler
+ * @author Dr.NP <np@herewe.tech>
+ * @since 10/18/2026
+ */
+
+package handler
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"icepay-svc/handler/response"
+	"icepay-svc/model"
+	"icepay-svc/runtime"
+	"icepay-svc/service"
+	"icepay-svc/utils"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type Settlement struct {
+	svcSettlement *service.Settlement
+}
+
+// InitSettlement : mounts settlement routes on router of tenant, authorization applied by parent group
+func InitSettlement(router fiber.Router) *Settlement {
+	h := new(Settlement)
+
+	router.Get("/list", requireScopes(service.ScopeSettlementRead), h.list).Name("SettlementGetList")
+	router.Get("/:id", requireScopes(service.ScopeSettlementRead), h.get).Name("SettlementGet")
+	router.Get("/:id/statement", requireScopes(service.ScopeSettlementRead), h.statement).Name("SettlementGetStatement")
+
+	h.svcSettlement = service.NewSettlement()
+
+	return h
+}
+
+/* {{{ [Routers] - Definitions */
+
+// list: List settlement batches
+
+// @Tags Settlement
+// @Summary Get settlement list
+// @Description 获取结算批次列表，每个批次为一个商户一种币种一天（按确认支付时间）的汇总，净额 = 交易总额 - 退款 - 手续费，金额单位为最小货币单位
+// @ID SettlementGetList
+// @Produce json
+// @Param from query string false "起始日期（含），YYYY-MM-DD"
+// @Param to query string false "截止日期（含），YYYY-MM-DD"
+// @Param currency query string false "币种"
+// @Success 200 {object} response.SettlementGetList
+// @Failure 400 {object} nil
+// @Failure 500 {object} nil
+// @Failure 403 {object} nil 角色无此权限
+// @Router /tenant/settlements/list [get]
+func (h *Settlement) list(c *fiber.Ctx) error {
+	id, _ := c.Locals("AuthID").(string)
+	t, _ := c.Locals("AuthType").(string)
+	if id == "" || t != "tenant" {
+		resp := utils.WrapResponse(nil)
+		resp.Code = response.CodeAuthInformationMissing
+		resp.Message = response.MsgAuthInformationMissing
+		resp.Status = fiber.StatusBadRequest
+
+		return c.Status(fiber.StatusBadRequest).JSON(resp)
+	}
+
+	var from, to time.Time
+	var err error
+	if v := c.Query("from"); v != "" {
+		from, err = h.svcSettlement.ParseDate(v)
+	}
+
+	if v := c.Query("to"); v != "" && err == nil {
+		to, err = h.svcSettlement.ParseDate(v)
+	}
+
+	if err != nil {
+		resp := utils.WrapResponse(nil)
+		resp.Code = response.CodeSettlementInvalidDate
+		resp.Message = response.MsgSettlementInvalidDate
+		resp.Status = fiber.StatusBadRequest
+
+		return c.Status(fiber.StatusBadRequest).JSON(resp)
+	}
+
+	settlements, err := h.svcSettlement.List(c.Context(), &model.Settlement{
+		Tenant:   id,
+		Currency: strings.ToUpper(c.Query("currency")),
+	}, from, to)
+	if err != nil {
+		return h.failed(c, err, response.CodeSettlementListFailed, response.MsgSettlementListFailed)
+	}
+
+	ret := &response.SettlementGetList{
+		Total: len(settlements),
+		List:  make([]*response.SettlementGet, len(settlements)),
+	}
+	for idx, settlement := range settlements {
+		ret.List[idx] = settlementGet(settlement)
+	}
+
+	return c.JSON(utils.WrapResponse(ret))
+}
+
+// get: Get settlement batch with statement lines
+
+// @Tags Settlement
+// @Summary Get settlement
+// @Description 获取结算批次及明细，退款明细金额为负数
+// @ID SettlementGet
+// @Produce json
+// @Param id path string true "Settlement ID"
+// @Success 200 {object} response.SettlementStatement
+// @Failure 400 {object} nil
+// @Failure 404 {object} nil 结算批次不存在
+// @Failure 500 {object} nil
+// @Failure 403 {object} nil 角色无此权限
+// @Router /tenant/settlements/{id} [get]
+func (h *Settlement) get(c *fiber.Ctx) error {
+	id, _ := c.Locals("AuthID").(string)
+	t, _ := c.Locals("AuthType").(string)
+	if id == "" || t != "tenant" {
+		resp := utils.WrapResponse(nil)
+		resp.Code = response.CodeAuthInformationMissing
+		resp.Message = response.MsgAuthInformationMissing
+		resp.Status = fiber.StatusBadRequest
+
+		return c.Status(fiber.StatusBadRequest).JSON(resp)
+	}
+
+	statement, err := h.settlementStatement(c, id)
+	if err != nil {
+		return h.failed(c, err, response.CodeSettlementGetFailed, response.MsgSettlementGetFailed)
+	}
+
+	return c.JSON(utils.WrapResponse(statement))
+}
+
+// statement: Download statement of settlement batch
+
+// @Tags Settlement
+// @Summary Download settlement statement
+// @Description 下载结算单，format为csv（默认）或json
+// @ID SettlementGetStatement
+// @Produce text/csv
+// @Produce json
+// @Param id path string true "Settlement ID"
+// @Param format query string false "csv or json"
+// @Success 200 string csv
+// @Failure 400 {object} nil
+// @Failure 404 {object} nil 结算批次不存在
+// @Failure 500 {object} nil
+// @Failure 403 {object} nil 角色无此权限
+// @Router /tenant/settlements/{id}/statement [get]
+func (h *Settlement) statement(c *fiber.Ctx) error {
+	id, _ := c.Locals("AuthID").(string)
+	t, _ := c.Locals("AuthType").(string)
+	if id == "" || t != "tenant" {
+		resp := utils.WrapResponse(nil)
+		resp.Code = response.CodeAuthInformationMissing
+		resp.Message = response.MsgAuthInformationMissing
+		resp.Status = fiber.StatusBadRequest
+
+		return c.Status(fiber.StatusBadRequest).JSON(resp)
+	}
+
+	format := strings.ToLower(c.Query("format", "csv"))
+	if format != "csv" && format != "json" {
+		resp := utils.WrapResponse(nil)
+		resp.Code = response.CodeSettlementInvalidFormat
+		resp.Message = response.MsgSettlementInvalidFormat
+		resp.Status = fiber.StatusBadRequest
+
+		return c.Status(fiber.StatusBadRequest).JSON(resp)
+	}
+
+	statement, err := h.settlementStatement(c, id)
+	if err != nil {
+		return h.failed(c, err, response.CodeSettlementGetFailed, response.MsgSettlementGetFailed)
+	}
+
+	filename := fmt.Sprintf("settlement-%s-%s-%s.%s", statement.Date, statement.Currency, statement.ID, format)
+	c.Set(fiber.HeaderContentDisposition, `attachment; filename="`+filename+`"`)
+	if format == "json" {
+		return c.JSON(statement)
+	}
+
+	c.Set(fiber.HeaderContentType, "text/csv; charset=utf-8")
+	w := csv.NewWriter(c)
+	w.Write([]string{"kind", "reference", "transaction", "occurred_at", "currency", "amount", "fee", "net"})
+	for _, item := range statement.Items {
+		w.Write([]string{
+			item.Kind,
+			item.Reference,
+			item.Transaction,
+			item.OccurredAt.Format(time.RFC3339),
+			statement.Currency,
+			strconv.FormatInt(item.Amount, 10),
+			strconv.FormatInt(item.Fee, 10),
+			strconv.FormatInt(item.Net, 10),
+		})
+	}
+
+	// Totals of batch
+	w.Write([]string{
+		"TOTAL",
+		statement.ID,
+		"",
+		statement.Date,
+		statement.Currency,
+		strconv.FormatInt(statement.Gross-statement.Refunds, 10),
+		strconv.FormatInt(statement.Fees, 10),
+		strconv.FormatInt(statement.Net, 10),
+	})
+	w.Flush()
+
+	return w.Error()
+}
+
+/* }}} */
+
+func (h *Settlement) settlementStatement(c *fiber.Ctx, tenant string) (*response.SettlementStatement, error) {
+	settlement, err := h.svcSettlement.Get(c.Context(), &model.Settlement{
+		ID:     c.Params("id"),
+		Tenant: tenant,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	items, err := h.svcSettlement.Items(c.Context(), settlement)
+	if err != nil {
+		return nil, err
+	}
+
+	statement := &response.SettlementStatement{
+		SettlementGet: *settlementGet(settlement),
+		Items:         make([]*response.SettlementItem, len(items)),
+	}
+	for idx, item := range items {
+		statement.Items[idx] = &response.SettlementItem{
+			Kind:        item.Kind,
+			Reference:   item.Reference,
+			Transaction: item.Transaction,
+			Amount:      item.Amount,
+			Fee:         item.Fee,
+			Net:         item.Net,
+			OccurredAt:  item.OccurredAt,
+		}
+	}
+
+	return statement, nil
+}
+
+func (h *Settlement) failed(c *fiber.Ctx, err error, code int, msg string) error {
+	resp := utils.WrapResponse(nil)
+	if errors.Is(err, sql.ErrNoRows) {
+		resp.Code = response.CodeSettlementDoesNotExists
+		resp.Message = response.MsgSettlementDoesNotExists
+		resp.Status = fiber.StatusNotFound
+	} else {
+		runtime.Logger.Errorf("settlement operation failed : %s", err)
+		resp.Code = code
+		resp.Message = msg
+		resp.Status = fiber.StatusInternalServerError
+	}
+
+	return c.Status(resp.Status).JSON(resp)
+}
+
+func settlementGet(settlement *model.Settlement) *response.SettlementGet {
+	return &response.SettlementGet{
+		ID:          settlement.ID,
+		Currency:    settlement.Currency,
+		Date:        settlement.Date.Format(service.SettlementDateLayout),
+		Payments:    settlement.Payments,
+		RefundCount: settlement.RefundCount,
+		Gross:       settlement.Gross,
+		Refunds:     settlement.Refunds,
+		Fees:        settlement.Fees,
+		Net:         settlement.Net,
+		CreatedAt:   settlement.CreatedAt,
+	}
+}
+
+/*
+ * Local variables:
+ * tab-width: 4
+ * c-basic-offset: 4
+ * End:
+ * vim600: sw=4 ts=4 fdm=marker
+ * vim<600: sw=4 ts=4
+ */
@@ -0,0 +1,276 @@
+/*
+ * Copyright (C) HereweTech, Inc - All Rights Reserved
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+/**
+ * @file staff.go
+ * @package handler
+ * @author Dr.NP <np@herewe.tech>
+ * @since 10/18/2026
+ */
+
+package handler
+
+import (
+	"database/sql"
+	"errors"
+	"icepay-svc/handler/request"
+	"icepay-svc/handler/response"
+	"icepay-svc/model"
+	"icepay-svc/runtime"
+	"icepay-svc/service"
+	"icepay-svc/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type Staff struct {
+	svcStaff *service.Staff
+}
+
+// InitStaff : mounts staff routes on router of tenant, authorization applied by parent group.
+// Staff sign in by routes of tenant
+func InitStaff(router fiber.Router) *Staff {
+	h := new(Staff)
+
+	router.Post("/", requireScopes(service.ScopeStaffWrite), h.invite).Name("StaffPost")
+	router.Get("/list", requireScopes(service.ScopeStaffRead), h.list).Name("StaffGetList")
+	router.Get("/:id", requireScopes(service.ScopeStaffRead), h.get).Name("StaffGet")
+	router.Post("/:id/disable", requireScopes(service.ScopeStaffWrite), h.disable).Name("StaffPostDisable")
+
+	h.svcStaff = service.NewStaff()
+
+	return h
+}
+
+/* {{{ [Routers] - Definitions */
+
+// invite: Invite staff
+
+// @Tags Staff
+// @Summary Invite staff
+// @Description 邀请员工子账号，role为tenant-cashier（收银，可创建和查看订单）或tenant-readonly（只读）。返回的invite_code仅显示一次，由tenant转交员工，员工通过/tenant/staff/accept设置密码后以/tenant/staff/token登录。邮箱在未禁用的员工中唯一
+// @ID StaffPost
+// @Produce json
+// @Param data body request.StaffPost true "Input information"
+// @Success 201 {object} response.StaffPost
+// @Failure 422 string message
+// @Failure 400 {object} nil
+// @Failure 409 {object} nil 邮箱已被其他员工使用
+// @Failure 500 {object} nil
+// @Failure 403 {object} nil 角色无此权限
+// @Router /tenant/staff [post]
+func (h *Staff) invite(c *fiber.Ctx) error {
+	var req request.StaffPost
+	err := c.BodyParser(&req)
+	if err != nil {
+		runtime.Logger.Warnf("parse request body failed : %s", err)
+		c.SendStatus(fiber.StatusBadRequest)
+
+		return err
+	}
+
+	id, _ := c.Locals("AuthID").(string)
+	t, _ := c.Locals("AuthType").(string)
+	if id == "" || t != "tenant" {
+		resp := utils.WrapResponse(nil)
+		resp.Code = response.CodeAuthInformationMissing
+		resp.Message = response.MsgAuthInformationMissing
+		resp.Status = fiber.StatusBadRequest
+
+		return c.Status(fiber.StatusBadRequest).JSON(resp)
+	}
+
+	staff, code, err := h.svcStaff.Invite(c.Context(), &model.Staff{
+		Tenant: id,
+		Email:  req.Email,
+		Name:   req.Name,
+		Role:   req.Role,
+	})
+	if err != nil {
+		return staffFailed(c, err, response.CodeStaffCreateFailed, response.MsgStaffCreateFailed)
+	}
+
+	resp := utils.WrapResponse(&response.StaffPost{
+		StaffGet:   *staffGet(staff),
+		InviteCode: code,
+	})
+	resp.Status = fiber.StatusCreated
+
+	return c.Status(fiber.StatusCreated).JSON(resp)
+}
+
+// list: List staff
+
+// @Tags Staff
+// @Summary List staff
+// @Description 获取当前tenant的员工列表，包括已禁用的员工
+// @ID StaffGetList
+// @Produce json
+// @Success 200 {object} response.StaffGetList
+// @Failure 400 {object} nil
+// @Failure 500 {object} nil
+// @Failure 403 {object} nil 角色无此权限
+// @Router /tenant/staff/list [get]
+func (h *Staff) list(c *fiber.Ctx) error {
+	id, _ := c.Locals("AuthID").(string)
+	t, _ := c.Locals("AuthType").(string)
+	if id == "" || t != "tenant" {
+		resp := utils.WrapResponse(nil)
+		resp.Code = response.CodeAuthInformationMissing
+		resp.Message = response.MsgAuthInformationMissing
+		resp.Status = fiber.StatusBadRequest
+
+		return c.Status(fiber.StatusBadRequest).JSON(resp)
+	}
+
+	ret, err := h.svcStaff.List(c.Context(), &model.Staff{Tenant: id})
+	if err != nil {
+		return staffFailed(c, err, response.CodeStaffListFailed, response.MsgStaffListFailed)
+	}
+
+	staff := &response.StaffGetList{
+		Total: len(ret),
+		List:  make([]*response.StaffGet, len(ret)),
+	}
+	for idx, s := range ret {
+		staff.List[idx] = staffGet(s)
+	}
+
+	return c.JSON(utils.WrapResponse(staff))
+}
+
+// get: Get staff
+
+// @Tags Staff
+// @Summary Get staff
+// @Description 获取员工信息
+// @ID StaffGet
+// @Produce json
+// @Success 200 {object} response.StaffGet
+// @Failure 400 {object} nil
+// @Failure 404 {object} nil
+// @Failure 500 {object} nil
+// @Failure 403 {object} nil 角色无此权限
+// @Router /tenant/staff/{:id} [get]
+func (h *Staff) get(c *fiber.Ctx) error {
+	id, _ := c.Locals("AuthID").(string)
+	t, _ := c.Locals("AuthType").(string)
+	if id == "" || t != "tenant" {
+		resp := utils.WrapResponse(nil)
+		resp.Code = response.CodeAuthInformationMissing
+		resp.Message = response.MsgAuthInformationMissing
+		resp.Status = fiber.StatusBadRequest
+
+		return c.Status(fiber.StatusBadRequest).JSON(resp)
+	}
+
+	staff, err := h.svcStaff.Get(c.Context(), &model.Staff{
+		ID:     c.Params("id"),
+		Tenant: id,
+	})
+	if err != nil {
+		return staffFailed(c, err, response.CodeStaffGetFailed, response.MsgStaffGetFailed)
+	}
+
+	return c.JSON(utils.WrapResponse(staffGet(staff)))
+}
+
+// disable: Disable staff
+
+// @Tags Staff
+// @Summary Disable staff
+// @Description 禁用员工，员工的会话全部撤销，已签发的access_token立即失效，未接受的邀请同时失效。禁用后该邮箱可再次邀请
+// @ID StaffPostDisable
+// @Produce json
+// @Success 200 {object} response.StaffGet
+// @Failure 400 {object} nil
+// @Failure 404 {object} nil
+// @Failure 500 {object} nil
+// @Failure 403 {object} nil 角色无此权限
+// @Router /tenant/staff/{:id}/disable [post]
+func (h *Staff) disable(c *fiber.Ctx) error {
+	id, _ := c.Locals("AuthID").(string)
+	t, _ := c.Locals("AuthType").(string)
+	if id == "" || t != "tenant" {
+		resp := utils.WrapResponse(nil)
+		resp.Code = response.CodeAuthInformationMissing
+		resp.Message = response.MsgAuthInformationMissing
+		resp.Status = fiber.StatusBadRequest
+
+		return c.Status(fiber.StatusBadRequest).JSON(resp)
+	}
+
+	staff, err := h.svcStaff.Disable(c.Context(), &model.Staff{
+		ID:     c.Params("id"),
+		Tenant: id,
+	})
+	if err != nil {
+		return staffFailed(c, err, response.CodeStaffDisableFailed, response.MsgStaffDisableFailed)
+	}
+
+	return c.JSON(utils.WrapResponse(staffGet(staff)))
+}
+
+/* }}} */
+
+// staffFailed : responses error of staff operations, shared by sign-in routes of tenant
+func staffFailed(c *fiber.Ctx, err error, code int, msg string) error {
+	resp := utils.WrapResponse(nil)
+	switch {
+	case errors.Is(err, service.ErrStaffInvalidEmail):
+		resp.Code = response.CodeStaffInvalidEmail
+		resp.Message = response.MsgStaffInvalidEmail
+		resp.Status = fiber.StatusBadRequest
+	case errors.Is(err, service.ErrStaffInvalidRole):
+		resp.Code = response.CodeStaffInvalidRole
+		resp.Message = response.MsgStaffInvalidRole
+		resp.Status = fiber.StatusBadRequest
+	case errors.Is(err, service.ErrStaffInvalidPassword):
+		resp.Code = response.CodeStaffInvalidPassword
+		resp.Message = response.MsgStaffInvalidPassword
+		resp.Status = fiber.StatusBadRequest
+	case errors.Is(err, service.ErrStaffInvitationInvalid):
+		resp.Code = response.CodeStaffInvitationInvalid
+		resp.Message = response.MsgStaffInvitationInvalid
+		resp.Status = fiber.StatusUnauthorized
+	case errors.Is(err, model.ErrStaffExists):
+		resp.Code = response.CodeStaffExists
+		resp.Message = response.MsgStaffExists
+		resp.Status = fiber.StatusConflict
+	case errors.Is(err, sql.ErrNoRows), errors.Is(err, model.ErrStaffDoesNotExists):
+		resp.Code = response.CodeStaffDoesNotExists
+		resp.Message = response.MsgStaffDoesNotExists
+		resp.Status = fiber.StatusNotFound
+	default:
+		runtime.Logger.Errorf("staff operation failed : %s", err)
+		resp.Code = code
+		resp.Message = msg
+		resp.Status = fiber.StatusInternalServerError
+	}
+
+	return c.Status(resp.Status).JSON(resp)
+}
+
+func staffGet(staff *model.Staff) *response.StaffGet {
+	return &response.StaffGet{
+		ID:              staff.ID,
+		Email:           staff.Email,
+		Name:            staff.Name,
+		Role:            staff.Role,
+		Status:          staff.Status,
+		InviteExpiresAt: staff.InviteExpiresAt,
+		CreatedAt:       staff.CreatedAt,
+	}
+}
+
+/*
+ * Local variables:
+ * tab-width: 4
+ * c-basic-offset: 4
+ * End:
+ * vim600: sw=4 ts=4 fdm=marker
+ * vim<600: sw=4 ts=4
+ */
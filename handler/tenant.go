@@ -14,6 +14,7 @@
 package handler
 
 import (
+	"database/sql"
 	"errors"
 	"icepay-svc/handler/request"
 	"icepay-svc/handler/response"
@@ -21,8 +22,6 @@ import (
 	"icepay-svc/runtime"
 	"icepay-svc/service"
 	"icepay-svc/utils"
-	"strings"
-	"time"
 
 	"github.com/gofiber/fiber/v2"
 	jwtware "github.com/gofiber/jwt/v3"
@@ -30,24 +29,43 @@ import (
 )
 
 type Tenant struct {
-	svcAuth *service.Auth
+	svcAuth     *service.Auth
+	svcTenant   *service.Tenant
+	svcStaff    *service.Staff
+	svcTerminal *service.Terminal
 }
 
 func InitTenant() *Tenant {
 	h := new(Tenant)
 
+	h.svcAuth = service.NewAuth()
+
 	tenantG := runtime.Server.Group("/tenant")
 	tenantG.Post("/token", h.token).Name("TenantPostToken")
 	tenantG.Post("/refresh", h.refresh).Name("TenantPostRefresh")
+	tenantG.Post("/logout", h.logout).Name("TenantPostLogout")
+	tenantG.Post("/staff/accept", h.staffAccept).Name("TenantPostStaffAccept")
+	tenantG.Post("/staff/token", h.staffToken).Name("TenantPostStaffToken")
+	tenantG.Post("/terminals/token", h.terminalToken).Name("TenantPostTerminalToken")
 	tenantG.Use(jwtware.New(jwtware.Config{
-		SigningKey:     []byte(runtime.Config.Auth.JWTRefreshSecret),
+		KeyFunc:        h.svcAuth.KeyFunc,
 		SuccessHandler: jwtSuccessHandler,
 		ErrorHandler:   jwtErrorHandler,
 	}))
-	tenantG.Put("/password", h.changePassword).Name("TenantPutPassword")
-	tenantG.Get("/me", h.me).Name("TenantGetMe")
+	tenantG.Put("/", requireScopes(service.ScopeAccountWrite), h.update).Name("TenantPut")
+	tenantG.Put("/password", requireScopes(service.ScopeAccountWrite), h.changePassword).Name("TenantPutPassword")
+	tenantG.Get("/me", requireScopes(service.ScopeAccountRead), h.me).Name("TenantGetMe")
 
-	h.svcAuth = service.NewAuth()
+	// Sub resources
+	InitWebhook(tenantG.Group("/webhooks"))
+	InitSettlement(tenantG.Group("/settlements"))
+	InitStaff(tenantG.Group("/staff"))
+	InitTerminal(tenantG.Group("/terminals"))
+	InitAPIKey(tenantG.Group("/api-keys"))
+
+	h.svcTenant = service.NewTenant()
+	h.svcStaff = service.NewStaff()
+	h.svcTerminal = service.NewTerminal()
 
 	return h
 }
@@ -124,21 +142,13 @@ func (h *Tenant) token(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusUnauthorized).JSON(resp)
 	}
 
-	// AccessToken
-	jwtAccess, errAccess := h.svcAuth.JWTSign(&service.Sign{
-		Sub:       tnt.ID,
-		Name:      tnt.Email,
-		Type:      "tenant",
-		ExpiresIn: time.Duration(runtime.Config.Auth.JWTAccessExpiry) * time.Minute,
-	})
-	// RefreshToken
-	jwtRefresh, errRefresh := h.svcAuth.JWTSign(&service.Sign{
-		Sub:       tnt.ID,
-		Name:      tnt.Email,
-		Type:      "client::refresh",
-		ExpiresIn: time.Duration(runtime.Config.Auth.JWTRefreshExpiry) * time.Minute,
-	})
-	if errAccess != nil || errRefresh != nil {
+	tokens, err := h.svcAuth.Login(c.Context(), &model.Session{
+		Subject:     tnt.ID,
+		SubjectType: "tenant",
+		Role:        service.RoleTenantOwner,
+	}, tnt.Email)
+	if err != nil {
+		runtime.Logger.Errorf("open session of tenant [%s] failed : %s", tnt.ID, err)
 		resp := utils.WrapResponse(nil)
 		resp.Status = fiber.StatusInternalServerError
 		resp.Code = response.CodeAuthInternal
@@ -148,10 +158,10 @@ func (h *Tenant) token(c *fiber.Ctx) error {
 	}
 
 	resp := utils.WrapResponse(&response.ClientPostToken{
-		AccessToken:   jwtAccess.Token,
-		RefreshToken:  jwtRefresh.Token,
-		AccessExpiry:  jwtAccess.Expiry,
-		RefreshExpiry: jwtRefresh.Expiry,
+		AccessToken:   tokens.Access.Token,
+		RefreshToken:  tokens.Refresh.Token,
+		AccessExpiry:  tokens.Access.Expiry,
+		RefreshExpiry: tokens.Refresh.Expiry,
 		TokenType:     "bearer",
 	})
 
@@ -162,7 +172,7 @@ func (h *Tenant) token(c *fiber.Ctx) error {
 
 // @Tags Tenant
 // @Summary Refresh access_token via refresh_token
-// @Description 使用refresh_token获取新的access_token，避免客户端重复登录
+// @Description 使用refresh_token（Authorization: Bearer）获取新的access_token，避免客户端重复登录。refresh_token每次使用后轮换，须保存本次返回的新refresh_token，旧的随即失效；再次使用已轮换的refresh_token视为泄露，整个会话被撤销，需重新登录
 // @ID TenantPostRefresh
 // @Produce json
 // @Success 201 {object} response.TenantPostRefresh
@@ -170,8 +180,8 @@ func (h *Tenant) token(c *fiber.Ctx) error {
 // @Failure 500 {object} nil
 // @Router /tenant/refresh [post]
 func (h *Tenant) refresh(c *fiber.Ctx) error {
-	auth := c.Get("Authorization")
-	if len(auth) < 8 || strings.ToLower(auth[0:7]) != "bearer " {
+	claims, err := h.svcAuth.JWTValid(bearerToken(c), "tenant"+service.TokenTypeRefreshSuffix)
+	if err != nil {
 		resp := utils.WrapResponse(nil)
 		resp.Code = response.CodeTenantInvalidAuthorization
 		resp.Message = response.MsgTenantInvalidAuthorization
@@ -180,39 +190,265 @@ func (h *Tenant) refresh(c *fiber.Ctx) error {
 		return c.Status(fiber.StatusUnauthorized).JSON(resp)
 	}
 
-	refreshToken := auth[7:]
-	claims, err := h.svcAuth.JWTValid(refreshToken)
+	// Rotate, sessions are revoked on password change
+	tokens, err := h.svcAuth.Refresh(c.Context(), claims)
+	if err != nil {
+		return h.sessionFailed(c, err)
+	}
+
+	resp := utils.WrapResponse(&response.TenantPostRefresh{
+		AccessToken:   tokens.Access.Token,
+		RefreshToken:  tokens.Refresh.Token,
+		AccessExpiry:  tokens.Access.Expiry,
+		RefreshExpiry: tokens.Refresh.Expiry,
+		TokenType:     "bearer",
+	})
+
+	return c.JSON(resp)
+}
+
+// logout: Revoke session
+
+// @Tags Tenant
+// @Summary Logout
+// @Description 撤销refresh_token（Authorization: Bearer）所属的会话，该会话的refresh_token与已签发的access_token立即失效
+// @ID TenantPostLogout
+// @Produce json
+// @Success 200 {object} response.TenantPostLogout
+// @Failure 401 {object} nil
+// @Failure 500 {object} nil
+// @Router /tenant/logout [post]
+func (h *Tenant) logout(c *fiber.Ctx) error {
+	claims, err := h.svcAuth.JWTValid(bearerToken(c), "tenant"+service.TokenTypeRefreshSuffix)
 	if err != nil {
 		resp := utils.WrapResponse(nil)
-		resp.Code = response.CodeAuthInternal
-		resp.Message = response.MsgAuthInternal
+		resp.Code = response.CodeTenantInvalidAuthorization
+		resp.Message = response.MsgTenantInvalidAuthorization
 		resp.Status = fiber.StatusUnauthorized
 
 		return c.Status(fiber.StatusUnauthorized).JSON(resp)
 	}
 
-	// Re-sign token
-	id, _ := claims["sub"].(string)
-	name, _ := claims["name"].(string)
-	jwt, err := h.svcAuth.JWTSign(&service.Sign{
-		Sub:       id,
-		Name:      name,
-		Type:      "tenant",
-		ExpiresIn: time.Duration(runtime.Config.Auth.JWTAccessExpiry) * time.Minute,
+	err = h.svcAuth.Logout(c.Context(), claims)
+	if err != nil {
+		return h.sessionFailed(c, err)
+	}
+
+	resp := utils.WrapResponse(&response.TenantPostLogout{
+		Revoked: true,
 	})
+
+	return c.JSON(resp)
+}
+
+// staffAccept: Accept invitation of staff
+
+// @Tags Tenant
+// @Summary Accept invitation of staff
+// @Description 员工使用tenant转交的invite_code设置登录密码，邀请码仅可使用一次，过期或员工被禁用后失效
+// @ID TenantPostStaffAccept
+// @Produce json
+// @Param data body request.StaffPostAccept true "Input information"
+// @Success 200 {object} response.StaffPostAccept
+// @Failure 422 string message
+// @Failure 400 {object} nil
+// @Failure 401 {object} nil 邀请码无效或已过期
+// @Failure 500 {object} nil
+// @Router /tenant/staff/accept [post]
+func (h *Tenant) staffAccept(c *fiber.Ctx) error {
+	var req request.StaffPostAccept
+	err := c.BodyParser(&req)
+	if err != nil {
+		runtime.Logger.Warnf("parse request body failed : %s", err)
+		c.SendStatus(fiber.StatusBadRequest)
+
+		return err
+	}
+
+	staff, err := h.svcStaff.Accept(c.Context(), req.Code, req.Password)
 	if err != nil {
+		return staffFailed(c, err, response.CodeStaffGetFailed, response.MsgStaffGetFailed)
+	}
+
+	return c.JSON(utils.WrapResponse(&response.StaffPostAccept{
+		ID:     staff.ID,
+		Email:  staff.Email,
+		Status: staff.Status,
+	}))
+}
+
+// staffToken: Get JWT token of staff
+
+// @Tags Tenant
+// @Summary Get authorize token of staff
+// @Description 员工以邮箱和密码登录，获取代表所属tenant的access_token和refresh_token，权限由员工角色决定，token中staff为员工ID。refresh和logout与tenant相同
+// @ID TenantPostStaffToken
+// @Produce json
+// @Param data body request.StaffPostToken true "Input information"
+// @Success 201 {object} response.TenantPostToken
+// @Failure 422 string message
+// @Failure 400 {object} nil
+// @Failure 401 {object} nil
+// @Failure 500 {object} nil
+// @Router /tenant/staff/token [post]
+func (h *Tenant) staffToken(c *fiber.Ctx) error {
+	var req request.StaffPostToken
+	err := c.BodyParser(&req)
+	if err != nil {
+		runtime.Logger.Warnf("parse request body failed: %s", err)
+
+		return err
+	}
+
+	if req.Email == "" || req.Password == "" {
 		resp := utils.WrapResponse(nil)
+		resp.Status = fiber.StatusBadRequest
+		resp.Code = response.CodeInvalidEmailOrPassword
+		resp.Message = response.MsgInvalidEmailOrPassword
+
+		return c.Status(fiber.StatusBadRequest).JSON(resp)
+	}
+
+	staff, err := h.svcStaff.Authenticate(c.Context(), req.Email, req.Password)
+	if errors.Is(err, sql.ErrNoRows) || errors.Is(err, service.ErrStaffWrongPassword) {
+		runtime.Logger.Warnf("wrong email or password given for staff [%s]", req.Email)
+
+		resp := utils.WrapResponse(nil)
+		resp.Status = fiber.StatusUnauthorized
+		resp.Code = response.CodeStaffWrongPassword
+		resp.Message = response.MsgStaffWrongPassword
+
+		return c.Status(fiber.StatusUnauthorized).JSON(resp)
+	}
+
+	if err != nil {
+		return staffFailed(c, err, response.CodeStaffGetFailed, response.MsgStaffGetFailed)
+	}
+
+	tokens, err := h.svcAuth.Login(c.Context(), &model.Session{
+		Subject:     staff.Tenant,
+		SubjectType: "tenant",
+		Role:        staff.Role,
+		Staff:       staff.ID,
+	}, staff.Email)
+	if err != nil {
+		return h.sessionFailed(c, err)
+	}
+
+	return h.tokens(c, tokens)
+}
+
+// terminalToken: Get JWT token of terminal
+
+// @Tags Tenant
+// @Summary Get authorize token of POS terminal
+// @Description POS终端以device_key登录，获取代表所属tenant的access_token和refresh_token，角色为tenant-cashier，token中terminal为终端ID。终端被禁用后device_key失效
+// @ID TenantPostTerminalToken
+// @Produce json
+// @Param data body request.TerminalPostToken true "Input information"
+// @Success 201 {object} response.TenantPostToken
+// @Failure 422 string message
+// @Failure 401 {object} nil
+// @Failure 500 {object} nil
+// @Router /tenant/terminals/token [post]
+func (h *Tenant) terminalToken(c *fiber.Ctx) error {
+	var req request.TerminalPostToken
+	err := c.BodyParser(&req)
+	if err != nil {
+		runtime.Logger.Warnf("parse request body failed: %s", err)
+
+		return err
+	}
+
+	terminal, err := h.svcTerminal.Authenticate(c.Context(), req.DeviceKey)
+	if err != nil {
+		return terminalFailed(c, err, response.CodeTerminalGetFailed, response.MsgTerminalGetFailed)
+	}
+
+	tokens, err := h.svcAuth.Login(c.Context(), &model.Session{
+		Subject:     terminal.Tenant,
+		SubjectType: "tenant",
+		Role:        service.RoleTerminal,
+		Terminal:    terminal.ID,
+	}, terminal.Label)
+	if err != nil {
+		return h.sessionFailed(c, err)
+	}
+
+	return h.tokens(c, tokens)
+}
+
+// update: Update tenant
+
+// @Tags Tenant
+// @Summary Update tenant
+// @Description 更新tenant资料（名称、电话、接受的币种）。currencies为ISO 4217代码列表，提交时整体替换，空列表表示仅接受默认币种
+// @ID TenantPut
+// @Produce json
+// @Param data body request.TenantPut true "input information"
+// @Success 200 {object} response.TenantPut
+// @Failure 422 string message
+// @Failure 400 {object} nil
+// @Failure 500 {object} nil
+// @Failure 403 {object} nil 角色无此权限
+// @Router /tenant [put]
+func (h *Tenant) update(c *fiber.Ctx) error {
+	var req request.TenantPut
+	err := c.BodyParser(&req)
+	if err != nil {
+		runtime.Logger.Warnf("parse request body failed : %s", err)
+		c.SendStatus(fiber.StatusBadRequest)
+
+		return err
+	}
+
+	id, _ := c.Locals("AuthID").(string)
+	t, _ := c.Locals("AuthType").(string)
+	if id == "" || t != "tenant" {
+		resp := utils.WrapResponse(nil)
+		resp.Code = response.CodeAuthInformationMissing
+		resp.Message = response.MsgAuthInformationMissing
+		resp.Status = fiber.StatusBadRequest
+
+		return c.Status(fiber.StatusBadRequest).JSON(resp)
+	}
+
+	tnt, err := h.svcTenant.Update(c.Context(), &model.Tenant{
+		ID:         id,
+		Name:       req.Name,
+		Phone:      req.Phone,
+		Currencies: req.Currencies,
+	})
+	if errors.Is(err, service.ErrCurrencyInvalid) {
+		resp := utils.WrapResponse(nil)
+		resp.Code = response.CodeTenantInvalidCurrency
+		resp.Message = response.MsgTenantInvalidCurrency
+		resp.Status = fiber.StatusBadRequest
+
+		return c.Status(fiber.StatusBadRequest).JSON(resp)
+	}
+
+	if err != nil {
+		runtime.Logger.Errorf("update tenant failed : %s", err)
+		resp := utils.WrapResponse(nil)
+		resp.Code = response.CodeTenantUpdateError
+		resp.Message = response.MsgTenantUpdateError
 		resp.Status = fiber.StatusInternalServerError
-		resp.Code = response.CodeAuthInternal
-		resp.Message = response.MsgAuthInternal
 
 		return c.Status(fiber.StatusInternalServerError).JSON(resp)
 	}
 
-	resp := utils.WrapResponse(&response.TenantPostRefresh{
-		AccessToken:  jwt.Token,
-		AccessExpiry: jwt.Expiry,
-		TokenType:    "bearer",
+	currencies := tnt.Currencies
+	if len(currencies) == 0 {
+		currencies = []string{runtime.Config.Payment.DefaultCurrency}
+	}
+
+	resp := utils.WrapResponse(&response.TenantPut{
+		ID:         tnt.ID,
+		Email:      tnt.Email,
+		Name:       tnt.Name,
+		Phone:      tnt.Phone,
+		Currencies: currencies,
 	})
 
 	return c.JSON(resp)
@@ -222,7 +458,7 @@ func (h *Tenant) refresh(c *fiber.Ctx) error {
 
 // @Tags Tenant
 // @Summary Change password
-// @Description 修改登录密码
+// @Description 修改登录密码，修改后之前的会话全部撤销，已签发的refresh_token与access_token立即失效（员工与终端的会话不受影响）
 // @ID TenantPutPassword
 // @Produce json
 // @Param data body request.TenantPutPassword true "input information"
@@ -231,9 +467,56 @@ func (h *Tenant) refresh(c *fiber.Ctx) error {
 // @Failure 400 {object} nil
 // @Failure 401 {object} nil
 // @Failure 500 {object} nil
+// @Failure 403 {object} nil 角色无此权限
 // @Router /tenant/password [put]
 func (h *Tenant) changePassword(c *fiber.Ctx) error {
-	return nil
+	var req request.TenantPutPassword
+	err := c.BodyParser(&req)
+	if err != nil {
+		runtime.Logger.Warnf("parse request body failed : %s", err)
+		c.SendStatus(fiber.StatusBadRequest)
+
+		return err
+	}
+
+	id, _ := c.Locals("AuthID").(string)
+	t, _ := c.Locals("AuthType").(string)
+	if id == "" || t != "tenant" {
+		resp := utils.WrapResponse(nil)
+		resp.Code = response.CodeAuthInformationMissing
+		resp.Message = response.MsgAuthInformationMissing
+		resp.Status = fiber.StatusBadRequest
+
+		return c.Status(fiber.StatusBadRequest).JSON(resp)
+	}
+
+	err = h.svcTenant.ChangePassword(c.Context(), id, req.OldPassword, req.NewPassword)
+	if err != nil {
+		resp := utils.WrapResponse(nil)
+		switch {
+		case errors.Is(err, service.ErrTenantWrongPassword):
+			resp.Code = response.CodeTenantWrongPassword
+			resp.Message = response.MsgTenantWrongPassword
+			resp.Status = fiber.StatusUnauthorized
+		case errors.Is(err, service.ErrTenantInvalidPassword):
+			resp.Code = response.CodeTenantInvalidPassword
+			resp.Message = response.MsgTenantInvalidPassword
+			resp.Status = fiber.StatusBadRequest
+		default:
+			runtime.Logger.Errorf("change tenant password failed : %s", err)
+			resp.Code = response.CodeTenantUpdateError
+			resp.Message = response.MsgTenantUpdateError
+			resp.Status = fiber.StatusInternalServerError
+		}
+
+		return c.Status(resp.Status).JSON(resp)
+	}
+
+	resp := utils.WrapResponse(&response.TenantPutPassword{
+		Changed: true,
+	})
+
+	return c.JSON(resp)
 }
 
 // me: Get myself
@@ -245,6 +528,7 @@ func (h *Tenant) changePassword(c *fiber.Ctx) error {
 // @Produce json
 // @Success 200 {object} nil
 // @Failure 500 {object} nil
+// @Failure 403 {object} nil 角色无此权限
 // @Router /tenant/me [get]
 func (h *Tenant) me(c *fiber.Ctx) error {
 	user, ok := c.Locals("user").(*jwt.Token)
@@ -264,6 +548,40 @@ func (h *Tenant) me(c *fiber.Ctx) error {
 
 /* }}} */
 
+// tokens : responses tokens of new session
+func (h *Tenant) tokens(c *fiber.Ctx, tokens *service.Tokens) error {
+	resp := utils.WrapResponse(&response.TenantPostToken{
+		AccessToken:   tokens.Access.Token,
+		RefreshToken:  tokens.Refresh.Token,
+		AccessExpiry:  tokens.Access.Expiry,
+		RefreshExpiry: tokens.Refresh.Expiry,
+		TokenType:     "bearer",
+	})
+
+	return c.JSON(resp)
+}
+
+func (h *Tenant) sessionFailed(c *fiber.Ctx, err error) error {
+	resp := utils.WrapResponse(nil)
+	switch {
+	case errors.Is(err, service.ErrSessionReused):
+		resp.Code = response.CodeTenantRefreshReused
+		resp.Message = response.MsgTenantRefreshReused
+		resp.Status = fiber.StatusUnauthorized
+	case errors.Is(err, service.ErrSessionInvalid), errors.Is(err, sql.ErrNoRows):
+		resp.Code = response.CodeTenantInvalidAuthorization
+		resp.Message = response.MsgTenantInvalidAuthorization
+		resp.Status = fiber.StatusUnauthorized
+	default:
+		runtime.Logger.Errorf("tenant session operation failed : %s", err)
+		resp.Code = response.CodeAuthInternal
+		resp.Message = response.MsgAuthInternal
+		resp.Status = fiber.StatusInternalServerError
+	}
+
+	return c.Status(resp.Status).JSON(resp)
+}
+
 /*
  * Local variables:
  * tab-width: 4
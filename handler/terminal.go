@@ -0,0 +1,256 @@
+/*
+ * Copyright (C) HereweTech, Inc - All Rights Reserved
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+/**
+ * @file terminal.go
+ * @package handler
+ * @author Dr.NP <np@herewe.tech>
+ * @since 10/18/2026
+ */
+
+package handler
+
+import (
+	"database/sql"
+	"errors"
+	"icepay-svc/handler/request"
+	"icepay-svc/handler/response"
+	"icepay-svc/model"
+	"icepay-svc/runtime"
+	"icepay-svc/service"
+	"icepay-svc/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type Terminal struct {
+	svcTerminal *service.Terminal
+}
+
+// InitTerminal : mounts terminal routes on router of tenant, authorization applied by parent group.
+// Terminals sign in by routes of tenant
+func InitTerminal(router fiber.Router) *Terminal {
+	h := new(Terminal)
+
+	router.Post("/", requireScopes(service.ScopeTerminalWrite), h.register).Name("TerminalPost")
+	router.Get("/list", requireScopes(service.ScopeTerminalRead), h.list).Name("TerminalGetList")
+	router.Get("/:id", requireScopes(service.ScopeTerminalRead), h.get).Name("TerminalGet")
+	router.Post("/:id/disable", requireScopes(service.ScopeTerminalWrite), h.disable).Name("TerminalPostDisable")
+
+	h.svcTerminal = service.NewTerminal()
+
+	return h
+}
+
+/* {{{ [Routers] - Definitions */
+
+// register: Register POS terminal
+
+// @Tags Terminal
+// @Summary Register POS terminal
+// @Description 登记POS终端，返回的device_key仅显示一次，终端以此通过/tenant/terminals/token登录，无需密码。终端以收银角色（tenant-cashier）代表tenant创建订单，订单记录终端ID
+// @ID TerminalPost
+// @Produce json
+// @Param data body request.TerminalPost true "Input information"
+// @Success 201 {object} response.TerminalPost
+// @Failure 422 string message
+// @Failure 400 {object} nil
+// @Failure 500 {object} nil
+// @Failure 403 {object} nil 角色无此权限
+// @Router /tenant/terminals [post]
+func (h *Terminal) register(c *fiber.Ctx) error {
+	var req request.TerminalPost
+	err := c.BodyParser(&req)
+	if err != nil {
+		runtime.Logger.Warnf("parse request body failed : %s", err)
+		c.SendStatus(fiber.StatusBadRequest)
+
+		return err
+	}
+
+	id, _ := c.Locals("AuthID").(string)
+	t, _ := c.Locals("AuthType").(string)
+	if id == "" || t != "tenant" {
+		resp := utils.WrapResponse(nil)
+		resp.Code = response.CodeAuthInformationMissing
+		resp.Message = response.MsgAuthInformationMissing
+		resp.Status = fiber.StatusBadRequest
+
+		return c.Status(fiber.StatusBadRequest).JSON(resp)
+	}
+
+	terminal, key, err := h.svcTerminal.Register(c.Context(), &model.Terminal{
+		Tenant: id,
+		Label:  req.Label,
+	})
+	if err != nil {
+		return terminalFailed(c, err, response.CodeTerminalCreateFailed, response.MsgTerminalCreateFailed)
+	}
+
+	resp := utils.WrapResponse(&response.TerminalPost{
+		TerminalGet: *terminalGet(terminal),
+		DeviceKey:   key,
+	})
+	resp.Status = fiber.StatusCreated
+
+	return c.Status(fiber.StatusCreated).JSON(resp)
+}
+
+// list: List terminals
+
+// @Tags Terminal
+// @Summary List POS terminals
+// @Description 获取当前tenant的POS终端列表，包括已禁用的终端
+// @ID TerminalGetList
+// @Produce json
+// @Success 200 {object} response.TerminalGetList
+// @Failure 400 {object} nil
+// @Failure 500 {object} nil
+// @Failure 403 {object} nil 角色无此权限
+// @Router /tenant/terminals/list [get]
+func (h *Terminal) list(c *fiber.Ctx) error {
+	id, _ := c.Locals("AuthID").(string)
+	t, _ := c.Locals("AuthType").(string)
+	if id == "" || t != "tenant" {
+		resp := utils.WrapResponse(nil)
+		resp.Code = response.CodeAuthInformationMissing
+		resp.Message = response.MsgAuthInformationMissing
+		resp.Status = fiber.StatusBadRequest
+
+		return c.Status(fiber.StatusBadRequest).JSON(resp)
+	}
+
+	ret, err := h.svcTerminal.List(c.Context(), &model.Terminal{Tenant: id})
+	if err != nil {
+		return terminalFailed(c, err, response.CodeTerminalListFailed, response.MsgTerminalListFailed)
+	}
+
+	terminals := &response.TerminalGetList{
+		Total: len(ret),
+		List:  make([]*response.TerminalGet, len(ret)),
+	}
+	for idx, terminal := range ret {
+		terminals.List[idx] = terminalGet(terminal)
+	}
+
+	return c.JSON(utils.WrapResponse(terminals))
+}
+
+// get: Get terminal
+
+// @Tags Terminal
+// @Summary Get POS terminal
+// @Description 获取POS终端信息，包括最近使用时间
+// @ID TerminalGet
+// @Produce json
+// @Success 200 {object} response.TerminalGet
+// @Failure 400 {object} nil
+// @Failure 404 {object} nil
+// @Failure 500 {object} nil
+// @Failure 403 {object} nil 角色无此权限
+// @Router /tenant/terminals/{:id} [get]
+func (h *Terminal) get(c *fiber.Ctx) error {
+	id, _ := c.Locals("AuthID").(string)
+	t, _ := c.Locals("AuthType").(string)
+	if id == "" || t != "tenant" {
+		resp := utils.WrapResponse(nil)
+		resp.Code = response.CodeAuthInformationMissing
+		resp.Message = response.MsgAuthInformationMissing
+		resp.Status = fiber.StatusBadRequest
+
+		return c.Status(fiber.StatusBadRequest).JSON(resp)
+	}
+
+	terminal, err := h.svcTerminal.Get(c.Context(), &model.Terminal{
+		ID:     c.Params("id"),
+		Tenant: id,
+	})
+	if err != nil {
+		return terminalFailed(c, err, response.CodeTerminalGetFailed, response.MsgTerminalGetFailed)
+	}
+
+	return c.JSON(utils.WrapResponse(terminalGet(terminal)))
+}
+
+// disable: Disable terminal
+
+// @Tags Terminal
+// @Summary Disable POS terminal
+// @Description 禁用POS终端，device_key立即失效，终端的会话全部撤销，已签发的access_token立即失效
+// @ID TerminalPostDisable
+// @Produce json
+// @Success 200 {object} response.TerminalGet
+// @Failure 400 {object} nil
+// @Failure 404 {object} nil
+// @Failure 500 {object} nil
+// @Failure 403 {object} nil 角色无此权限
+// @Router /tenant/terminals/{:id}/disable [post]
+func (h *Terminal) disable(c *fiber.Ctx) error {
+	id, _ := c.Locals("AuthID").(string)
+	t, _ := c.Locals("AuthType").(string)
+	if id == "" || t != "tenant" {
+		resp := utils.WrapResponse(nil)
+		resp.Code = response.CodeAuthInformationMissing
+		resp.Message = response.MsgAuthInformationMissing
+		resp.Status = fiber.StatusBadRequest
+
+		return c.Status(fiber.StatusBadRequest).JSON(resp)
+	}
+
+	terminal, err := h.svcTerminal.Disable(c.Context(), &model.Terminal{
+		ID:     c.Params("id"),
+		Tenant: id,
+	})
+	if err != nil {
+		return terminalFailed(c, err, response.CodeTerminalDisableFailed, response.MsgTerminalDisableFailed)
+	}
+
+	return c.JSON(utils.WrapResponse(terminalGet(terminal)))
+}
+
+/* }}} */
+
+// terminalFailed : responses error of terminal operations, shared by sign-in routes of tenant
+func terminalFailed(c *fiber.Ctx, err error, code int, msg string) error {
+	resp := utils.WrapResponse(nil)
+	switch {
+	case errors.Is(err, service.ErrTerminalKeyInvalid):
+		resp.Code = response.CodeTerminalKeyInvalid
+		resp.Message = response.MsgTerminalKeyInvalid
+		resp.Status = fiber.StatusUnauthorized
+	case errors.Is(err, sql.ErrNoRows), errors.Is(err, model.ErrTerminalDoesNotExists):
+		resp.Code = response.CodeTerminalDoesNotExists
+		resp.Message = response.MsgTerminalDoesNotExists
+		resp.Status = fiber.StatusNotFound
+	default:
+		runtime.Logger.Errorf("terminal operation failed : %s", err)
+		resp.Code = code
+		resp.Message = msg
+		resp.Status = fiber.StatusInternalServerError
+	}
+
+	return c.Status(resp.Status).JSON(resp)
+}
+
+func terminalGet(terminal *model.Terminal) *response.TerminalGet {
+	return &response.TerminalGet{
+		ID:         terminal.ID,
+		Label:      terminal.Label,
+		KeyPrefix:  terminal.KeyPrefix,
+		Enabled:    terminal.DisabledAt.IsZero(),
+		LastUsedAt: terminal.LastUsedAt,
+		CreatedAt:  terminal.CreatedAt,
+	}
+}
+
+/*
+ * Local variables:
+ * tab-width: 4
+ * c-basic-offset: 4
+ * End:
+ * vim600: sw=4 ts=4 fdm=marker
+ * vim<600: sw=4 ts=4
+ */
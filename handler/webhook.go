@@ -0,0 +1,458 @@
+/*
+ * Copyright (C) HereweTech, Inc - All Rights Reserved
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+/**
+ * @file webhook.go
+ * @package handler
+ * @author Dr.NP <np@herewe.tech>
+ * @since 10/18/2026
+ */
+
+package handler
+
+import (
+	"database/sql"
+	"errors"
+	"icepay-svc/handler/request"
+	"icepay-svc/handler/response"
+	"icepay-svc/model"
+	"icepay-svc/runtime"
+	"icepay-svc/service"
+	"icepay-svc/utils"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	defaultDeliveryListLimit = 50
+	maxDeliveryListLimit     = 200
+)
+
+type Webhook struct {
+	svcWebhook *service.Webhook
+}
+
+// InitWebhook : mounts webhook routes on router of tenant, authorization applied by parent group
+func InitWebhook(router fiber.Router) *Webhook {
+	h := new(Webhook)
+
+	router.Post("/", requireScopes(service.ScopeWebhookWrite), h.add).Name("WebhookPost")
+	router.Get("/list", requireScopes(service.ScopeWebhookRead), h.list).Name("WebhookGetList")
+	router.Get("/:id", requireScopes(service.ScopeWebhookRead), h.get).Name("WebhookGet")
+	router.Put("/:id", requireScopes(service.ScopeWebhookWrite), h.update).Name("WebhookPut")
+	router.Delete("/:id", requireScopes(service.ScopeWebhookWrite), h.delete).Name("WebhookDelete")
+	router.Get("/:id/deliveries", requireScopes(service.ScopeWebhookRead), h.deliveries).Name("WebhookGetDeliveries")
+	router.Post("/:id/deliveries/:delivery/redeliver", requireScopes(service.ScopeWebhookWrite), h.redeliver).Name("WebhookPostRedeliver")
+
+	h.svcWebhook = service.NewWebhook()
+
+	runtime.RegisterWorker(&runtime.Worker{
+		Name:     "webhook-delivery",
+		Interval: time.Duration(runtime.Config.Webhook.DeliveryInterval) * time.Second,
+		Run:      h.svcWebhook.DeliverDue,
+	})
+
+	return h
+}
+
+/* {{{ [Routers] - Definitions */
+
+// add: Register webhook
+
+// @Tags Webhook
+// @Summary Register webhook
+// @Description 注册webhook地址，订单状态变化时以签名的HTTP POST推送至该地址。签名为以secret为key对“X-Icepay-Timestamp头的值.请求体”计算的HMAC-SHA256，通过X-Icepay-Signature头传递，接收方应校验时间戳并拒绝过旧的请求。secret仅在创建和轮换时返回。地址须解析为公网地址，回环、链路本地、私有及运营商NAT共享网段的地址返回HTTP 400
+// @ID WebhookPost
+// @Produce json
+// @Param data body request.WebhookPost true "Input information"
+// @Success 201 {object} response.WebhookPost
+// @Failure 422 string message
+// @Failure 400 {object} nil
+// @Failure 500 {object} nil
+// @Failure 403 {object} nil 角色无此权限
+// @Router /tenant/webhooks [post]
+func (h *Webhook) add(c *fiber.Ctx) error {
+	var req request.WebhookPost
+	err := c.BodyParser(&req)
+	if err != nil {
+		runtime.Logger.Warnf("parse request body failed : %s", err)
+		c.SendStatus(fiber.StatusBadRequest)
+
+		return err
+	}
+
+	id, _ := c.Locals("AuthID").(string)
+	t, _ := c.Locals("AuthType").(string)
+	if id == "" || t != "tenant" {
+		resp := utils.WrapResponse(nil)
+		resp.Code = response.CodeAuthInformationMissing
+		resp.Message = response.MsgAuthInformationMissing
+		resp.Status = fiber.StatusBadRequest
+
+		return c.Status(fiber.StatusBadRequest).JSON(resp)
+	}
+
+	webhook, err := h.svcWebhook.Create(c.Context(), &model.Webhook{
+		Tenant:      id,
+		URL:         req.URL,
+		Description: req.Description,
+	})
+	if err != nil {
+		return h.failed(c, err, response.CodeWebhookCreateFailed, response.MsgWebhookCreateFailed)
+	}
+
+	resp := utils.WrapResponse(&response.WebhookPost{
+		WebhookGet: *webhookGet(webhook),
+		Secret:     webhook.Secret,
+	})
+	resp.Status = fiber.StatusCreated
+
+	return c.Status(fiber.StatusCreated).JSON(resp)
+}
+
+// list: List webhooks
+
+// @Tags Webhook
+// @Summary List webhooks
+// @Description 获取当前tenant的webhook列表
+// @ID WebhookGetList
+// @Produce json
+// @Success 200 {object} response.WebhookGetList
+// @Failure 400 {object} nil
+// @Failure 500 {object} nil
+// @Failure 403 {object} nil 角色无此权限
+// @Router /tenant/webhooks/list [get]
+func (h *Webhook) list(c *fiber.Ctx) error {
+	id, _ := c.Locals("AuthID").(string)
+	t, _ := c.Locals("AuthType").(string)
+	if id == "" || t != "tenant" {
+		resp := utils.WrapResponse(nil)
+		resp.Code = response.CodeAuthInformationMissing
+		resp.Message = response.MsgAuthInformationMissing
+		resp.Status = fiber.StatusBadRequest
+
+		return c.Status(fiber.StatusBadRequest).JSON(resp)
+	}
+
+	ret, err := h.svcWebhook.List(c.Context(), &model.Webhook{Tenant: id})
+	if err != nil {
+		return h.failed(c, err, response.CodeWebhookListFailed, response.MsgWebhookListFailed)
+	}
+
+	webhooks := &response.WebhookGetList{
+		Total: len(ret),
+		List:  make([]*response.WebhookGet, len(ret)),
+	}
+	for idx, webhook := range ret {
+		webhooks.List[idx] = webhookGet(webhook)
+	}
+
+	return c.JSON(utils.WrapResponse(webhooks))
+}
+
+// get: Get webhook
+
+// @Tags Webhook
+// @Summary Get webhook
+// @Description 获取webhook信息
+// @ID WebhookGet
+// @Produce json
+// @Success 200 {object} response.WebhookGet
+// @Failure 400 {object} nil
+// @Failure 404 {object} nil
+// @Failure 500 {object} nil
+// @Failure 403 {object} nil 角色无此权限
+// @Router /tenant/webhooks/{:id} [get]
+func (h *Webhook) get(c *fiber.Ctx) error {
+	id, _ := c.Locals("AuthID").(string)
+	t, _ := c.Locals("AuthType").(string)
+	if id == "" || t != "tenant" {
+		resp := utils.WrapResponse(nil)
+		resp.Code = response.CodeAuthInformationMissing
+		resp.Message = response.MsgAuthInformationMissing
+		resp.Status = fiber.StatusBadRequest
+
+		return c.Status(fiber.StatusBadRequest).JSON(resp)
+	}
+
+	webhook, err := h.svcWebhook.Get(c.Context(), &model.Webhook{
+		ID:     c.Params("id"),
+		Tenant: id,
+	})
+	if err != nil {
+		return h.failed(c, err, response.CodeWebhookGetFailed, response.MsgWebhookGetFailed)
+	}
+
+	return c.JSON(utils.WrapResponse(webhookGet(webhook)))
+}
+
+// update: Update webhook
+
+// @Tags Webhook
+// @Summary Update webhook
+// @Description 更新webhook地址、描述或启用状态，rotate_secret为true时生成新的secret并返回，旧secret立即失效
+// @ID WebhookPut
+// @Produce json
+// @Param data body request.WebhookPut true "Input information"
+// @Success 200 {object} response.WebhookPut
+// @Failure 422 string message
+// @Failure 400 {object} nil
+// @Failure 404 {object} nil
+// @Failure 500 {object} nil
+// @Failure 403 {object} nil 角色无此权限
+// @Router /tenant/webhooks/{:id} [put]
+func (h *Webhook) update(c *fiber.Ctx) error {
+	var req request.WebhookPut
+	err := c.BodyParser(&req)
+	if err != nil {
+		runtime.Logger.Warnf("parse request body failed : %s", err)
+		c.SendStatus(fiber.StatusBadRequest)
+
+		return err
+	}
+
+	id, _ := c.Locals("AuthID").(string)
+	t, _ := c.Locals("AuthType").(string)
+	if id == "" || t != "tenant" {
+		resp := utils.WrapResponse(nil)
+		resp.Code = response.CodeAuthInformationMissing
+		resp.Message = response.MsgAuthInformationMissing
+		resp.Status = fiber.StatusBadRequest
+
+		return c.Status(fiber.StatusBadRequest).JSON(resp)
+	}
+
+	webhook, err := h.svcWebhook.Update(c.Context(), &model.Webhook{
+		ID:          c.Params("id"),
+		Tenant:      id,
+		URL:         req.URL,
+		Description: req.Description,
+	}, req.Enabled, req.RotateSecret)
+	if err != nil {
+		return h.failed(c, err, response.CodeWebhookUpdateFailed, response.MsgWebhookUpdateFailed)
+	}
+
+	ret := &response.WebhookPut{
+		WebhookGet: *webhookGet(webhook),
+	}
+	if req.RotateSecret {
+		ret.Secret = webhook.Secret
+	}
+
+	return c.JSON(utils.WrapResponse(ret))
+}
+
+// delete: Delete webhook
+
+// @Tags Webhook
+// @Summary Delete webhook
+// @Description 删除webhook，尚未完成的推送将不再重试
+// @ID WebhookDelete
+// @Produce json
+// @Success 200 {object} response.WebhookDelete
+// @Failure 400 {object} nil
+// @Failure 404 {object} nil
+// @Failure 500 {object} nil
+// @Failure 403 {object} nil 角色无此权限
+// @Router /tenant/webhooks/{:id} [delete]
+func (h *Webhook) delete(c *fiber.Ctx) error {
+	id, _ := c.Locals("AuthID").(string)
+	t, _ := c.Locals("AuthType").(string)
+	if id == "" || t != "tenant" {
+		resp := utils.WrapResponse(nil)
+		resp.Code = response.CodeAuthInformationMissing
+		resp.Message = response.MsgAuthInformationMissing
+		resp.Status = fiber.StatusBadRequest
+
+		return c.Status(fiber.StatusBadRequest).JSON(resp)
+	}
+
+	err := h.svcWebhook.Delete(c.Context(), &model.Webhook{
+		ID:     c.Params("id"),
+		Tenant: id,
+	})
+	if err != nil {
+		return h.failed(c, err, response.CodeWebhookDeleteFailed, response.MsgWebhookDeleteFailed)
+	}
+
+	return c.JSON(utils.WrapResponse(&response.WebhookDelete{
+		ID: c.Params("id"),
+	}))
+}
+
+// deliveries: Delivery log of webhook
+
+// @Tags Webhook
+// @Summary List deliveries of webhook
+// @Description 获取webhook的推送记录，按时间倒序，可通过status（PENDING/SUCCEEDED/FAILED）过滤，limit默认50，最大200
+// @ID WebhookGetDeliveries
+// @Produce json
+// @Param status query string false "Delivery status"
+// @Param limit query int false "Max records"
+// @Success 200 {object} response.WebhookGetDeliveries
+// @Failure 400 {object} nil
+// @Failure 404 {object} nil
+// @Failure 500 {object} nil
+// @Failure 403 {object} nil 角色无此权限
+// @Router /tenant/webhooks/{:id}/deliveries [get]
+func (h *Webhook) deliveries(c *fiber.Ctx) error {
+	id, _ := c.Locals("AuthID").(string)
+	t, _ := c.Locals("AuthType").(string)
+	if id == "" || t != "tenant" {
+		resp := utils.WrapResponse(nil)
+		resp.Code = response.CodeAuthInformationMissing
+		resp.Message = response.MsgAuthInformationMissing
+		resp.Status = fiber.StatusBadRequest
+
+		return c.Status(fiber.StatusBadRequest).JSON(resp)
+	}
+
+	// Check ownership
+	webhook, err := h.svcWebhook.Get(c.Context(), &model.Webhook{
+		ID:     c.Params("id"),
+		Tenant: id,
+	})
+	if err != nil {
+		return h.failed(c, err, response.CodeWebhookGetFailed, response.MsgWebhookGetFailed)
+	}
+
+	limit := c.QueryInt("limit", defaultDeliveryListLimit)
+	if limit <= 0 || limit > maxDeliveryListLimit {
+		limit = maxDeliveryListLimit
+	}
+
+	ret, err := h.svcWebhook.Deliveries(c.Context(), &model.WebhookDelivery{
+		Webhook: webhook.ID,
+		Tenant:  id,
+		Status:  c.Query("status"),
+	}, limit)
+	if err != nil {
+		return h.failed(c, err, response.CodeWebhookListFailed, response.MsgWebhookListFailed)
+	}
+
+	deliveries := &response.WebhookGetDeliveries{
+		Total: len(ret),
+		List:  make([]*response.WebhookDelivery, len(ret)),
+	}
+	for idx, delivery := range ret {
+		deliveries.List[idx] = webhookDelivery(delivery)
+	}
+
+	return c.JSON(utils.WrapResponse(deliveries))
+}
+
+// redeliver: Redeliver
+
+// @Tags Webhook
+// @Summary Redeliver webhook event
+// @Description 立即重新推送指定记录（无论之前成功与否），重置重试次数，返回本次推送结果。同一记录可能被推送多次，接收方应按X-Icepay-Delivery去重
+// @ID WebhookPostRedeliver
+// @Produce json
+// @Success 200 {object} response.WebhookDelivery
+// @Failure 400 {object} nil
+// @Failure 404 {object} nil
+// @Failure 409 {object} nil 该记录正在推送中
+// @Failure 500 {object} nil
+// @Failure 403 {object} nil 角色无此权限
+// @Router /tenant/webhooks/{:id}/deliveries/{:delivery}/redeliver [post]
+func (h *Webhook) redeliver(c *fiber.Ctx) error {
+	id, _ := c.Locals("AuthID").(string)
+	t, _ := c.Locals("AuthType").(string)
+	if id == "" || t != "tenant" {
+		resp := utils.WrapResponse(nil)
+		resp.Code = response.CodeAuthInformationMissing
+		resp.Message = response.MsgAuthInformationMissing
+		resp.Status = fiber.StatusBadRequest
+
+		return c.Status(fiber.StatusBadRequest).JSON(resp)
+	}
+
+	delivery, err := h.svcWebhook.Redeliver(c.Context(), &model.WebhookDelivery{
+		ID:      c.Params("delivery"),
+		Webhook: c.Params("id"),
+		Tenant:  id,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		resp := utils.WrapResponse(nil)
+		resp.Code = response.CodeWebhookDeliveryNotFound
+		resp.Message = response.MsgWebhookDeliveryNotFound
+		resp.Status = fiber.StatusNotFound
+
+		return c.Status(fiber.StatusNotFound).JSON(resp)
+	}
+
+	if err != nil {
+		return h.failed(c, err, response.CodeWebhookRedeliverFailed, response.MsgWebhookRedeliverFailed)
+	}
+
+	return c.JSON(utils.WrapResponse(webhookDelivery(delivery)))
+}
+
+/* }}} */
+
+// failed : maps service errors to response, code and msg used for unexpected errors
+func (h *Webhook) failed(c *fiber.Ctx, err error, code int, msg string) error {
+	resp := utils.WrapResponse(nil)
+	switch {
+	case errors.Is(err, service.ErrWebhookInvalidURL):
+		resp.Code = response.CodeWebhookInvalidURL
+		resp.Message = response.MsgWebhookInvalidURL
+		resp.Status = fiber.StatusBadRequest
+	case errors.Is(err, service.ErrWebhookForbiddenHost):
+		resp.Code = response.CodeWebhookForbiddenHost
+		resp.Message = response.MsgWebhookForbiddenHost
+		resp.Status = fiber.StatusBadRequest
+	case errors.Is(err, sql.ErrNoRows), errors.Is(err, model.ErrWebhookDoesNotExists):
+		resp.Code = response.CodeWebhookDoesNotExists
+		resp.Message = response.MsgWebhookDoesNotExists
+		resp.Status = fiber.StatusNotFound
+	case errors.Is(err, service.ErrWebhookDeliveryBusy):
+		resp.Code = response.CodeWebhookDeliveryBusy
+		resp.Message = response.MsgWebhookDeliveryBusy
+		resp.Status = fiber.StatusConflict
+	default:
+		runtime.Logger.Errorf("webhook operation failed : %s", err)
+		resp.Code = code
+		resp.Message = msg
+		resp.Status = fiber.StatusInternalServerError
+	}
+
+	return c.Status(resp.Status).JSON(resp)
+}
+
+func webhookGet(webhook *model.Webhook) *response.WebhookGet {
+	return &response.WebhookGet{
+		ID:          webhook.ID,
+		URL:         webhook.URL,
+		Description: webhook.Description,
+		Enabled:     webhook.Enabled,
+		CreatedAt:   webhook.CreatedAt,
+	}
+}
+
+func webhookDelivery(delivery *model.WebhookDelivery) *response.WebhookDelivery {
+	return &response.WebhookDelivery{
+		ID:             delivery.ID,
+		Transaction:    delivery.Transaction,
+		Event:          delivery.Event,
+		Status:         delivery.Status,
+		Attempts:       delivery.Attempts,
+		NextAttemptAt:  delivery.NextAttemptAt,
+		LastStatusCode: delivery.LastStatusCode,
+		LastError:      delivery.LastError,
+		CreatedAt:      delivery.CreatedAt,
+	}
+}
+
+/*
+ * Local variables:
+ * tab-width: 4
+ * c-basic-offset: 4
+ * End:
+ * vim600: sw=4 ts=4 fdm=marker
+ * vim<600: sw=4 ts=4
+ */
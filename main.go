@@ -14,10 +14,15 @@
 package main
 
 import (
+	"fmt"
 	"icepay-svc/handler"
+	"icepay-svc/migrations"
 	"icepay-svc/runtime"
+	"icepay-svc/service"
 	"os"
+	"time"
 
+	"github.com/uptrace/bun/migrate"
 	"github.com/urfave/cli/v2"
 )
 
@@ -31,9 +36,143 @@ func actionServe(c *cli.Context) error {
 	return runtime.Serve()
 }
 
+// newMigrator : migrations recorded as applied only if succeeded
+func newMigrator() *migrate.Migrator {
+	return migrate.NewMigrator(runtime.DB, migrations.Migrations, migrate.WithMarkAppliedOnSuccess(true))
+}
+
+// actionInitdb creates migration tables and applies all migrations, tables created by icepay-admin before are kept.
+// Baseline goes in its own group, so rolling back the rest never reaches it
 func actionInitdb(c *cli.Context) error {
-	// We do not initialize database here
-	// Run php bin/console doctrine:schema:update --force --complete in icepay-admin
+	migrator := newMigrator()
+	err := migrator.Init(c.Context)
+	if err != nil {
+		return err
+	}
+
+	baseline := migrate.NewMigrator(runtime.DB, migrations.BaselineMigrations, migrate.WithMarkAppliedOnSuccess(true))
+	err = baseline.Lock(c.Context)
+	if err != nil {
+		return err
+	}
+
+	_, err = baseline.Migrate(c.Context)
+	baseline.Unlock(c.Context)
+	if err != nil {
+		return err
+	}
+
+	return actionMigrateUp(c)
+}
+
+func actionMigrateUp(c *cli.Context) error {
+	migrator := newMigrator()
+	err := migrator.Lock(c.Context)
+	if err != nil {
+		return err
+	}
+
+	defer migrator.Unlock(c.Context)
+	group, err := migrator.Migrate(c.Context)
+	if err != nil {
+		return err
+	}
+
+	if group.IsZero() {
+		runtime.Logger.Infof("database is up to date")
+	} else {
+		runtime.Logger.Infof("migrated to %s", group)
+	}
+
+	return nil
+}
+
+func actionMigrateDown(c *cli.Context) error {
+	migrator := newMigrator()
+	err := migrator.Lock(c.Context)
+	if err != nil {
+		return err
+	}
+
+	defer migrator.Unlock(c.Context)
+	group, err := migrator.Rollback(c.Context)
+	if err != nil {
+		return err
+	}
+
+	if group.IsZero() {
+		runtime.Logger.Infof("no groups to roll back")
+	} else {
+		runtime.Logger.Infof("rolled back %s", group)
+	}
+
+	return nil
+}
+
+func actionMigrateStatus(c *cli.Context) error {
+	migrator := newMigrator()
+	ms, err := migrator.MigrationsWithStatus(c.Context)
+	if err != nil {
+		return err
+	}
+
+	runtime.Logger.Infof("migrations : %s", ms)
+	runtime.Logger.Infof("unapplied migrations : %s", ms.Unapplied())
+	runtime.Logger.Infof("last migration group : %s", ms.LastGroup())
+
+	return nil
+}
+
+func actionRekeyCards(c *cli.Context) error {
+	n, err := service.NewVault().Rekey(c.Context)
+	runtime.Logger.Infof("%d cards sealed by vault key [%s]", n, runtime.Config.Security.VaultKeyID)
+
+	return err
+}
+
+// actionSettle closes settlement batches of the given date, yesterday by default
+func actionSettle(c *cli.Context) error {
+	svcSettlement := service.NewSettlement()
+	date := svcSettlement.Day(time.Now()).AddDate(0, 0, -1)
+	if c.String("date") != "" {
+		var err error
+		date, err = svcSettlement.ParseDate(c.String("date"))
+		if err != nil {
+			return err
+		}
+	}
+
+	settlements, err := svcSettlement.Close(c.Context, date)
+	if err != nil {
+		return err
+	}
+
+	runtime.Logger.Infof("%d settlement batches closed on %s", len(settlements), date.Format(service.SettlementDateLayout))
+
+	return nil
+}
+
+// actionLedgerCheck verifies debits equal credits of every ledger entry and currency
+func actionLedgerCheck(c *cli.Context) error {
+	report, err := service.NewLedger().Check(c.Context)
+	if err != nil {
+		return err
+	}
+
+	for _, total := range report.Totals {
+		runtime.Logger.Infof("ledger [%s] : debit %d, credit %d", total.Currency, total.Debit, total.Credit)
+	}
+
+	for _, total := range report.Unbalanced {
+		runtime.Logger.Errorf("ledger entry [%s] unbalanced in [%s] : debit %d, credit %d", total.Entry, total.Currency, total.Debit, total.Credit)
+	}
+
+	if !report.Balanced() {
+		return fmt.Errorf("ledger unbalanced : %d entries, %d currencies", len(report.Unbalanced), len(report.Mismatched))
+	}
+
+	runtime.Logger.Infof("ledger balanced")
+
 	return nil
 }
 
@@ -68,6 +207,54 @@ func main() {
 				Usage:  "Initialize database tables",
 				Action: actionInitdb,
 			},
+			{
+				Name:  "migrate",
+				Usage: "Database migrations",
+				Subcommands: []*cli.Command{
+					{
+						Name:   "up",
+						Usage:  "Apply pending migrations",
+						Action: actionMigrateUp,
+					},
+					{
+						Name:   "down",
+						Usage:  "Roll back the last migration group",
+						Action: actionMigrateDown,
+					},
+					{
+						Name:   "status",
+						Usage:  "Show applied and pending migrations",
+						Action: actionMigrateStatus,
+					},
+				},
+			},
+			{
+				Name:  "settle",
+				Usage: "Close settlement batches of a day",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "date",
+						Usage: "Day to close in YYYY-MM-DD, yesterday if omitted",
+					},
+				},
+				Action: actionSettle,
+			},
+			{
+				Name:  "ledger",
+				Usage: "Transaction ledger",
+				Subcommands: []*cli.Command{
+					{
+						Name:   "check",
+						Usage:  "Verify debits equal credits",
+						Action: actionLedgerCheck,
+					},
+				},
+			},
+			{
+				Name:   "rekey-cards",
+				Usage:  "Seal plaintext card numbers and cards of retired keys by the active vault key",
+				Action: actionRekeyCards,
+			},
 		},
 		DefaultCommand: "serve",
 	}
@@ -0,0 +1,7 @@
+-- Tables created by icepay-admin before, never rolled back
+
+DO $$
+BEGIN
+    RAISE EXCEPTION 'baseline migration 20230225000000 can not be rolled back, tables of icepay-admin kept';
+END
+$$;
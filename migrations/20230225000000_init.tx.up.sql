@@ -0,0 +1,72 @@
+-- Tables created by icepay-admin before, kept if exist
+
+CREATE TABLE IF NOT EXISTS client (
+    id varchar(64) NOT NULL PRIMARY KEY,
+    name varchar(255) NOT NULL,
+    email varchar(255) NOT NULL,
+    phone varchar(64),
+    password varchar(255),
+    payment_password varchar(255),
+    salt varchar(64),
+    created_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at timestamptz
+);
+
+--bun:split
+
+CREATE TABLE IF NOT EXISTS tenant (
+    id varchar(64) NOT NULL PRIMARY KEY,
+    name varchar(255) NOT NULL,
+    email varchar(255) NOT NULL,
+    phone varchar(64),
+    password varchar(255),
+    salt varchar(64),
+    created_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at timestamptz
+);
+
+--bun:split
+
+CREATE TABLE IF NOT EXISTS card (
+    id varchar(64) NOT NULL PRIMARY KEY,
+    owner_id varchar(64),
+    owner_type varchar(16),
+    number varchar(32),
+    card_type varchar(32),
+    holder varchar(255),
+    expiration varchar(16),
+    cvv varchar(8),
+    created_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at timestamptz
+);
+
+--bun:split
+
+CREATE INDEX IF NOT EXISTS card_owner_id_idx ON card (owner_id);
+
+--bun:split
+
+CREATE TABLE IF NOT EXISTS transaction (
+    id varchar(64) NOT NULL PRIMARY KEY,
+    client varchar(64) NOT NULL,
+    tenant varchar(64) NOT NULL,
+    amount bigint NOT NULL,
+    currency varchar(8) NOT NULL,
+    status varchar(32),
+    card varchar(64),
+    detail text,
+    created_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at timestamptz
+);
+
+--bun:split
+
+CREATE INDEX IF NOT EXISTS transaction_client_idx ON transaction (client);
+
+--bun:split
+
+CREATE INDEX IF NOT EXISTS transaction_tenant_idx ON transaction (tenant);
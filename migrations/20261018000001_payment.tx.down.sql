@@ -0,0 +1,27 @@
+DROP TABLE IF EXISTS idempotency;
+
+--bun:split
+
+DROP TABLE IF EXISTS refund;
+
+--bun:split
+
+DROP INDEX IF EXISTS transaction_status_created_at_idx;
+
+--bun:split
+
+ALTER TABLE transaction
+    DROP COLUMN IF EXISTS refunded;
+
+--bun:split
+
+ALTER TABLE tenant
+    DROP COLUMN IF EXISTS password_changed_at;
+
+--bun:split
+
+ALTER TABLE client
+    DROP COLUMN IF EXISTS payment_salt,
+    DROP COLUMN IF EXISTS password_changed_at,
+    DROP COLUMN IF EXISTS payment_failures,
+    DROP COLUMN IF EXISTS payment_locked_until;
@@ -0,0 +1,58 @@
+-- Payment passwords, refunds, idempotency keys and expiry of transactions
+
+ALTER TABLE client
+    ADD COLUMN IF NOT EXISTS payment_salt varchar(64),
+    ADD COLUMN IF NOT EXISTS password_changed_at timestamptz,
+    ADD COLUMN IF NOT EXISTS payment_failures integer NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS payment_locked_until timestamptz;
+
+--bun:split
+
+ALTER TABLE tenant
+    ADD COLUMN IF NOT EXISTS password_changed_at timestamptz;
+
+--bun:split
+
+ALTER TABLE transaction
+    ADD COLUMN IF NOT EXISTS refunded bigint NOT NULL DEFAULT 0;
+
+--bun:split
+
+CREATE INDEX IF NOT EXISTS transaction_status_created_at_idx ON transaction (status, created_at);
+
+--bun:split
+
+CREATE TABLE IF NOT EXISTS refund (
+    id varchar(64) NOT NULL PRIMARY KEY,
+    transaction varchar(64) NOT NULL,
+    client varchar(64) NOT NULL,
+    tenant varchar(64) NOT NULL,
+    amount bigint NOT NULL,
+    currency varchar(8) NOT NULL,
+    reason text,
+    created_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+--bun:split
+
+CREATE INDEX IF NOT EXISTS refund_transaction_idx ON refund (transaction);
+
+--bun:split
+
+CREATE TABLE IF NOT EXISTS idempotency (
+    id varchar(64) NOT NULL PRIMARY KEY,
+    owner_id varchar(64) NOT NULL,
+    owner_type varchar(16) NOT NULL,
+    key varchar(255) NOT NULL,
+    request_hash varchar(64) NOT NULL,
+    status_code integer NOT NULL DEFAULT 0,
+    response bytea,
+    created_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (owner_id, owner_type, key)
+);
+
+--bun:split
+
+CREATE INDEX IF NOT EXISTS idempotency_created_at_idx ON idempotency (created_at);
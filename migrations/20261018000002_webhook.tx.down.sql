@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS webhook_delivery;
+
+--bun:split
+
+DROP TABLE IF EXISTS webhook;
@@ -0,0 +1,41 @@
+CREATE TABLE IF NOT EXISTS webhook (
+    id varchar(64) NOT NULL PRIMARY KEY,
+    tenant varchar(64) NOT NULL,
+    url text NOT NULL,
+    secret varchar(255) NOT NULL,
+    description text,
+    enabled boolean NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    deleted_at timestamptz
+);
+
+--bun:split
+
+CREATE INDEX IF NOT EXISTS webhook_tenant_idx ON webhook (tenant);
+
+--bun:split
+
+CREATE TABLE IF NOT EXISTS webhook_delivery (
+    id varchar(64) NOT NULL PRIMARY KEY,
+    webhook varchar(64) NOT NULL,
+    tenant varchar(64) NOT NULL,
+    transaction varchar(64),
+    event varchar(64) NOT NULL,
+    payload text NOT NULL,
+    status varchar(16) NOT NULL,
+    attempts integer NOT NULL DEFAULT 0,
+    next_attempt_at timestamptz,
+    last_status_code integer NOT NULL DEFAULT 0,
+    last_error text,
+    created_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+--bun:split
+
+CREATE INDEX IF NOT EXISTS webhook_delivery_webhook_idx ON webhook_delivery (webhook, created_at);
+
+--bun:split
+
+CREATE INDEX IF NOT EXISTS webhook_delivery_due_idx ON webhook_delivery (status, next_attempt_at);
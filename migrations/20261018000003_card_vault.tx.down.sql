@@ -0,0 +1,8 @@
+ALTER TABLE card
+    DROP COLUMN IF EXISTS token,
+    DROP COLUMN IF EXISTS bin,
+    DROP COLUMN IF EXISTS last4,
+    DROP COLUMN IF EXISTS pan,
+    DROP COLUMN IF EXISTS key_id,
+    DROP COLUMN IF EXISTS fingerprint,
+    DROP COLUMN IF EXISTS verified_at;
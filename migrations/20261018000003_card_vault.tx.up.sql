@@ -0,0 +1,18 @@
+-- Legacy number and cvv columns kept, cleared by rekey-cards
+
+ALTER TABLE card
+    ADD COLUMN IF NOT EXISTS token varchar(64),
+    ADD COLUMN IF NOT EXISTS bin varchar(8),
+    ADD COLUMN IF NOT EXISTS last4 varchar(4),
+    ADD COLUMN IF NOT EXISTS pan bytea,
+    ADD COLUMN IF NOT EXISTS key_id varchar(64),
+    ADD COLUMN IF NOT EXISTS fingerprint varchar(64),
+    ADD COLUMN IF NOT EXISTS verified_at timestamptz;
+
+--bun:split
+
+CREATE UNIQUE INDEX IF NOT EXISTS card_token_idx ON card (token);
+
+--bun:split
+
+CREATE INDEX IF NOT EXISTS card_fingerprint_idx ON card (fingerprint);
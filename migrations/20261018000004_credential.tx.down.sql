@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS totp_seed;
+
+--bun:split
+
+DROP TABLE IF EXISTS credential_nonce;
@@ -0,0 +1,24 @@
+-- One-time credentials and offline codes
+
+CREATE TABLE IF NOT EXISTS credential_nonce (
+    nonce varchar(128) NOT NULL PRIMARY KEY,
+    client varchar(64) NOT NULL,
+    tenant varchar(64) NOT NULL,
+    expires_at timestamptz NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+--bun:split
+
+CREATE INDEX IF NOT EXISTS credential_nonce_expires_at_idx ON credential_nonce (expires_at);
+
+--bun:split
+
+CREATE TABLE IF NOT EXISTS totp_seed (
+    client varchar(64) NOT NULL PRIMARY KEY,
+    code_index varchar(8) NOT NULL UNIQUE,
+    seed bytea NOT NULL,
+    key_id varchar(64) NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS transaction_tenant_created_at_idx;
+
+--bun:split
+
+DROP INDEX IF EXISTS transaction_client_created_at_idx;
@@ -0,0 +1,7 @@
+-- Keyset pagination of payment lists
+
+CREATE INDEX IF NOT EXISTS transaction_client_created_at_idx ON transaction (client, created_at, id);
+
+--bun:split
+
+CREATE INDEX IF NOT EXISTS transaction_tenant_created_at_idx ON transaction (tenant, created_at, id);
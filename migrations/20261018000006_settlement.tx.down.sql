@@ -0,0 +1,18 @@
+DROP TABLE IF EXISTS settlement_item;
+
+--bun:split
+
+DROP TABLE IF EXISTS settlement;
+
+--bun:split
+
+DROP INDEX IF EXISTS refund_created_at_idx;
+
+--bun:split
+
+DROP INDEX IF EXISTS transaction_confirmed_at_idx;
+
+--bun:split
+
+ALTER TABLE transaction
+    DROP COLUMN IF EXISTS confirmed_at;
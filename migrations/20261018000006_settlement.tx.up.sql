@@ -0,0 +1,54 @@
+-- Settlement batches by the day of confirmation, legacy confirmations approximated by last update
+
+ALTER TABLE transaction
+    ADD COLUMN IF NOT EXISTS confirmed_at timestamptz;
+
+--bun:split
+
+UPDATE transaction SET confirmed_at = updated_at
+    WHERE confirmed_at IS NULL AND status IN ('CONFIRMED', 'PARTIALLY_REFUNDED', 'REFUNDED');
+
+--bun:split
+
+CREATE INDEX IF NOT EXISTS transaction_confirmed_at_idx ON transaction (confirmed_at);
+
+--bun:split
+
+CREATE INDEX IF NOT EXISTS refund_created_at_idx ON refund (created_at);
+
+--bun:split
+
+CREATE TABLE IF NOT EXISTS settlement (
+    id varchar(64) NOT NULL PRIMARY KEY,
+    tenant varchar(64) NOT NULL,
+    currency varchar(8) NOT NULL,
+    date date NOT NULL,
+    payments integer NOT NULL DEFAULT 0,
+    refund_count integer NOT NULL DEFAULT 0,
+    gross bigint NOT NULL DEFAULT 0,
+    refunds bigint NOT NULL DEFAULT 0,
+    fees bigint NOT NULL DEFAULT 0,
+    net bigint NOT NULL DEFAULT 0,
+    created_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (tenant, currency, date)
+);
+
+--bun:split
+
+CREATE TABLE IF NOT EXISTS settlement_item (
+    id varchar(64) NOT NULL PRIMARY KEY,
+    settlement varchar(64) NOT NULL,
+    tenant varchar(64) NOT NULL,
+    kind varchar(16) NOT NULL,
+    reference varchar(64) NOT NULL,
+    transaction varchar(64) NOT NULL,
+    amount bigint NOT NULL,
+    fee bigint NOT NULL DEFAULT 0,
+    net bigint NOT NULL,
+    occurred_at timestamptz NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+--bun:split
+
+CREATE INDEX IF NOT EXISTS settlement_item_settlement_idx ON settlement_item (settlement, occurred_at);
@@ -0,0 +1,13 @@
+DROP TABLE IF EXISTS ledger_posting;
+
+--bun:split
+
+DROP TABLE IF EXISTS ledger_entry;
+
+--bun:split
+
+DROP TABLE IF EXISTS ledger_account;
+
+--bun:split
+
+DROP FUNCTION IF EXISTS ledger_append_only();
@@ -0,0 +1,73 @@
+-- Double-entry ledger of transactions, entries and postings are append only.
+-- Transactions changed before have no entries
+
+CREATE TABLE IF NOT EXISTS ledger_account (
+    id varchar(64) NOT NULL PRIMARY KEY,
+    kind varchar(32) NOT NULL,
+    owner varchar(64) NOT NULL,
+    currency varchar(8) NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (kind, owner, currency)
+);
+
+--bun:split
+
+CREATE TABLE IF NOT EXISTS ledger_entry (
+    id varchar(64) NOT NULL PRIMARY KEY,
+    transaction varchar(64) NOT NULL,
+    refund varchar(64),
+    from_status varchar(32) NOT NULL,
+    to_status varchar(32) NOT NULL,
+    currency varchar(8) NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+--bun:split
+
+CREATE INDEX IF NOT EXISTS ledger_entry_transaction_idx ON ledger_entry (transaction, created_at);
+
+--bun:split
+
+CREATE TABLE IF NOT EXISTS ledger_posting (
+    id varchar(64) NOT NULL PRIMARY KEY,
+    entry varchar(64) NOT NULL REFERENCES ledger_entry (id),
+    account varchar(64) NOT NULL REFERENCES ledger_account (id),
+    debit bigint NOT NULL DEFAULT 0,
+    credit bigint NOT NULL DEFAULT 0,
+    created_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CHECK (debit >= 0 AND credit >= 0 AND (debit = 0 OR credit = 0))
+);
+
+--bun:split
+
+CREATE INDEX IF NOT EXISTS ledger_posting_entry_idx ON ledger_posting (entry);
+
+--bun:split
+
+CREATE INDEX IF NOT EXISTS ledger_posting_account_idx ON ledger_posting (account);
+
+--bun:split
+
+CREATE OR REPLACE FUNCTION ledger_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION '% is append only', TG_TABLE_NAME;
+END;
+$$ LANGUAGE plpgsql;
+
+--bun:split
+
+DROP TRIGGER IF EXISTS ledger_entry_append_only ON ledger_entry;
+
+--bun:split
+
+CREATE TRIGGER ledger_entry_append_only BEFORE UPDATE OR DELETE ON ledger_entry
+    FOR EACH ROW EXECUTE FUNCTION ledger_append_only();
+
+--bun:split
+
+DROP TRIGGER IF EXISTS ledger_posting_append_only ON ledger_posting;
+
+--bun:split
+
+CREATE TRIGGER ledger_posting_append_only BEFORE UPDATE OR DELETE ON ledger_posting
+    FOR EACH ROW EXECUTE FUNCTION ledger_append_only();
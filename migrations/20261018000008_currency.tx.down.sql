@@ -0,0 +1,7 @@
+ALTER TABLE tenant
+    DROP COLUMN IF EXISTS currencies;
+
+--bun:split
+
+ALTER TABLE card
+    DROP COLUMN IF EXISTS currency;
@@ -0,0 +1,9 @@
+-- Settlement currency of cards and accepted currencies of tenants, the default currency of payment if empty
+
+ALTER TABLE card
+    ADD COLUMN IF NOT EXISTS currency varchar(8);
+
+--bun:split
+
+ALTER TABLE tenant
+    ADD COLUMN IF NOT EXISTS currencies varchar(8)[];
@@ -0,0 +1,22 @@
+-- Refresh token sessions, refresh tokens issued before have no session and are rejected
+
+CREATE TABLE IF NOT EXISTS session (
+    id varchar(64) NOT NULL PRIMARY KEY,
+    subject varchar(64) NOT NULL,
+    subject_type varchar(16) NOT NULL,
+    token_id varchar(64) NOT NULL,
+    rotations integer NOT NULL DEFAULT 0,
+    expires_at timestamptz NOT NULL,
+    revoked_at timestamptz,
+    revoked_reason varchar(16) NOT NULL DEFAULT '',
+    created_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+--bun:split
+
+CREATE INDEX IF NOT EXISTS session_subject_idx ON session (subject, subject_type);
+
+--bun:split
+
+CREATE INDEX IF NOT EXISTS session_expires_at_idx ON session (expires_at);
@@ -0,0 +1,10 @@
+-- Role of session, scopes of access tokens granted by it. Sessions opened before act as the owner of the account
+
+ALTER TABLE session
+    ADD COLUMN IF NOT EXISTS role varchar(32) NOT NULL DEFAULT '';
+
+--bun:split
+
+UPDATE session
+    SET role = CASE subject_type WHEN 'client' THEN 'client' WHEN 'tenant' THEN 'tenant-owner' ELSE role END
+    WHERE role = '';
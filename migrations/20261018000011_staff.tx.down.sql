@@ -0,0 +1,17 @@
+ALTER TABLE session
+    DROP COLUMN IF EXISTS terminal,
+    DROP COLUMN IF EXISTS staff;
+
+--bun:split
+
+ALTER TABLE transaction
+    DROP COLUMN IF EXISTS terminal,
+    DROP COLUMN IF EXISTS staff;
+
+--bun:split
+
+DROP TABLE IF EXISTS terminal;
+
+--bun:split
+
+DROP TABLE IF EXISTS staff;
@@ -0,0 +1,63 @@
+-- Staff and POS terminals of tenants, acting on behalf of the tenant
+
+CREATE TABLE IF NOT EXISTS staff (
+    id varchar(64) NOT NULL PRIMARY KEY,
+    tenant varchar(64) NOT NULL,
+    email varchar(255) NOT NULL,
+    name varchar(255),
+    role varchar(32) NOT NULL,
+    status varchar(16) NOT NULL,
+    password varchar(255),
+    salt varchar(64),
+    invite_hash varchar(64),
+    invite_expires_at timestamptz,
+    disabled_at timestamptz,
+    created_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+--bun:split
+
+CREATE INDEX IF NOT EXISTS staff_tenant_idx ON staff (tenant);
+
+--bun:split
+
+CREATE UNIQUE INDEX IF NOT EXISTS staff_email_idx ON staff (email) WHERE status <> 'DISABLED';
+
+--bun:split
+
+CREATE UNIQUE INDEX IF NOT EXISTS staff_invite_hash_idx ON staff (invite_hash);
+
+--bun:split
+
+CREATE TABLE IF NOT EXISTS terminal (
+    id varchar(64) NOT NULL PRIMARY KEY,
+    tenant varchar(64) NOT NULL,
+    label varchar(255),
+    key_hash varchar(64) NOT NULL,
+    key_prefix varchar(16) NOT NULL,
+    last_used_at timestamptz,
+    disabled_at timestamptz,
+    created_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+--bun:split
+
+CREATE INDEX IF NOT EXISTS terminal_tenant_idx ON terminal (tenant);
+
+--bun:split
+
+CREATE UNIQUE INDEX IF NOT EXISTS terminal_key_hash_idx ON terminal (key_hash);
+
+--bun:split
+
+ALTER TABLE transaction
+    ADD COLUMN IF NOT EXISTS staff varchar(64),
+    ADD COLUMN IF NOT EXISTS terminal varchar(64);
+
+--bun:split
+
+ALTER TABLE session
+    ADD COLUMN IF NOT EXISTS staff varchar(64),
+    ADD COLUMN IF NOT EXISTS terminal varchar(64);
@@ -0,0 +1,22 @@
+-- API keys of tenants for server-to-server calls
+
+CREATE TABLE IF NOT EXISTS api_key (
+    id varchar(64) NOT NULL PRIMARY KEY,
+    tenant varchar(64) NOT NULL,
+    label varchar(255),
+    key_hash varchar(64) NOT NULL,
+    key_prefix varchar(16) NOT NULL,
+    allowed_ips varchar(64)[],
+    last_used_at timestamptz,
+    revoked_at timestamptz,
+    created_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at timestamptz NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+--bun:split
+
+CREATE INDEX IF NOT EXISTS api_key_tenant_idx ON api_key (tenant);
+
+--bun:split
+
+CREATE UNIQUE INDEX IF NOT EXISTS api_key_key_hash_idx ON api_key (key_hash);
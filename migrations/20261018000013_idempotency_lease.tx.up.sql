@@ -0,0 +1,10 @@
+-- Lease of idempotency keys in progress, re-claimable after it if the request never finished
+
+ALTER TABLE idempotency
+    ADD COLUMN IF NOT EXISTS leased_until timestamptz;
+
+--bun:split
+
+UPDATE idempotency
+    SET leased_until = created_at
+    WHERE status_code = 0 AND leased_until IS NULL;
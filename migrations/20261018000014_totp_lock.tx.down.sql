@@ -0,0 +1,3 @@
+ALTER TABLE totp_seed
+    DROP COLUMN IF EXISTS failures,
+    DROP COLUMN IF EXISTS locked_until;
@@ -0,0 +1,5 @@
+-- Failed offline codes counted per index, locked after too many of them
+
+ALTER TABLE totp_seed
+    ADD COLUMN IF NOT EXISTS failures integer NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS locked_until timestamptz;
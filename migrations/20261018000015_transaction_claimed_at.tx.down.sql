@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS transaction_status_expiry_idx;
+
+--bun:split
+
+ALTER TABLE transaction
+    DROP COLUMN IF EXISTS claimed_at;
@@ -0,0 +1,8 @@
+-- Claim time of payment requests, expiry of claimed ones counted from it
+
+ALTER TABLE transaction
+    ADD COLUMN IF NOT EXISTS claimed_at timestamptz;
+
+--bun:split
+
+CREATE INDEX IF NOT EXISTS transaction_status_expiry_idx ON transaction (status, (COALESCE(claimed_at, created_at)));
@@ -0,0 +1,18 @@
+-- Fee of payments charged at confirmation, settled as is.
+-- Confirmed before take the fee posted to the ledger, none if confirmed before the ledger
+
+ALTER TABLE transaction
+    ADD COLUMN IF NOT EXISTS fee bigint NOT NULL DEFAULT 0;
+
+--bun:split
+
+UPDATE transaction t SET fee = f.fee
+    FROM (
+        SELECT e.transaction, SUM(p.credit) AS fee
+            FROM ledger_entry e
+            JOIN ledger_posting p ON p.entry = e.id
+            JOIN ledger_account a ON a.id = p.account
+            WHERE a.kind = 'FEES'
+            GROUP BY e.transaction
+    ) f
+    WHERE f.transaction = t.id;
@@ -0,0 +1,2 @@
+ALTER TABLE idempotency
+    DROP COLUMN IF EXISTS content_type;
@@ -0,0 +1,4 @@
+-- Content type of stored responses, replayed as is. Responses stored before are JSON envelopes
+
+ALTER TABLE idempotency
+    ADD COLUMN IF NOT EXISTS content_type varchar(255) NOT NULL DEFAULT 'application/json';
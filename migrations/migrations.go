@@ -0,0 +1,56 @@
+/*
+ * Copyright (C) HereweTech, Inc - All Rights Reserved
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+/**
+ * @file migrations.go
+ * @package migrations
+ * @author Dr.NP <np@herewe.tech>
+ * @since 10/18/2026
+ */
+
+package migrations
+
+import (
+	"embed"
+
+	"github.com/uptrace/bun/migrate"
+)
+
+// SQL migrations : <version>_<name>.tx.(up|down).sql, each runs in a database transaction.
+// Statements separated by --bun:split
+//
+//go:embed *.sql
+var sqlMigrations embed.FS
+
+// Baseline : initial migration of tables created by icepay-admin before, rolling back it fails
+const Baseline = "20230225000000"
+
+var Migrations = migrate.NewMigrations()
+
+// BaselineMigrations : the baseline alone, applied in its own group by initdb
+var BaselineMigrations = migrate.NewMigrations()
+
+func init() {
+	err := Migrations.Discover(sqlMigrations)
+	if err != nil {
+		panic(err)
+	}
+
+	for _, m := range Migrations.Sorted() {
+		if m.Name == Baseline {
+			BaselineMigrations.Add(m)
+		}
+	}
+}
+
+/*
+ * Local variables:
+ * tab-width: 4
+ * c-basic-offset: 4
+ * End:
+ * vim600: sw=4 ts=4 fdm=marker
+ * vim<600: sw=4 ts=4
+ */
@@ -0,0 +1,199 @@
+/*
+ * Copyright (C) HereweTech, Inc - All Rights Reserved
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+/**
+ * @file api_key.go
+ * @package model
+ * @author Dr.NP <np@herewe.tech>
+ * @since 10/18/2026
+ */
+
+package model
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"icepay-svc/runtime"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect/pgdialect"
+)
+
+// APIKey : key of tenant for server-to-server calls, only digest of the secret stored
+type APIKey struct {
+	bun.BaseModel `bun:"table:api_key"`
+	ID            string    `bun:"id,pk" json:"id"`
+	Tenant        string    `bun:"tenant,notnull" json:"tenant"`
+	Label         string    `bun:"label" json:"label"`
+	KeyHash       string    `bun:"key_hash,notnull" json:"-"`
+	KeyPrefix     string    `bun:"key_prefix,notnull" json:"key_prefix"` // Leading characters of key, telling keys apart
+	AllowedIPs    []string  `bun:"allowed_ips,array" json:"allowed_ips"` // CIDRs, any IP allowed if empty
+	LastUsedAt    time.Time `bun:"last_used_at,nullzero" json:"last_used_at"`
+	RevokedAt     time.Time `bun:"revoked_at,nullzero" json:"revoked_at"`
+
+	CreatedAt time.Time `bun:"created_at,nullzero,notnull,default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt time.Time `bun:"updated_at,nullzero,notnull,default:CURRENT_TIMESTAMP" json:"updated_at"`
+}
+
+var (
+	ErrAPIKeyDoesNotExists = errors.New("API key does not exists")
+)
+
+/* {{{ [Actions] - Definitions */
+
+// Create
+func (m *APIKey) Create(ctx context.Context) error {
+	if m.ID == "" {
+		m.ID = uuid.NewString()
+	}
+
+	_, err := runtime.IDB(ctx).NewInsert().Model(m).Returning("").Exec(ctx)
+	if err == nil {
+		runtime.Logger.Infof("API key [%s] of tenant [%s] created", m.ID, m.Tenant)
+	} else {
+		runtime.Logger.Errorf("create API key failed : %s", err)
+	}
+
+	return err
+}
+
+// Get: gets API key by id (of tenant) or by digest of key, revoked ones included
+func (m *APIKey) Get(ctx context.Context) error {
+	sq := runtime.IDB(ctx).NewSelect().Model(m)
+	if m.ID != "" {
+		sq = sq.Where("id = ?", m.ID)
+	}
+
+	if m.Tenant != "" {
+		sq = sq.Where("tenant = ?", m.Tenant)
+	}
+
+	if m.KeyHash != "" {
+		sq = sq.Where("key_hash = ?", m.KeyHash)
+	}
+
+	err := sq.Limit(1).Scan(ctx)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			runtime.Logger.Warnf("API key does not exists")
+		} else {
+			runtime.Logger.Errorf("get API key failed : %s", err)
+		}
+	}
+
+	return err
+}
+
+// List: lists API keys of tenant
+func (m *APIKey) List(ctx context.Context) ([]*APIKey, error) {
+	var keys []*APIKey
+	err := runtime.IDB(ctx).NewSelect().Model(&keys).
+		Where("tenant = ?", m.Tenant).
+		Order("created_at ASC").
+		Scan(ctx)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return keys, nil
+		}
+
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+// Update: updates label and allowed IPs (replaced if not nil) of API key not revoked
+func (m *APIKey) Update(ctx context.Context) error {
+	uq := runtime.IDB(ctx).NewUpdate().Model(m).
+		Set("updated_at = CURRENT_TIMESTAMP").
+		Where("id = ?", m.ID).
+		Where("tenant = ?", m.Tenant).
+		Where("revoked_at IS NULL")
+	if m.Label != "" {
+		uq = uq.Set("label = ?", m.Label)
+	}
+
+	if m.AllowedIPs != nil {
+		uq = uq.Set("allowed_ips = ?", pgdialect.Array(m.AllowedIPs))
+	}
+
+	res, err := uq.Returning("").Exec(ctx)
+	if err != nil {
+		runtime.Logger.Errorf("update API key [%s] failed : %s", m.ID, err)
+
+		return err
+	}
+
+	n, _ := res.RowsAffected()
+	if n == 0 {
+		return ErrAPIKeyDoesNotExists
+	}
+
+	return nil
+}
+
+// Touch: records the time key used, at most once in interval
+func (m *APIKey) Touch(ctx context.Context, interval time.Duration) error {
+	_, err := runtime.IDB(ctx).NewUpdate().Model(m).
+		Set("last_used_at = CURRENT_TIMESTAMP").
+		Where("id = ?", m.ID).
+		Where("(last_used_at IS NULL OR last_used_at < ?)", time.Now().Add(-interval)).
+		Returning("").
+		Exec(ctx)
+	if err != nil {
+		runtime.Logger.Errorf("touch API key [%s] failed : %s", m.ID, err)
+	}
+
+	return err
+}
+
+// Revoke: revokes API key of tenant, not accepted anymore
+func (m *APIKey) Revoke(ctx context.Context) error {
+	res, err := runtime.IDB(ctx).NewUpdate().Model(m).
+		Set("revoked_at = CURRENT_TIMESTAMP").
+		Set("updated_at = CURRENT_TIMESTAMP").
+		Where("id = ?", m.ID).
+		Where("tenant = ?", m.Tenant).
+		Where("revoked_at IS NULL").
+		Returning("").
+		Exec(ctx)
+	if err != nil {
+		runtime.Logger.Errorf("revoke API key [%s] failed : %s", m.ID, err)
+
+		return err
+	}
+
+	n, _ := res.RowsAffected()
+	if n == 0 {
+		return ErrAPIKeyDoesNotExists
+	}
+
+	runtime.Logger.Infof("API key [%s] of tenant [%s] revoked", m.ID, m.Tenant)
+
+	return nil
+}
+
+// Debug
+func (m *APIKey) Debug() string {
+	b, _ := json.MarshalIndent(m, "", "  ")
+
+	return string(b)
+}
+
+/* }}} */
+
+/*
+ * Local variables:
+ * tab-width: 4
+ * c-basic-offset: 4
+ * End:
+ * vim600: sw=4 ts=4 fdm=marker
+ * vim<600: sw=4 ts=4
+ */
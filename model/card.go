@@ -27,14 +27,21 @@ import (
 
 type Card struct {
 	bun.BaseModel `bun:"table:card"`
-	ID            string `bun:"id,pk" json:"id"`
-	OwnerID       string `bun:"owner_id" json:"owner_id"`
-	OwnerType     string `bun:"owner_type" json:"owner_type"`
-	Number        string `bun:"number" json:"nunber"`
-	CardType      string `bun:"card_type" json:"card_type"`
-	Holder        string `bun:"holder" json:"holder"`
-	Expiration    string `bun:"expiration" json:"expiration"`
-	CVV           string `bun:"cvv" json:"cvv"`
+	ID            string    `bun:"id,pk" json:"id"`
+	OwnerID       string    `bun:"owner_id" json:"owner_id"`
+	OwnerType     string    `bun:"owner_type" json:"owner_type"`
+	Token         string    `bun:"token,unique" json:"token"`
+	CardType      string    `bun:"card_type" json:"card_type"`
+	BIN           string    `bun:"bin" json:"bin"`
+	Last4         string    `bun:"last4" json:"last4"`
+	PAN           []byte    `bun:"pan,type:bytea" json:"-"` // Sealed by vault
+	KeyID         string    `bun:"key_id" json:"-"`
+	Fingerprint   string    `bun:"fingerprint" json:"-"`
+	Number        string    `bun:"number,nullzero" json:"-"` // Legacy plaintext, cleared by vault rekey
+	Holder        string    `bun:"holder" json:"holder"`
+	Expiration    string    `bun:"expiration" json:"expiration"`
+	Currency      string    `bun:"currency,nullzero" json:"currency"` // Settlement currency, the default one if empty
+	VerifiedAt    time.Time `bun:"verified_at,nullzero" json:"verified_at"`
 
 	CreatedAt time.Time `bun:"created_at,nullzero,notnull,default:CURRENT_TIMESTAMP" json:"created_at"`
 	UpdatedAt time.Time `bun:"updated_at,nullzero,notnull,default:CURRENT_TIMESTAMP" json:"updated_at"`
@@ -53,7 +60,7 @@ func (m *Card) Create(ctx context.Context) error {
 		m.ID = uuid.NewString()
 	}
 
-	_, err := runtime.DB.NewInsert().Model(m).Returning("").Exec(ctx)
+	_, err := runtime.IDB(ctx).NewInsert().Model(m).Returning("").Exec(ctx)
 	if err == nil {
 		runtime.Logger.Infof("card [%s] created", m.ID)
 	} else {
@@ -65,7 +72,7 @@ func (m *Card) Create(ctx context.Context) error {
 
 // Get: gets card
 func (m *Card) Get(ctx context.Context) error {
-	sq := runtime.DB.NewSelect().Model(m)
+	sq := runtime.IDB(ctx).NewSelect().Model(m)
 	if m.ID != "" {
 		sq = sq.Where("id = ?", m.ID)
 	}
@@ -78,8 +85,12 @@ func (m *Card) Get(ctx context.Context) error {
 		sq = sq.Where("owner_type = ?", m.OwnerType)
 	}
 
-	if m.Number != "" {
-		sq = sq.Where("number = ?", m.Number)
+	if m.Token != "" {
+		sq = sq.Where("token = ?", m.Token)
+	}
+
+	if m.Fingerprint != "" {
+		sq = sq.Where("fingerprint = ?", m.Fingerprint)
 	}
 
 	if m.CardType != "" {
@@ -101,7 +112,7 @@ func (m *Card) Get(ctx context.Context) error {
 // List: list card by given conditoins
 func (m *Card) List(ctx context.Context) ([]*Card, error) {
 	var cards []*Card
-	sq := runtime.DB.NewSelect().Model(&cards)
+	sq := runtime.IDB(ctx).NewSelect().Model(&cards)
 	if m.OwnerID != "" {
 		sq = sq.Where("owner_id = ?", m.OwnerID)
 	}
@@ -110,8 +121,12 @@ func (m *Card) List(ctx context.Context) ([]*Card, error) {
 		sq = sq.Where("owner_type = ?", m.OwnerType)
 	}
 
-	if m.Number != "" {
-		sq = sq.Where("number = ?", m.Number)
+	if m.Token != "" {
+		sq = sq.Where("token = ?", m.Token)
+	}
+
+	if m.Fingerprint != "" {
+		sq = sq.Where("fingerprint = ?", m.Fingerprint)
 	}
 
 	if m.CardType != "" {
@@ -132,7 +147,7 @@ func (m *Card) List(ctx context.Context) ([]*Card, error) {
 
 // Delete: delete(soft) card
 func (m *Card) Delete(ctx context.Context) error {
-	res, err := runtime.DB.NewDelete().
+	res, err := runtime.IDB(ctx).NewDelete().
 		Model(m).
 		Where("id = ?", m.ID).
 		Where("owner_id = ?", m.OwnerID).
@@ -156,7 +171,7 @@ func (m *Card) Delete(ctx context.Context) error {
 
 // Update: updates card
 func (m *Card) Update(ctx context.Context) error {
-	uq := runtime.DB.NewUpdate().Model(m).Set("update_at = CURRENT_TIMESTAMP")
+	uq := runtime.IDB(ctx).NewUpdate().Model(m).Set("updated_at = CURRENT_TIMESTAMP")
 	if m.Holder != "" {
 		uq = uq.Set("holder = ?", m.Holder)
 	}
@@ -165,8 +180,8 @@ func (m *Card) Update(ctx context.Context) error {
 		uq = uq.Set("expiration = ?", m.Expiration)
 	}
 
-	if m.CVV != "" {
-		uq = uq.Set("cvv = ?", m.CVV)
+	if !m.VerifiedAt.IsZero() {
+		uq = uq.Set("verified_at = ?", m.VerifiedAt)
 	}
 
 	if m.ID != "" {
@@ -181,14 +196,88 @@ func (m *Card) Update(ctx context.Context) error {
 		uq = uq.Where("owner_type = ?", m.OwnerType)
 	}
 
-	_, err := uq.Returning("").Exec(ctx)
+	res, err := uq.Returning("").Exec(ctx)
 	if err != nil {
 		runtime.Logger.Errorf("Update card failed : %s", err)
+
+		return err
+	}
+
+	n, _ := res.RowsAffected()
+	if n == 0 {
+		return ErrCardDoesNotExists
 	}
 
 	return nil
 }
 
+// LockStale: gets cards sealed by keys other than active one, or still with plaintext number, and locks them.
+// Deleted ones included, plaintext of them cleared as well. Rows locked by others are skipped
+func (m *Card) LockStale(ctx context.Context, active string, limit int) ([]*Card, error) {
+	var cards []*Card
+	err := runtime.IDB(ctx).NewSelect().Model(&cards).
+		WhereAllWithDeleted().
+		WhereGroup(" AND ", func(q *bun.SelectQuery) *bun.SelectQuery {
+			return q.Where("key_id IS DISTINCT FROM ?", active).WhereOr("number IS NOT NULL")
+		}).
+		Order("created_at ASC").
+		Limit(limit).
+		For("UPDATE SKIP LOCKED").
+		Scan(ctx)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return cards, nil
+		}
+
+		runtime.Logger.Errorf("lock stale cards failed : %s", err)
+
+		return nil, err
+	}
+
+	return cards, nil
+}
+
+// UpdateVault: stores sealed number, plaintext number and CVV of legacy rows cleared, deleted ones too
+func (m *Card) UpdateVault(ctx context.Context, legacy bool) error {
+	uq := runtime.IDB(ctx).NewUpdate().Model(m).
+		WhereAllWithDeleted().
+		Set("pan = ?", m.PAN).
+		Set("key_id = ?", m.KeyID).
+		Set("fingerprint = ?", m.Fingerprint).
+		Set("bin = ?", m.BIN).
+		Set("last4 = ?", m.Last4).
+		Set("updated_at = CURRENT_TIMESTAMP").
+		WherePK()
+	if m.Token != "" {
+		uq = uq.Set("token = ?", m.Token)
+	}
+
+	if legacy {
+		uq = uq.Set("number = NULL").Set("cvv = NULL")
+	}
+
+	_, err := uq.Returning("").Exec(ctx)
+	if err != nil {
+		runtime.Logger.Errorf("update vault of card [%s] failed : %s", m.ID, err)
+	}
+
+	return err
+}
+
+// Settles: checks if owner has any card settling currency, cards without currency settle the fallback one
+func (m *Card) Settles(ctx context.Context, currency, fallback string) (bool, error) {
+	exists, err := runtime.IDB(ctx).NewSelect().Model((*Card)(nil)).
+		Where("owner_id = ?", m.OwnerID).
+		Where("owner_type = ?", m.OwnerType).
+		Where("COALESCE(currency, ?) = ?", fallback, currency).
+		Exists(ctx)
+	if err != nil {
+		runtime.Logger.Errorf("check card currency failed : %s", err)
+	}
+
+	return exists, err
+}
+
 // Debug
 func (m *Card) Debug() string {
 	b, _ := json.MarshalIndent(m, "", "  ")
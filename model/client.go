@@ -33,12 +33,20 @@ type Client struct {
 	Password        string `bun:"password" json:"password"`
 	PaymentPassword string `bun:"payment_password" json:"payment_password"`
 	Salt            string `bun:"salt" json:"salt"`
+	PaymentSalt     string `bun:"payment_salt" json:"payment_salt"`
+
+	PasswordChangedAt  time.Time `bun:"password_changed_at,nullzero" json:"password_changed_at"`
+	PaymentFailures    int       `bun:"payment_failures,notnull,default:0" json:"payment_failures"`
+	PaymentLockedUntil time.Time `bun:"payment_locked_until,nullzero" json:"payment_locked_until"`
 
 	CreatedAt time.Time `bun:"created_at,nullzero,notnull,default:CURRENT_TIMESTAMP" json:"created_at"`
 	UpdatedAt time.Time `bun:"updated_at,nullzero,notnull,default:CURRENT_TIMESTAMP" json:"updated_at"`
 	DeletedAt time.Time `bun:"deleted_at,soft_delete,nullzero" json:"-"`
 }
 
+// Placeholder of passwords never set (eg. clients created via firebase)
+const PasswordNotSet = "__NOT_SET__"
+
 /* {{{ [Actions] - Definitions */
 
 // Create: creates client
@@ -52,21 +60,25 @@ func (m *Client) Create(ctx context.Context) error {
 		m.Salt = utils.RandomString(32)
 	}
 
+	if m.PaymentSalt == "" {
+		m.PaymentSalt = utils.RandomString(32)
+	}
+
 	if m.Password == "" {
-		m.Password = "__NOT_SET__"
+		m.Password = PasswordNotSet
 	} else {
 		// Encrypt
 		m.Password = utils.EncryptPassword(m.Password, m.Salt, m.Email)
 	}
 
 	if m.PaymentPassword == "" {
-		m.PaymentPassword = "__NOT_SET__"
+		m.PaymentPassword = PasswordNotSet
 	} else {
 		// Encrypt
-		m.PaymentPassword = utils.EncryptPaymentPassword(m.PaymentPassword, m.Salt, m.Email)
+		m.PaymentPassword = utils.EncryptPaymentPassword(m.PaymentPassword, m.PaymentSalt, m.Email)
 	}
 
-	_, err := runtime.DB.NewInsert().Model(m).Returning("").Exec(ctx)
+	_, err := runtime.IDB(ctx).NewInsert().Model(m).Returning("").Exec(ctx)
 	if err == nil {
 		runtime.Logger.Infof("client [%s] created", m.ID)
 	} else {
@@ -78,7 +90,7 @@ func (m *Client) Create(ctx context.Context) error {
 
 // Get: gets client
 func (m *Client) Get(ctx context.Context) error {
-	sq := runtime.DB.NewSelect().Model(m)
+	sq := runtime.IDB(ctx).NewSelect().Model(m)
 	if m.ID != "" {
 		sq = sq.Where("id = ?", m.ID)
 	}
@@ -101,7 +113,7 @@ func (m *Client) Get(ctx context.Context) error {
 
 // Update: updates client
 func (m *Client) Update(ctx context.Context) error {
-	uq := runtime.DB.NewUpdate().Model(m).Where("id = ?", m.ID)
+	uq := runtime.IDB(ctx).NewUpdate().Model(m).Where("id = ?", m.ID)
 	if m.Phone != "" {
 		uq = uq.Set("phone = ?", m.Phone)
 	}
@@ -110,12 +122,24 @@ func (m *Client) Update(ctx context.Context) error {
 		uq = uq.Set("name = ?", m.Name)
 	}
 
+	if m.Salt != "" {
+		uq = uq.Set("salt = ?", m.Salt)
+	}
+
+	if m.PaymentSalt != "" {
+		uq = uq.Set("payment_salt = ?", m.PaymentSalt)
+	}
+
 	if m.Password != "" {
 		uq = uq.Set("password = ?", utils.EncryptPassword(m.Password, m.Salt, m.Email))
 	}
 
 	if m.PaymentPassword != "" {
-		uq = uq.Set("payment_password = ?", utils.EncryptPaymentPassword(m.PaymentPassword, m.Salt, m.Email))
+		uq = uq.Set("payment_password = ?", utils.EncryptPaymentPassword(m.PaymentPassword, m.PaymentSalt, m.Email))
+	}
+
+	if !m.PasswordChangedAt.IsZero() {
+		uq = uq.Set("password_changed_at = ?", m.PasswordChangedAt)
 	}
 
 	_, err := uq.Returning("").Exec(ctx)
@@ -128,6 +152,39 @@ func (m *Client) Update(ctx context.Context) error {
 	return err
 }
 
+// FailPaymentPassword: counts a payment password failure, locks payment until lockUntil once limit reached
+func (m *Client) FailPaymentPassword(ctx context.Context, limit int, lockUntil time.Time) error {
+	_, err := runtime.IDB(ctx).NewUpdate().Model(m).
+		Set("payment_failures = CASE WHEN payment_failures + 1 >= ? THEN 0 ELSE payment_failures + 1 END", limit).
+		Set("payment_locked_until = CASE WHEN payment_failures + 1 >= ? THEN ? ELSE payment_locked_until END", limit, lockUntil).
+		Where("id = ?", m.ID).
+		Returning("payment_failures, payment_locked_until").
+		Exec(ctx)
+	if err != nil {
+		runtime.Logger.Errorf("count payment password failure of client [%s] failed : %s", m.ID, err)
+	}
+
+	return err
+}
+
+// ResetPaymentFailures: clears payment password failures and lock
+func (m *Client) ResetPaymentFailures(ctx context.Context) error {
+	_, err := runtime.IDB(ctx).NewUpdate().Model(m).
+		Set("payment_failures = 0").
+		Set("payment_locked_until = NULL").
+		Where("id = ?", m.ID).
+		Returning("").
+		Exec(ctx)
+	if err == nil {
+		m.PaymentFailures = 0
+		m.PaymentLockedUntil = time.Time{}
+	} else {
+		runtime.Logger.Errorf("reset payment password failures of client [%s] failed : %s", m.ID, err)
+	}
+
+	return err
+}
+
 // Debug
 func (m *Client) Debug() string {
 	b, _ := json.MarshalIndent(m, "", "  ")
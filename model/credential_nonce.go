@@ -0,0 +1,95 @@
+/*
+ * Copyright (C) HereweTech, Inc - All Rights Reserved
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+/**
+ * @file credential_nonce.go
+ * @package model
+ * @author Dr.NP <np@herewe.tech>
+ * @since 10/18/2026
+ */
+
+package model
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"icepay-svc/runtime"
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+// CredentialNonce : nonce of payment credential consumed, kept until the credential expires
+type CredentialNonce struct {
+	bun.BaseModel `bun:"table:credential_nonce"`
+	Nonce         string    `bun:"nonce,pk" json:"nonce"`
+	Client        string    `bun:"client,notnull" json:"client"`
+	Tenant        string    `bun:"tenant,notnull" json:"tenant"`
+	ExpiresAt     time.Time `bun:"expires_at,notnull" json:"expires_at"`
+
+	CreatedAt time.Time `bun:"created_at,nullzero,notnull,default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+var (
+	ErrCredentialNonceUsed = errors.New("Credential nonce used")
+)
+
+/* {{{ [Actions] - Definitions */
+
+// Create: consumes nonce, ErrCredentialNonceUsed returned if consumed before
+func (m *CredentialNonce) Create(ctx context.Context) error {
+	res, err := runtime.IDB(ctx).NewInsert().Model(m).
+		On("CONFLICT (nonce) DO NOTHING").
+		Returning("").
+		Exec(ctx)
+	if err != nil {
+		runtime.Logger.Errorf("consume credential nonce failed : %s", err)
+
+		return err
+	}
+
+	n, _ := res.RowsAffected()
+	if n == 0 {
+		return ErrCredentialNonceUsed
+	}
+
+	return nil
+}
+
+// Purge: removes nonces of credentials expired before deadline
+func (m *CredentialNonce) Purge(ctx context.Context, deadline time.Time) (int64, error) {
+	res, err := runtime.IDB(ctx).NewDelete().Model((*CredentialNonce)(nil)).
+		Where("expires_at < ?", deadline).
+		Exec(ctx)
+	if err != nil {
+		runtime.Logger.Errorf("purge credential nonces failed : %s", err)
+
+		return 0, err
+	}
+
+	n, _ := res.RowsAffected()
+
+	return n, nil
+}
+
+// Debug
+func (m *CredentialNonce) Debug() string {
+	b, _ := json.MarshalIndent(m, "", "  ")
+
+	return string(b)
+}
+
+/* }}} */
+
+/*
+ * Local variables:
+ * tab-width: 4
+ * c-basic-offset: 4
+ * End:
+ * vim600: sw=4 ts=4 fdm=marker
+ * vim<600: sw=4 ts=4
+ */
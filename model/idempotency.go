@@ -0,0 +1,151 @@
+/*
+ * Copyright (C) HereweTech, Inc - All Rights Reserved
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+/**
+ * @file idempotency.go
+ * @package model
+ * @author Dr.NP <np@herewe.tech>
+ * @since 10/18/2026
+ */
+
+package model
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"icepay-svc/runtime"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+type Idempotency struct {
+	bun.BaseModel `bun:"table:idempotency"`
+	ID            string `bun:"id,pk" json:"id"`
+	OwnerID       string `bun:"owner_id,notnull" json:"owner_id"`
+	OwnerType     string `bun:"owner_type,notnull" json:"owner_type"`
+	Key           string `bun:"key,notnull" json:"key"`
+	RequestHash   string `bun:"request_hash,notnull" json:"request_hash"`
+	StatusCode    int    `bun:"status_code,notnull,default:0" json:"status_code"` // 0 while the request is in progress
+	ContentType   string `bun:"content_type,notnull,default:'application/json'" json:"content_type"`
+	Response      []byte `bun:"response,type:bytea" json:"response"`
+
+	LeasedUntil time.Time `bun:"leased_until,nullzero" json:"leased_until"` // Re-claimable after it while in progress
+
+	CreatedAt time.Time `bun:"created_at,nullzero,notnull,default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt time.Time `bun:"updated_at,nullzero,notnull,default:CURRENT_TIMESTAMP" json:"updated_at"`
+}
+
+var (
+	ErrIdempotencyKeyExists = errors.New("Idempotency key exists")
+)
+
+/* {{{ [Actions] - Definitions */
+
+// Create: claims idempotency key of owner
+func (m *Idempotency) Create(ctx context.Context) error {
+	if m.ID == "" {
+		m.ID = uuid.NewString()
+	}
+
+	res, err := runtime.IDB(ctx).NewInsert().Model(m).
+		On("CONFLICT (owner_id, owner_type, key) DO NOTHING").
+		Returning("").
+		Exec(ctx)
+	if err != nil {
+		runtime.Logger.Errorf("create idempotency key failed : %s", err)
+
+		return err
+	}
+
+	n, _ := res.RowsAffected()
+	if n == 0 {
+		return ErrIdempotencyKeyExists
+	}
+
+	return nil
+}
+
+// Get: gets idempotency key of owner
+func (m *Idempotency) Get(ctx context.Context) error {
+	err := runtime.IDB(ctx).NewSelect().Model(m).
+		Where("owner_id = ?", m.OwnerID).
+		Where("owner_type = ?", m.OwnerType).
+		Where("key = ?", m.Key).
+		Limit(1).
+		Scan(ctx)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		runtime.Logger.Errorf("get idempotency key failed : %s", err)
+	}
+
+	return err
+}
+
+// Update: stores response of the request
+func (m *Idempotency) Update(ctx context.Context) error {
+	_, err := runtime.IDB(ctx).NewUpdate().Model(m).
+		Set("status_code = ?", m.StatusCode).
+		Set("content_type = ?", m.ContentType).
+		Set("response = ?", m.Response).
+		Set("updated_at = CURRENT_TIMESTAMP").
+		Where("id = ?", m.ID).
+		Returning("").
+		Exec(ctx)
+	if err != nil {
+		runtime.Logger.Errorf("update idempotency key [%s] failed : %s", m.ID, err)
+	}
+
+	return err
+}
+
+// Release: releases idempotency key left in progress after its lease, false if finished or still leased
+func (m *Idempotency) Release(ctx context.Context, at time.Time) (bool, error) {
+	res, err := runtime.IDB(ctx).NewDelete().Model(m).
+		Where("id = ?", m.ID).
+		Where("status_code = 0").
+		Where("leased_until < ?", at).
+		Exec(ctx)
+	if err != nil {
+		runtime.Logger.Errorf("release idempotency key [%s] failed : %s", m.ID, err)
+
+		return false, err
+	}
+
+	n, _ := res.RowsAffected()
+
+	return n > 0, nil
+}
+
+// Delete: releases idempotency key
+func (m *Idempotency) Delete(ctx context.Context) error {
+	_, err := runtime.IDB(ctx).NewDelete().Model(m).Where("id = ?", m.ID).Exec(ctx)
+	if err != nil {
+		runtime.Logger.Errorf("delete idempotency key [%s] failed : %s", m.ID, err)
+	}
+
+	return err
+}
+
+// Debug
+func (m *Idempotency) Debug() string {
+	b, _ := json.MarshalIndent(m, "", "  ")
+
+	return string(b)
+}
+
+/* }}} */
+
+/*
+ * Local variables:
+ * tab-width: 4
+ * c-basic-offset: 4
+ * End:
+ * vim600: sw=4 ts=4 fdm=marker
+ * vim<600: sw=4 ts=4
+ */
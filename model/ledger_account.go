@@ -0,0 +1,84 @@
+/*
+ * Copyright (C) HereweTech, Inc - All Rights Reserved
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+/**
+ * @file ledger_account.go
+ * @package model
+ * @author Dr.NP <np@herewe.tech>
+ * @since 10/18/2026
+ */
+
+package model
+
+import (
+	"context"
+	"encoding/json"
+	"icepay-svc/runtime"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+// LedgerAccount : one per kind, owner and currency. Owner is empty for accounts of the platform
+type LedgerAccount struct {
+	bun.BaseModel `bun:"table:ledger_account"`
+	ID            string `bun:"id,pk" json:"id"`
+	Kind          string `bun:"kind,notnull" json:"kind"`
+	Owner         string `bun:"owner,notnull" json:"owner"`
+	Currency      string `bun:"currency,notnull" json:"currency"`
+
+	CreatedAt time.Time `bun:"created_at,nullzero,notnull,default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+/* {{{ [Actions] - Definitions */
+
+// Open: gets account of kind, owner and currency, creates it if not exists
+func (m *LedgerAccount) Open(ctx context.Context) error {
+	if m.ID == "" {
+		m.ID = uuid.NewString()
+	}
+
+	_, err := runtime.IDB(ctx).NewInsert().Model(m).
+		On("CONFLICT (kind, owner, currency) DO NOTHING").
+		Returning("").
+		Exec(ctx)
+	if err != nil {
+		runtime.Logger.Errorf("create ledger account failed : %s", err)
+
+		return err
+	}
+
+	err = runtime.IDB(ctx).NewSelect().Model(m).
+		Where("kind = ?", m.Kind).
+		Where("owner = ?", m.Owner).
+		Where("currency = ?", m.Currency).
+		Limit(1).
+		Scan(ctx)
+	if err != nil {
+		runtime.Logger.Errorf("get ledger account failed : %s", err)
+	}
+
+	return err
+}
+
+// Debug
+func (m *LedgerAccount) Debug() string {
+	b, _ := json.MarshalIndent(m, "", "  ")
+
+	return string(b)
+}
+
+/* }}} */
+
+/*
+ * Local variables:
+ * tab-width: 4
+ * c-basic-offset: 4
+ * End:
+ * vim600: sw=4 ts=4 fdm=marker
+ * vim<600: sw=4 ts=4
+ */
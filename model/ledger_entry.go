@@ -0,0 +1,91 @@
+/*
+ * Copyright (C) HereweTech, Inc - All Rights Reserved
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+/**
+ * @file ledger_entry.go
+ * @package model
+ * @author Dr.NP <np@herewe.tech>
+ * @since 10/18/2026
+ */
+
+package model
+
+import (
+	"context"
+	"encoding/json"
+	"icepay-svc/runtime"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+// LedgerEntry : journal entry of one status change of transaction, append only.
+// Changes moving no money have no postings
+type LedgerEntry struct {
+	bun.BaseModel `bun:"table:ledger_entry"`
+	ID            string           `bun:"id,pk" json:"id"`
+	Transaction   string           `bun:"transaction,notnull" json:"transaction"`
+	Refund        string           `bun:"refund,nullzero" json:"refund"`
+	FromStatus    string           `bun:"from_status,notnull" json:"from_status"`
+	ToStatus      string           `bun:"to_status,notnull" json:"to_status"`
+	Currency      string           `bun:"currency,notnull" json:"currency"`
+	Postings      []*LedgerPosting `bun:"rel:has-many,join:id=entry" json:"postings"`
+
+	CreatedAt time.Time `bun:"created_at,nullzero,notnull,default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+/* {{{ [Actions] - Definitions */
+
+// Create: creates entry with its postings
+func (m *LedgerEntry) Create(ctx context.Context) error {
+	if m.ID == "" {
+		m.ID = uuid.NewString()
+	}
+
+	_, err := runtime.IDB(ctx).NewInsert().Model(m).Returning("").Exec(ctx)
+	if err != nil {
+		runtime.Logger.Errorf("create ledger entry failed : %s", err)
+
+		return err
+	}
+
+	if len(m.Postings) == 0 {
+		return nil
+	}
+
+	for _, posting := range m.Postings {
+		posting.Entry = m.ID
+		if posting.ID == "" {
+			posting.ID = uuid.NewString()
+		}
+	}
+
+	_, err = runtime.IDB(ctx).NewInsert().Model(&m.Postings).Returning("").Exec(ctx)
+	if err != nil {
+		runtime.Logger.Errorf("create postings of ledger entry [%s] failed : %s", m.ID, err)
+	}
+
+	return err
+}
+
+// Debug
+func (m *LedgerEntry) Debug() string {
+	b, _ := json.MarshalIndent(m, "", "  ")
+
+	return string(b)
+}
+
+/* }}} */
+
+/*
+ * Local variables:
+ * tab-width: 4
+ * c-basic-offset: 4
+ * End:
+ * vim600: sw=4 ts=4 fdm=marker
+ * vim<600: sw=4 ts=4
+ */
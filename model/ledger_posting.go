@@ -0,0 +1,104 @@
+/*
+ * Copyright (C) HereweTech, Inc - All Rights Reserved
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+/**
+ * @file ledger_posting.go
+ * @package model
+ * @author Dr.NP <np@herewe.tech>
+ * @since 10/18/2026
+ */
+
+package model
+
+import (
+	"context"
+	"encoding/json"
+	"icepay-svc/runtime"
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+// LedgerPosting : one side of entry on account, either debit or credit is non-zero
+type LedgerPosting struct {
+	bun.BaseModel `bun:"table:ledger_posting"`
+	ID            string `bun:"id,pk" json:"id"`
+	Entry         string `bun:"entry,notnull" json:"entry"`
+	Account       string `bun:"account,notnull" json:"account"`
+	Debit         int64  `bun:"debit,notnull,default:0" json:"debit"`
+	Credit        int64  `bun:"credit,notnull,default:0" json:"credit"`
+
+	CreatedAt time.Time `bun:"created_at,nullzero,notnull,default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+// LedgerTotal : sums of postings grouped by entry or by currency
+type LedgerTotal struct {
+	Entry    string `bun:"entry" json:"entry,omitempty"`
+	Currency string `bun:"currency" json:"currency"`
+	Debit    int64  `bun:"debit" json:"debit"`
+	Credit   int64  `bun:"credit" json:"credit"`
+}
+
+/* {{{ [Actions] - Definitions */
+
+// Unbalanced: entries whose debits differ from credits
+func (m *LedgerPosting) Unbalanced(ctx context.Context) ([]*LedgerTotal, error) {
+	var totals []*LedgerTotal
+	err := runtime.IDB(ctx).NewSelect().
+		TableExpr("ledger_posting AS p").
+		Join("JOIN ledger_entry AS e ON e.id = p.entry").
+		ColumnExpr("p.entry, e.currency").
+		ColumnExpr("SUM(p.debit) AS debit, SUM(p.credit) AS credit").
+		Group("p.entry", "e.currency").
+		Having("SUM(p.debit) <> SUM(p.credit)").
+		Order("p.entry ASC").
+		Scan(ctx, &totals)
+	if err != nil {
+		runtime.Logger.Errorf("sum ledger entries failed : %s", err)
+
+		return nil, err
+	}
+
+	return totals, nil
+}
+
+// Totals: debits and credits of all postings per currency of accounts
+func (m *LedgerPosting) Totals(ctx context.Context) ([]*LedgerTotal, error) {
+	var totals []*LedgerTotal
+	err := runtime.IDB(ctx).NewSelect().
+		TableExpr("ledger_posting AS p").
+		Join("JOIN ledger_account AS a ON a.id = p.account").
+		ColumnExpr("a.currency").
+		ColumnExpr("SUM(p.debit) AS debit, SUM(p.credit) AS credit").
+		Group("a.currency").
+		Order("a.currency ASC").
+		Scan(ctx, &totals)
+	if err != nil {
+		runtime.Logger.Errorf("sum ledger postings failed : %s", err)
+
+		return nil, err
+	}
+
+	return totals, nil
+}
+
+// Debug
+func (m *LedgerPosting) Debug() string {
+	b, _ := json.MarshalIndent(m, "", "  ")
+
+	return string(b)
+}
+
+/* }}} */
+
+/*
+ * Local variables:
+ * tab-width: 4
+ * c-basic-offset: 4
+ * End:
+ * vim600: sw=4 ts=4 fdm=marker
+ * vim<600: sw=4 ts=4
+ */
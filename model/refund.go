@@ -0,0 +1,125 @@
+/*
+ * Copyright (C) HereweTech, Inc - All Rights Reserved
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+/**
+ * @file refund.go
+ * @package model
+ * @author Dr.NP <np@herewe.tech>
+ * @since 10/18/2026
+ */
+
+package model
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"icepay-svc/runtime"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+type Refund struct {
+	bun.BaseModel `bun:"table:refund"`
+	ID            string `bun:"id,pk" json:"id"`
+	Transaction   string `bun:"transaction,notnull" json:"transaction"`
+	Client        string `bun:"client,notnull" json:"client"`
+	Tenant        string `bun:"tenant,notnull" json:"tenant"`
+	Amount        int64  `bun:"amount,notnull" json:"amount"`
+	Currency      string `bun:"currency,notnull" json:"currency"`
+	Reason        string `bun:"reason" json:"reason"`
+
+	CreatedAt time.Time `bun:"created_at,nullzero,notnull,default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt time.Time `bun:"updated_at,nullzero,notnull,default:CURRENT_TIMESTAMP" json:"updated_at"`
+}
+
+/* {{{ [Actions] - Definitions */
+
+// Create
+func (m *Refund) Create(ctx context.Context) error {
+	if m.ID == "" {
+		m.ID = uuid.NewString()
+	}
+
+	_, err := runtime.IDB(ctx).NewInsert().Model(m).Returning("").Exec(ctx)
+	if err == nil {
+		runtime.Logger.Infof("refund [%s] of transaction [%s] created", m.ID, m.Transaction)
+	} else {
+		runtime.Logger.Errorf("create refund failed : %s", err)
+	}
+
+	return err
+}
+
+// List: list refunds by given conditions
+func (m *Refund) List(ctx context.Context) ([]*Refund, error) {
+	var refunds []*Refund
+	sq := runtime.IDB(ctx).NewSelect().Model(&refunds)
+	if m.Transaction != "" {
+		sq = sq.Where("transaction = ?", m.Transaction)
+	}
+
+	if m.Client != "" {
+		sq = sq.Where("client = ?", m.Client)
+	}
+
+	if m.Tenant != "" {
+		sq = sq.Where("tenant = ?", m.Tenant)
+	}
+
+	err := sq.Order("created_at ASC").Scan(ctx)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return refunds, nil
+		}
+
+		return nil, err
+	}
+
+	return refunds, nil
+}
+
+// ListCreated: list refunds created in [from, to), by tenant and currency
+func (m *Refund) ListCreated(ctx context.Context, from, to time.Time) ([]*Refund, error) {
+	var refunds []*Refund
+	err := runtime.IDB(ctx).NewSelect().Model(&refunds).
+		Where("created_at >= ?", from).
+		Where("created_at < ?", to).
+		Order("tenant ASC", "currency ASC", "created_at ASC", "id ASC").
+		Scan(ctx)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return refunds, nil
+		}
+
+		runtime.Logger.Errorf("list created refunds failed : %s", err)
+
+		return nil, err
+	}
+
+	return refunds, nil
+}
+
+// Debug
+func (m *Refund) Debug() string {
+	b, _ := json.MarshalIndent(m, "", "  ")
+
+	return string(b)
+}
+
+/* }}} */
+
+/*
+ * Local variables:
+ * tab-width: 4
+ * c-basic-offset: 4
+ * End:
+ * vim600: sw=4 ts=4 fdm=marker
+ * vim<600: sw=4 ts=4
+ */
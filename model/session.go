@@ -0,0 +1,191 @@
+/*
+ * Copyright (C) HereweTech, Inc - All Rights Reserved
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+/**
+ * @file session.go
+ * @package model
+ * @author Dr.NP <np@herewe.tech>
+ * @since 10/18/2026
+ */
+
+package model
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"icepay-svc/runtime"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+// Session : refresh token family of one login, TokenID is the jti of the only valid refresh token
+type Session struct {
+	bun.BaseModel `bun:"table:session"`
+	ID            string    `bun:"id,pk" json:"id"`
+	Subject       string    `bun:"subject,notnull" json:"subject"`
+	SubjectType   string    `bun:"subject_type,notnull" json:"subject_type"`
+	Role          string    `bun:"role,notnull" json:"role"`
+	Staff         string    `bun:"staff,nullzero" json:"staff"`       // Staff of tenant signed in
+	Terminal      string    `bun:"terminal,nullzero" json:"terminal"` // Terminal of tenant signed in
+	TokenID       string    `bun:"token_id,notnull" json:"-"`
+	Rotations     int       `bun:"rotations,notnull,default:0" json:"rotations"`
+	ExpiresAt     time.Time `bun:"expires_at,notnull" json:"expires_at"`
+	RevokedAt     time.Time `bun:"revoked_at,nullzero" json:"revoked_at"`
+	RevokedReason string    `bun:"revoked_reason" json:"revoked_reason"`
+
+	CreatedAt time.Time `bun:"created_at,nullzero,notnull,default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt time.Time `bun:"updated_at,nullzero,notnull,default:CURRENT_TIMESTAMP" json:"updated_at"`
+}
+
+/* {{{ [Actions] - Definitions */
+
+// Create
+func (m *Session) Create(ctx context.Context) error {
+	if m.ID == "" {
+		m.ID = uuid.NewString()
+	}
+
+	_, err := runtime.IDB(ctx).NewInsert().Model(m).Returning("").Exec(ctx)
+	if err == nil {
+		runtime.Logger.Infof("session [%s] of %s [%s] created", m.ID, m.SubjectType, m.Subject)
+	} else {
+		runtime.Logger.Errorf("create session failed : %s", err)
+	}
+
+	return err
+}
+
+// Get: gets session
+func (m *Session) Get(ctx context.Context) error {
+	err := runtime.IDB(ctx).NewSelect().Model(m).
+		Where("id = ?", m.ID).
+		Limit(1).
+		Scan(ctx)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		runtime.Logger.Errorf("get session failed : %s", err)
+	}
+
+	return err
+}
+
+// Lock: gets session and locks the row until the end of database transaction bound to ctx
+func (m *Session) Lock(ctx context.Context) error {
+	err := runtime.IDB(ctx).NewSelect().Model(m).
+		Where("id = ?", m.ID).
+		For("UPDATE").
+		Limit(1).
+		Scan(ctx)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			runtime.Logger.Warnf("session [%s] does not exists", m.ID)
+		} else {
+			runtime.Logger.Errorf("lock session failed : %s", err)
+		}
+	}
+
+	return err
+}
+
+// Rotate: stores the jti of the new refresh token
+func (m *Session) Rotate(ctx context.Context) error {
+	_, err := runtime.IDB(ctx).NewUpdate().Model(m).
+		Set("token_id = ?", m.TokenID).
+		Set("rotations = rotations + 1").
+		Set("expires_at = ?", m.ExpiresAt).
+		Set("updated_at = CURRENT_TIMESTAMP").
+		Where("id = ?", m.ID).
+		Returning("rotations").
+		Exec(ctx)
+	if err != nil {
+		runtime.Logger.Errorf("rotate session [%s] failed : %s", m.ID, err)
+	}
+
+	return err
+}
+
+// Revoke: revokes session, revoked ones kept as is
+func (m *Session) Revoke(ctx context.Context) error {
+	_, err := runtime.IDB(ctx).NewUpdate().Model(m).
+		Set("revoked_at = CURRENT_TIMESTAMP").
+		Set("revoked_reason = ?", m.RevokedReason).
+		Set("updated_at = CURRENT_TIMESTAMP").
+		Where("id = ?", m.ID).
+		Where("revoked_at IS NULL").
+		Returning("").
+		Exec(ctx)
+	if err == nil {
+		runtime.Logger.Infof("session [%s] revoked : %s", m.ID, m.RevokedReason)
+	} else {
+		runtime.Logger.Errorf("revoke session [%s] failed : %s", m.ID, err)
+	}
+
+	return err
+}
+
+// RevokeAll: revokes all live sessions of subject, signed in as the staff or terminal given (or the subject itself)
+func (m *Session) RevokeAll(ctx context.Context) error {
+	res, err := runtime.IDB(ctx).NewUpdate().Model((*Session)(nil)).
+		Set("revoked_at = CURRENT_TIMESTAMP").
+		Set("revoked_reason = ?", m.RevokedReason).
+		Set("updated_at = CURRENT_TIMESTAMP").
+		Where("subject = ?", m.Subject).
+		Where("subject_type = ?", m.SubjectType).
+		Where("COALESCE(staff, '') = ?", m.Staff).
+		Where("COALESCE(terminal, '') = ?", m.Terminal).
+		Where("revoked_at IS NULL").
+		Where("expires_at > CURRENT_TIMESTAMP").
+		Exec(ctx)
+	if err != nil {
+		runtime.Logger.Errorf("revoke sessions of %s [%s] failed : %s", m.SubjectType, m.Subject, err)
+
+		return err
+	}
+
+	n, _ := res.RowsAffected()
+	if n > 0 {
+		runtime.Logger.Infof("%d sessions of %s [%s] revoked : %s", n, m.SubjectType, m.Subject, m.RevokedReason)
+	}
+
+	return nil
+}
+
+// Purge: removes sessions expired before deadline
+func (m *Session) Purge(ctx context.Context, deadline time.Time) (int64, error) {
+	res, err := runtime.IDB(ctx).NewDelete().Model((*Session)(nil)).
+		Where("expires_at < ?", deadline).
+		Exec(ctx)
+	if err != nil {
+		runtime.Logger.Errorf("purge sessions failed : %s", err)
+
+		return 0, err
+	}
+
+	n, _ := res.RowsAffected()
+
+	return n, nil
+}
+
+// Debug
+func (m *Session) Debug() string {
+	b, _ := json.MarshalIndent(m, "", "  ")
+
+	return string(b)
+}
+
+/* }}} */
+
+/*
+ * Local variables:
+ * tab-width: 4
+ * c-basic-offset: 4
+ * End:
+ * vim600: sw=4 ts=4 fdm=marker
+ * vim<600: sw=4 ts=4
+ */
@@ -0,0 +1,140 @@
+/*
+ * Copyright (C) HereweTech, Inc - All Rights Reserved
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+/**
+ * @file settlement.go
+ * @package model
+ * @author Dr.NP <np@herewe.tech>
+ * @since 10/18/2026
+ */
+
+package model
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"icepay-svc/runtime"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+// Settlement : batch of one tenant, one currency, one day. Net = Gross - Refunds - Fees
+type Settlement struct {
+	bun.BaseModel `bun:"table:settlement"`
+	ID            string    `bun:"id,pk" json:"id"`
+	Tenant        string    `bun:"tenant,notnull" json:"tenant"`
+	Currency      string    `bun:"currency,notnull" json:"currency"`
+	Date          time.Time `bun:"date,type:date,notnull" json:"date"`
+	Payments      int       `bun:"payments,notnull,default:0" json:"payments"`
+	RefundCount   int       `bun:"refund_count,notnull,default:0" json:"refund_count"`
+	Gross         int64     `bun:"gross,notnull,default:0" json:"gross"`
+	Refunds       int64     `bun:"refunds,notnull,default:0" json:"refunds"`
+	Fees          int64     `bun:"fees,notnull,default:0" json:"fees"`
+	Net           int64     `bun:"net,notnull,default:0" json:"net"`
+
+	CreatedAt time.Time `bun:"created_at,nullzero,notnull,default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+var (
+	ErrSettlementExists = errors.New("Settlement exists")
+)
+
+/* {{{ [Actions] - Definitions */
+
+// Create: creates batch, ErrSettlementExists returned if the day of tenant and currency closed before
+func (m *Settlement) Create(ctx context.Context) error {
+	if m.ID == "" {
+		m.ID = uuid.NewString()
+	}
+
+	res, err := runtime.IDB(ctx).NewInsert().Model(m).
+		On("CONFLICT (tenant, currency, date) DO NOTHING").
+		Returning("").
+		Exec(ctx)
+	if err != nil {
+		runtime.Logger.Errorf("create settlement failed : %s", err)
+
+		return err
+	}
+
+	n, _ := res.RowsAffected()
+	if n == 0 {
+		return ErrSettlementExists
+	}
+
+	runtime.Logger.Infof("settlement [%s] of tenant [%s] created", m.ID, m.Tenant)
+
+	return nil
+}
+
+// Get
+func (m *Settlement) Get(ctx context.Context) error {
+	sq := runtime.IDB(ctx).NewSelect().Model(m).Where("id = ?", m.ID)
+	if m.Tenant != "" {
+		sq = sq.Where("tenant = ?", m.Tenant)
+	}
+
+	err := sq.Limit(1).Scan(ctx)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			runtime.Logger.Warnf("settlement does not exists")
+		} else {
+			runtime.Logger.Errorf("get settlement failed : %s", err)
+		}
+	}
+
+	return err
+}
+
+// List: list batches of tenant in date range [from, to], latest first. Zero dates mean no limit
+func (m *Settlement) List(ctx context.Context, from, to time.Time) ([]*Settlement, error) {
+	var settlements []*Settlement
+	sq := runtime.IDB(ctx).NewSelect().Model(&settlements).Where("tenant = ?", m.Tenant)
+	if m.Currency != "" {
+		sq = sq.Where("currency = ?", m.Currency)
+	}
+
+	if !from.IsZero() {
+		sq = sq.Where("date >= ?", from)
+	}
+
+	if !to.IsZero() {
+		sq = sq.Where("date <= ?", to)
+	}
+
+	err := sq.Order("date DESC", "currency ASC").Scan(ctx)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return settlements, nil
+		}
+
+		return nil, err
+	}
+
+	return settlements, nil
+}
+
+// Debug
+func (m *Settlement) Debug() string {
+	b, _ := json.MarshalIndent(m, "", "  ")
+
+	return string(b)
+}
+
+/* }}} */
+
+/*
+ * Local variables:
+ * tab-width: 4
+ * c-basic-offset: 4
+ * End:
+ * vim600: sw=4 ts=4 fdm=marker
+ * vim<600: sw=4 ts=4
+ */
@@ -0,0 +1,97 @@
+/*
+ * Copyright (C) HereweTech, Inc - All Rights Reserved
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+/**
+ * @file settlement_item.go
+ * @package model
+ * @author Dr.NP <np@herewe.tech>
+ * @since 10/18/2026
+ */
+
+package model
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"icepay-svc/runtime"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+// SettlementItem : line of statement, a payment or a refund. Refund lines carry negative amount and net
+type SettlementItem struct {
+	bun.BaseModel `bun:"table:settlement_item"`
+	ID            string    `bun:"id,pk" json:"id"`
+	Settlement    string    `bun:"settlement,notnull" json:"settlement"`
+	Tenant        string    `bun:"tenant,notnull" json:"tenant"`
+	Kind          string    `bun:"kind,notnull" json:"kind"`
+	Reference     string    `bun:"reference,notnull" json:"reference"` // Transaction or refund ID
+	Transaction   string    `bun:"transaction,notnull" json:"transaction"`
+	Amount        int64     `bun:"amount,notnull" json:"amount"`
+	Fee           int64     `bun:"fee,notnull,default:0" json:"fee"`
+	Net           int64     `bun:"net,notnull" json:"net"`
+	OccurredAt    time.Time `bun:"occurred_at,notnull" json:"occurred_at"`
+
+	CreatedAt time.Time `bun:"created_at,nullzero,notnull,default:CURRENT_TIMESTAMP" json:"created_at"`
+}
+
+/* {{{ [Actions] - Definitions */
+
+// Create
+func (m *SettlementItem) Create(ctx context.Context) error {
+	if m.ID == "" {
+		m.ID = uuid.NewString()
+	}
+
+	_, err := runtime.IDB(ctx).NewInsert().Model(m).Returning("").Exec(ctx)
+	if err != nil {
+		runtime.Logger.Errorf("create settlement item failed : %s", err)
+	}
+
+	return err
+}
+
+// List: list items of settlement in time order
+func (m *SettlementItem) List(ctx context.Context) ([]*SettlementItem, error) {
+	var items []*SettlementItem
+	sq := runtime.IDB(ctx).NewSelect().Model(&items).Where("settlement = ?", m.Settlement)
+	if m.Tenant != "" {
+		sq = sq.Where("tenant = ?", m.Tenant)
+	}
+
+	err := sq.Order("occurred_at ASC", "id ASC").Scan(ctx)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return items, nil
+		}
+
+		return nil, err
+	}
+
+	return items, nil
+}
+
+// Debug
+func (m *SettlementItem) Debug() string {
+	b, _ := json.MarshalIndent(m, "", "  ")
+
+	return string(b)
+}
+
+/* }}} */
+
+/*
+ * Local variables:
+ * tab-width: 4
+ * c-basic-offset: 4
+ * End:
+ * vim600: sw=4 ts=4 fdm=marker
+ * vim<600: sw=4 ts=4
+ */
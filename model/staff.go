@@ -0,0 +1,206 @@
+/*
+ * Copyright (C) HereweTech, Inc - All Rights Reserved
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+/**
+ * @file staff.go
+ * @package model
+ * @author Dr.NP <np@herewe.tech>
+ * @since 10/18/2026
+ */
+
+package model
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"icepay-svc/runtime"
+	"icepay-svc/utils"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+// Staff : sub-account of tenant, acting on behalf of the tenant with its own role. Email unique among staff not disabled
+type Staff struct {
+	bun.BaseModel   `bun:"table:staff"`
+	ID              string    `bun:"id,pk" json:"id"`
+	Tenant          string    `bun:"tenant,notnull" json:"tenant"`
+	Email           string    `bun:"email,notnull" json:"email"`
+	Name            string    `bun:"name" json:"name"`
+	Role            string    `bun:"role,notnull" json:"role"`
+	Status          string    `bun:"status,notnull" json:"status"`
+	Password        string    `bun:"password" json:"-"`
+	Salt            string    `bun:"salt" json:"-"`
+	InviteHash      string    `bun:"invite_hash,nullzero" json:"-"` // Digest of invitation code, cleared on acceptance
+	InviteExpiresAt time.Time `bun:"invite_expires_at,nullzero" json:"invite_expires_at"`
+	DisabledAt      time.Time `bun:"disabled_at,nullzero" json:"disabled_at"`
+
+	CreatedAt time.Time `bun:"created_at,nullzero,notnull,default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt time.Time `bun:"updated_at,nullzero,notnull,default:CURRENT_TIMESTAMP" json:"updated_at"`
+}
+
+var (
+	ErrStaffExists        = errors.New("Staff of email exists")
+	ErrStaffDoesNotExists = errors.New("Staff does not exists")
+)
+
+/* {{{ [Actions] - Definitions */
+
+// Create: invites staff, email taken by another staff not disabled rejected
+func (m *Staff) Create(ctx context.Context) error {
+	if m.ID == "" {
+		m.ID = uuid.NewString()
+	}
+
+	res, err := runtime.IDB(ctx).NewInsert().Model(m).
+		On("CONFLICT (email) WHERE status <> 'DISABLED' DO NOTHING").
+		Returning("").
+		Exec(ctx)
+	if err != nil {
+		runtime.Logger.Errorf("create staff failed : %s", err)
+
+		return err
+	}
+
+	n, _ := res.RowsAffected()
+	if n == 0 {
+		return ErrStaffExists
+	}
+
+	runtime.Logger.Infof("staff [%s] of tenant [%s] invited", m.ID, m.Tenant)
+
+	return nil
+}
+
+// Get: gets staff by id (of tenant), email or invitation, in status if given
+func (m *Staff) Get(ctx context.Context) error {
+	sq := runtime.IDB(ctx).NewSelect().Model(m)
+	if m.ID != "" {
+		sq = sq.Where("id = ?", m.ID)
+	}
+
+	if m.Tenant != "" {
+		sq = sq.Where("tenant = ?", m.Tenant)
+	}
+
+	if m.Email != "" {
+		sq = sq.Where("email = ?", m.Email)
+	}
+
+	if m.InviteHash != "" {
+		sq = sq.Where("invite_hash = ?", m.InviteHash)
+	}
+
+	if m.Status != "" {
+		sq = sq.Where("status = ?", m.Status)
+	}
+
+	err := sq.Limit(1).Scan(ctx)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			runtime.Logger.Warnf("staff does not exists")
+		} else {
+			runtime.Logger.Errorf("get staff failed : %s", err)
+		}
+	}
+
+	return err
+}
+
+// List: lists staff of tenant, disabled ones included
+func (m *Staff) List(ctx context.Context) ([]*Staff, error) {
+	var staff []*Staff
+	err := runtime.IDB(ctx).NewSelect().Model(&staff).
+		Where("tenant = ?", m.Tenant).
+		Order("created_at ASC").
+		Scan(ctx)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return staff, nil
+		}
+
+		return nil, err
+	}
+
+	return staff, nil
+}
+
+// Activate: sets password of invited staff, the invitation consumed
+func (m *Staff) Activate(ctx context.Context, status string) error {
+	res, err := runtime.IDB(ctx).NewUpdate().Model(m).
+		Set("status = ?", status).
+		Set("salt = ?", m.Salt).
+		Set("password = ?", utils.EncryptPassword(m.Password, m.Salt, m.Email)).
+		Set("invite_hash = NULL").
+		Set("updated_at = CURRENT_TIMESTAMP").
+		Where("id = ?", m.ID).
+		Where("invite_hash IS NOT NULL").
+		Returning("").
+		Exec(ctx)
+	if err != nil {
+		runtime.Logger.Errorf("activate staff [%s] failed : %s", m.ID, err)
+
+		return err
+	}
+
+	n, _ := res.RowsAffected()
+	if n == 0 {
+		return ErrStaffDoesNotExists
+	}
+
+	runtime.Logger.Infof("staff [%s] activated", m.ID)
+
+	return nil
+}
+
+// Disable: disables staff of tenant, invitation not accepted yet revoked too
+func (m *Staff) Disable(ctx context.Context, status string) error {
+	res, err := runtime.IDB(ctx).NewUpdate().Model(m).
+		Set("status = ?", status).
+		Set("invite_hash = NULL").
+		Set("disabled_at = CURRENT_TIMESTAMP").
+		Set("updated_at = CURRENT_TIMESTAMP").
+		Where("id = ?", m.ID).
+		Where("tenant = ?", m.Tenant).
+		Where("disabled_at IS NULL").
+		Returning("").
+		Exec(ctx)
+	if err != nil {
+		runtime.Logger.Errorf("disable staff [%s] failed : %s", m.ID, err)
+
+		return err
+	}
+
+	n, _ := res.RowsAffected()
+	if n == 0 {
+		return ErrStaffDoesNotExists
+	}
+
+	runtime.Logger.Infof("staff [%s] of tenant [%s] disabled", m.ID, m.Tenant)
+
+	return nil
+}
+
+// Debug
+func (m *Staff) Debug() string {
+	b, _ := json.MarshalIndent(m, "", "  ")
+
+	return string(b)
+}
+
+/* }}} */
+
+/*
+ * Local variables:
+ * tab-width: 4
+ * c-basic-offset: 4
+ * End:
+ * vim600: sw=4 ts=4 fdm=marker
+ * vim<600: sw=4 ts=4
+ */
@@ -22,16 +22,20 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect/pgdialect"
 )
 
 type Tenant struct {
 	bun.BaseModel `bun:"table:tenant"`
-	ID            string `bun:"id,pk" json:"id"`
-	Name          string `bun:"name,notnull" json:"name"`
-	Email         string `bun:"email,notnull" json:"email"`
-	Phone         string `bun:"phone" json:"phone"`
-	Password      string `bun:"password" json:"password"`
-	Salt          string `bun:"salt" json:"salt"`
+	ID            string   `bun:"id,pk" json:"id"`
+	Name          string   `bun:"name,notnull" json:"name"`
+	Email         string   `bun:"email,notnull" json:"email"`
+	Phone         string   `bun:"phone" json:"phone"`
+	Password      string   `bun:"password" json:"password"`
+	Salt          string   `bun:"salt" json:"salt"`
+	Currencies    []string `bun:"currencies,array" json:"currencies"` // Accepted, the default one if empty
+
+	PasswordChangedAt time.Time `bun:"password_changed_at,nullzero" json:"password_changed_at"`
 
 	CreatedAt time.Time `bun:"created_at,nullzero,notnull,default:CURRENT_TIMESTAMP" json:"created_at"`
 	UpdatedAt time.Time `bun:"updated_at,nullzero,notnull,default:CURRENT_TIMESTAMP" json:"updated_at"`
@@ -51,13 +55,13 @@ func (m *Tenant) Create(ctx context.Context) error {
 	}
 
 	if m.Password == "" {
-		m.Password = "__NOT_SET__"
+		m.Password = PasswordNotSet
 	} else {
 		// Encrypt
 		m.Password = utils.EncryptPassword(m.Password, m.Salt, m.Email)
 	}
 
-	_, err := runtime.DB.NewInsert().Model(m).Returning("").Exec(ctx)
+	_, err := runtime.IDB(ctx).NewInsert().Model(m).Returning("").Exec(ctx)
 	if err == nil {
 		runtime.Logger.Infof("tenent [%s] created", m.ID)
 	} else {
@@ -69,7 +73,7 @@ func (m *Tenant) Create(ctx context.Context) error {
 
 // Get: gets client
 func (m *Tenant) Get(ctx context.Context) error {
-	sq := runtime.DB.NewSelect().Model(m)
+	sq := runtime.IDB(ctx).NewSelect().Model(m)
 	if m.ID != "" {
 		sq = sq.Where("id = ?", m.ID)
 	}
@@ -92,7 +96,7 @@ func (m *Tenant) Get(ctx context.Context) error {
 
 // Update: updates tenant
 func (m *Tenant) Update(ctx context.Context) error {
-	uq := runtime.DB.NewUpdate().Model(m).Where("id = ?", m.ID)
+	uq := runtime.IDB(ctx).NewUpdate().Model(m).Where("id = ?", m.ID)
 	if m.Phone != "" {
 		uq = uq.Set("phone = ?", m.Phone)
 	}
@@ -101,15 +105,27 @@ func (m *Tenant) Update(ctx context.Context) error {
 		uq = uq.Set("name = ?", m.Name)
 	}
 
+	if m.Salt != "" {
+		uq = uq.Set("salt = ?", m.Salt)
+	}
+
 	if m.Password != "" {
 		uq = uq.Set("password = ?", utils.EncryptPassword(m.Password, m.Salt, m.Email))
 	}
 
-	_, err := uq.Returning("").Exec(ctx)
+	if !m.PasswordChangedAt.IsZero() {
+		uq = uq.Set("password_changed_at = ?", m.PasswordChangedAt)
+	}
+
+	if m.Currencies != nil {
+		uq = uq.Set("currencies = ?", pgdialect.Array(m.Currencies))
+	}
+
+	_, err := uq.Set("updated_at = CURRENT_TIMESTAMP").Returning("").Exec(ctx)
 	if err == nil {
-		runtime.Logger.Infof("client [%s] updated", m.ID)
+		runtime.Logger.Infof("tenant [%s] updated", m.ID)
 	} else {
-		runtime.Logger.Errorf("update client failed : %s", err)
+		runtime.Logger.Errorf("update tenant failed : %s", err)
 	}
 
 	return err
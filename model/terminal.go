@@ -0,0 +1,166 @@
+/*
+ * Copyright (C) HereweTech, Inc - All Rights Reserved
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+/**
+ * @file terminal.go
+ * @package model
+ * @author Dr.NP <np@herewe.tech>
+ * @since 10/18/2026
+ */
+
+package model
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"icepay-svc/runtime"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+// Terminal : POS device of tenant, authenticated by its device key. Only digest of the key stored
+type Terminal struct {
+	bun.BaseModel `bun:"table:terminal"`
+	ID            string    `bun:"id,pk" json:"id"`
+	Tenant        string    `bun:"tenant,notnull" json:"tenant"`
+	Label         string    `bun:"label" json:"label"`
+	KeyHash       string    `bun:"key_hash,notnull" json:"-"`
+	KeyPrefix     string    `bun:"key_prefix,notnull" json:"key_prefix"` // Leading characters of key, telling keys apart
+	LastUsedAt    time.Time `bun:"last_used_at,nullzero" json:"last_used_at"`
+	DisabledAt    time.Time `bun:"disabled_at,nullzero" json:"disabled_at"`
+
+	CreatedAt time.Time `bun:"created_at,nullzero,notnull,default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt time.Time `bun:"updated_at,nullzero,notnull,default:CURRENT_TIMESTAMP" json:"updated_at"`
+}
+
+var (
+	ErrTerminalDoesNotExists = errors.New("Terminal does not exists")
+)
+
+/* {{{ [Actions] - Definitions */
+
+// Create
+func (m *Terminal) Create(ctx context.Context) error {
+	if m.ID == "" {
+		m.ID = uuid.NewString()
+	}
+
+	_, err := runtime.IDB(ctx).NewInsert().Model(m).Returning("").Exec(ctx)
+	if err == nil {
+		runtime.Logger.Infof("terminal [%s] of tenant [%s] registered", m.ID, m.Tenant)
+	} else {
+		runtime.Logger.Errorf("create terminal failed : %s", err)
+	}
+
+	return err
+}
+
+// Get: gets terminal by id (of tenant) or by digest of key, disabled ones included
+func (m *Terminal) Get(ctx context.Context) error {
+	sq := runtime.IDB(ctx).NewSelect().Model(m)
+	if m.ID != "" {
+		sq = sq.Where("id = ?", m.ID)
+	}
+
+	if m.Tenant != "" {
+		sq = sq.Where("tenant = ?", m.Tenant)
+	}
+
+	if m.KeyHash != "" {
+		sq = sq.Where("key_hash = ?", m.KeyHash)
+	}
+
+	err := sq.Limit(1).Scan(ctx)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			runtime.Logger.Warnf("terminal does not exists")
+		} else {
+			runtime.Logger.Errorf("get terminal failed : %s", err)
+		}
+	}
+
+	return err
+}
+
+// List: lists terminals of tenant
+func (m *Terminal) List(ctx context.Context) ([]*Terminal, error) {
+	var terminals []*Terminal
+	err := runtime.IDB(ctx).NewSelect().Model(&terminals).
+		Where("tenant = ?", m.Tenant).
+		Order("created_at ASC").
+		Scan(ctx)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return terminals, nil
+		}
+
+		return nil, err
+	}
+
+	return terminals, nil
+}
+
+// Touch: records the time key used
+func (m *Terminal) Touch(ctx context.Context) error {
+	_, err := runtime.IDB(ctx).NewUpdate().Model(m).
+		Set("last_used_at = CURRENT_TIMESTAMP").
+		Where("id = ?", m.ID).
+		Returning("").
+		Exec(ctx)
+	if err != nil {
+		runtime.Logger.Errorf("touch terminal [%s] failed : %s", m.ID, err)
+	}
+
+	return err
+}
+
+// Disable: disables terminal of tenant, its key not accepted anymore
+func (m *Terminal) Disable(ctx context.Context) error {
+	res, err := runtime.IDB(ctx).NewUpdate().Model(m).
+		Set("disabled_at = CURRENT_TIMESTAMP").
+		Set("updated_at = CURRENT_TIMESTAMP").
+		Where("id = ?", m.ID).
+		Where("tenant = ?", m.Tenant).
+		Where("disabled_at IS NULL").
+		Returning("").
+		Exec(ctx)
+	if err != nil {
+		runtime.Logger.Errorf("disable terminal [%s] failed : %s", m.ID, err)
+
+		return err
+	}
+
+	n, _ := res.RowsAffected()
+	if n == 0 {
+		return ErrTerminalDoesNotExists
+	}
+
+	runtime.Logger.Infof("terminal [%s] of tenant [%s] disabled", m.ID, m.Tenant)
+
+	return nil
+}
+
+// Debug
+func (m *Terminal) Debug() string {
+	b, _ := json.MarshalIndent(m, "", "  ")
+
+	return string(b)
+}
+
+/* }}} */
+
+/*
+ * Local variables:
+ * tab-width: 4
+ * c-basic-offset: 4
+ * End:
+ * vim600: sw=4 ts=4 fdm=marker
+ * vim<600: sw=4 ts=4
+ */
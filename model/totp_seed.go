@@ -0,0 +1,165 @@
+/*
+ * Copyright (C) HereweTech, Inc - All Rights Reserved
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+/**
+ * @file totp_seed.go
+ * @package model
+ * @author Dr.NP <np@herewe.tech>
+ * @since 10/18/2026
+ */
+
+package model
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"icepay-svc/runtime"
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+// TOTPSeed : seed of offline payment codes, one per client. Index is the public part of codes, resolves code to client
+type TOTPSeed struct {
+	bun.BaseModel `bun:"table:totp_seed"`
+	Client        string `bun:"client,pk" json:"client"`
+	Index         string `bun:"code_index,notnull,unique" json:"index"`
+	Seed          []byte `bun:"seed,type:bytea,notnull" json:"-"` // Sealed by credential keyring
+	KeyID         string `bun:"key_id,notnull" json:"key_id"`
+
+	Failures    int       `bun:"failures,notnull,default:0" json:"failures"`
+	LockedUntil time.Time `bun:"locked_until,nullzero" json:"locked_until"`
+
+	CreatedAt time.Time `bun:"created_at,nullzero,notnull,default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt time.Time `bun:"updated_at,nullzero,notnull,default:CURRENT_TIMESTAMP" json:"updated_at"`
+}
+
+var (
+	ErrTOTPSeedDoesNotExists = errors.New("TOTP seed does not exists")
+)
+
+/* {{{ [Actions] - Definitions */
+
+// Create: provisions seed of client, replaces the existing one
+func (m *TOTPSeed) Create(ctx context.Context) error {
+	_, err := runtime.IDB(ctx).NewInsert().Model(m).
+		On("CONFLICT (client) DO UPDATE").
+		Set("code_index = EXCLUDED.code_index").
+		Set("seed = EXCLUDED.seed").
+		Set("key_id = EXCLUDED.key_id").
+		Set("failures = 0").
+		Set("locked_until = NULL").
+		Set("updated_at = CURRENT_TIMESTAMP").
+		Returning("").
+		Exec(ctx)
+	if err == nil {
+		runtime.Logger.Infof("TOTP seed of client [%s] provisioned", m.Client)
+	} else {
+		runtime.Logger.Errorf("provision TOTP seed failed : %s", err)
+	}
+
+	return err
+}
+
+// Get: gets seed by client or index
+func (m *TOTPSeed) Get(ctx context.Context) error {
+	sq := runtime.IDB(ctx).NewSelect().Model(m)
+	if m.Client != "" {
+		sq = sq.Where("client = ?", m.Client)
+	}
+
+	if m.Index != "" {
+		sq = sq.Where("code_index = ?", m.Index)
+	}
+
+	err := sq.Limit(1).Scan(ctx)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrTOTPSeedDoesNotExists
+		}
+
+		runtime.Logger.Errorf("get TOTP seed failed : %s", err)
+	}
+
+	return err
+}
+
+// Fail: counts a failed code of index, locks the index until lockUntil once limit reached
+func (m *TOTPSeed) Fail(ctx context.Context, limit int, lockUntil time.Time) error {
+	_, err := runtime.IDB(ctx).NewUpdate().Model(m).
+		Set("failures = CASE WHEN failures + 1 >= ? THEN 0 ELSE failures + 1 END", limit).
+		Set("locked_until = CASE WHEN failures + 1 >= ? THEN ? ELSE locked_until END", limit, lockUntil).
+		Where("client = ?", m.Client).
+		Where("code_index = ?", m.Index).
+		Returning("failures, locked_until").
+		Exec(ctx)
+	if err != nil {
+		runtime.Logger.Errorf("count TOTP failure of client [%s] failed : %s", m.Client, err)
+	}
+
+	return err
+}
+
+// ResetFailures: clears failures and lock of index
+func (m *TOTPSeed) ResetFailures(ctx context.Context) error {
+	_, err := runtime.IDB(ctx).NewUpdate().Model(m).
+		Set("failures = 0").
+		Set("locked_until = NULL").
+		Where("client = ?", m.Client).
+		Where("code_index = ?", m.Index).
+		Returning("").
+		Exec(ctx)
+	if err == nil {
+		m.Failures = 0
+		m.LockedUntil = time.Time{}
+	} else {
+		runtime.Logger.Errorf("reset TOTP failures of client [%s] failed : %s", m.Client, err)
+	}
+
+	return err
+}
+
+// Delete: revokes seed of client
+func (m *TOTPSeed) Delete(ctx context.Context) error {
+	res, err := runtime.IDB(ctx).NewDelete().
+		Model(m).
+		Where("client = ?", m.Client).
+		Exec(ctx)
+	if err != nil {
+		runtime.Logger.Errorf("revoke TOTP seed of client [%s] failed : %s", m.Client, err)
+
+		return err
+	}
+
+	n, _ := res.RowsAffected()
+	if n == 0 {
+		return ErrTOTPSeedDoesNotExists
+	}
+
+	runtime.Logger.Infof("TOTP seed of client [%s] revoked", m.Client)
+
+	return nil
+}
+
+// Debug
+func (m *TOTPSeed) Debug() string {
+	b, _ := json.MarshalIndent(m, "", "  ")
+
+	return string(b)
+}
+
+/* }}} */
+
+/*
+ * Local variables:
+ * tab-width: 4
+ * c-basic-offset: 4
+ * End:
+ * vim600: sw=4 ts=4 fdm=marker
+ * vim<600: sw=4 ts=4
+ */
@@ -19,6 +19,7 @@ import (
 	"encoding/json"
 	"errors"
 	"icepay-svc/runtime"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -31,16 +32,58 @@ type Transaction struct {
 	Client        string `bun:"client,notnull" json:"client"`
 	Tenant        string `bun:"tenant,notnull" json:"tenant"`
 	Amount        int64  `bun:"amount,notnull" json:"amount"`
+	Refunded      int64  `bun:"refunded,notnull,default:0" json:"refunded"`
+	Fee           int64  `bun:"fee,notnull,default:0" json:"-"` // Charged to tenant at confirmation
 	Currency      string `bun:"currency,notnull" json:"currency"`
 	Status        string `bun:"status" json:"status"`
 	Card          string `bun:"card" json:"card"`
 	Detail        string `bun:"detail" json:"detail"`
+	Staff         string `bun:"staff,nullzero" json:"staff"`       // Staff of tenant who created it
+	Terminal      string `bun:"terminal,nullzero" json:"terminal"` // Terminal it created on
+
+	ClaimedAt   time.Time `bun:"claimed_at,nullzero" json:"claimed_at"` // Payment request claimed by client
+	ConfirmedAt time.Time `bun:"confirmed_at,nullzero" json:"confirmed_at"`
+	CreatedAt   time.Time `bun:"created_at,nullzero,notnull,default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt   time.Time `bun:"updated_at,nullzero,notnull,default:CURRENT_TIMESTAMP" json:"updated_at"`
+	DeletedAt   time.Time `bun:"deleted_at,soft_delete,nullzero" json:"-"`
+}
+
+// TransactionFilter : conditions, order and keyset page of list, zero values mean no condition
+type TransactionFilter struct {
+	Statuses    []string
+	Currency    string
+	MinAmount   int64
+	MaxAmount   int64
+	CreatedFrom time.Time
+	CreatedTo   time.Time
+	Search      string // Substring of detail
+	SortBy      string // created_at or amount, ties broken by id
+	Desc        bool
+	After       *TransactionCursor
+	Limit       int
+}
 
-	CreatedAt time.Time `bun:"created_at,nullzero,notnull,default:CURRENT_TIMESTAMP" json:"created_at"`
-	UpdatedAt time.Time `bun:"updated_at,nullzero,notnull,default:CURRENT_TIMESTAMP" json:"updated_at"`
-	DeletedAt time.Time `bun:"deleted_at,soft_delete,nullzero" json:"-"`
+// TransactionCursor : sort key of the last row of previous page, with the order it was taken in
+type TransactionCursor struct {
+	SortBy    string    `json:"s"`
+	Desc      bool      `json:"d,omitempty"`
+	CreatedAt time.Time `json:"c,omitempty"`
+	Amount    int64     `json:"a,omitempty"`
+	ID        string    `json:"i"`
 }
 
+// Wildcards of LIKE patterns matched literally
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+const (
+	TransactionSortCreatedAt = "created_at"
+	TransactionSortAmount    = "amount"
+)
+
+var (
+	ErrTransactionStatusMismatch = errors.New("Transaction status mismatch")
+)
+
 /* {{{ [Actions] - Definitions */
 
 // Create
@@ -49,7 +92,7 @@ func (m *Transaction) Create(ctx context.Context) error {
 		m.ID = uuid.NewString()
 	}
 
-	_, err := runtime.DB.NewInsert().Model(m).Returning("").Exec(ctx)
+	_, err := runtime.IDB(ctx).NewInsert().Model(m).Returning("").Exec(ctx)
 	if err == nil {
 		runtime.Logger.Infof("Transaction [%s] created", m.ID)
 	}
@@ -57,13 +100,28 @@ func (m *Transaction) Create(ctx context.Context) error {
 	return err
 }
 
-// Update: updates transcation
-func (m *Transaction) Update(ctx context.Context) error {
-	uq := runtime.DB.NewUpdate().Model(m).Set("status = ?", m.Status).Set("updated_at = CURRENT_TIMESTAMP")
+// Update: updates transcation, only if it is still in the expected status
+func (m *Transaction) Update(ctx context.Context, expected string) error {
+	uq := runtime.IDB(ctx).NewUpdate().Model(m).
+		Set("status = ?", m.Status).
+		Set("updated_at = CURRENT_TIMESTAMP").
+		Where("status = ?", expected)
 	if m.Card != "" {
 		uq = uq.Set("card = ?", m.Card)
 	}
 
+	if m.Refunded > 0 {
+		uq = uq.Set("refunded = ?", m.Refunded)
+	}
+
+	if !m.ConfirmedAt.IsZero() {
+		uq = uq.Set("confirmed_at = ?", m.ConfirmedAt)
+	}
+
+	if m.Fee > 0 {
+		uq = uq.Set("fee = ?", m.Fee)
+	}
+
 	if m.ID != "" {
 		uq = uq.Where("id = ?", m.ID)
 	}
@@ -73,20 +131,57 @@ func (m *Transaction) Update(ctx context.Context) error {
 	}
 
 	if m.Tenant != "" {
-		uq = uq.Where("tenent = ?", m.Tenant)
+		uq = uq.Where("tenant = ?", m.Tenant)
 	}
 
-	_, err := uq.Returning("").Exec(ctx)
+	res, err := uq.Returning("").Exec(ctx)
 	if err != nil {
 		runtime.Logger.Errorf("Update transaction failed : %s", err)
+
+		return err
 	}
 
-	return err
+	n, _ := res.RowsAffected()
+	if n == 0 {
+		runtime.Logger.Warnf("transaction [%s] is not in status [%s]", m.ID, expected)
+
+		return ErrTransactionStatusMismatch
+	}
+
+	return nil
+}
+
+// Claim: binds transaction without client to m.Client and moves it to m.Status, only if it is still in the expected status
+func (m *Transaction) Claim(ctx context.Context, expected string) error {
+	res, err := runtime.IDB(ctx).NewUpdate().Model(m).
+		Set("client = ?", m.Client).
+		Set("status = ?", m.Status).
+		Set("claimed_at = CURRENT_TIMESTAMP").
+		Set("updated_at = CURRENT_TIMESTAMP").
+		Where("id = ?", m.ID).
+		Where("status = ?", expected).
+		Where("client = ''").
+		Returning("claimed_at").
+		Exec(ctx)
+	if err != nil {
+		runtime.Logger.Errorf("claim transaction [%s] failed : %s", m.ID, err)
+
+		return err
+	}
+
+	n, _ := res.RowsAffected()
+	if n == 0 {
+		runtime.Logger.Warnf("transaction [%s] is not claimable in status [%s]", m.ID, expected)
+
+		return ErrTransactionStatusMismatch
+	}
+
+	return nil
 }
 
 // Get
 func (m *Transaction) Get(ctx context.Context) error {
-	sq := runtime.DB.NewSelect().Model(m)
+	sq := runtime.IDB(ctx).NewSelect().Model(m)
 	if m.ID != "" {
 		sq = sq.Where("id = ?", m.ID)
 	}
@@ -96,7 +191,7 @@ func (m *Transaction) Get(ctx context.Context) error {
 	}
 
 	if m.Tenant != "" {
-		sq = sq.Where("tenent = ?", m.Tenant)
+		sq = sq.Where("tenant = ?", m.Tenant)
 	}
 
 	if m.Status != "" {
@@ -115,10 +210,9 @@ func (m *Transaction) Get(ctx context.Context) error {
 	return err
 }
 
-// List: list transaction by given conditions
-func (m *Transaction) List(ctx context.Context) ([]*Transaction, error) {
-	var transactions []*Transaction
-	sq := runtime.DB.NewSelect().Model(&transactions)
+// Lock: gets transaction and locks the row until the end of database transaction bound to ctx
+func (m *Transaction) Lock(ctx context.Context) error {
+	sq := runtime.IDB(ctx).NewSelect().Model(m).Where("id = ?", m.ID)
 	if m.Client != "" {
 		sq = sq.Where("client = ?", m.Client)
 	}
@@ -127,22 +221,153 @@ func (m *Transaction) List(ctx context.Context) ([]*Transaction, error) {
 		sq = sq.Where("tenant = ?", m.Tenant)
 	}
 
-	if m.Status != "" {
-		sq = sq.Where("status = ?", m.Status)
+	err := sq.For("UPDATE").Limit(1).Scan(ctx)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		runtime.Logger.Errorf("lock transaction [%s] failed : %s", m.ID, err)
 	}
 
-	err := sq.Scan(ctx)
+	return err
+}
+
+// LockExpired: gets transactions in given statuses created (or claimed) before the deadline and locks them,
+// rows locked by others are skipped
+func (m *Transaction) LockExpired(ctx context.Context, statuses []string, deadline time.Time, limit int) ([]*Transaction, error) {
+	var transactions []*Transaction
+	err := runtime.IDB(ctx).NewSelect().Model(&transactions).
+		Where("status IN (?)", bun.In(statuses)).
+		Where("COALESCE(claimed_at, created_at) < ?", deadline).
+		OrderExpr("COALESCE(claimed_at, created_at) ASC").
+		Limit(limit).
+		For("UPDATE SKIP LOCKED").
+		Scan(ctx)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return transactions, nil
 		}
 
+		runtime.Logger.Errorf("lock expired transactions failed : %s", err)
+
 		return nil, err
 	}
 
 	return transactions, nil
 }
 
+// ListConfirmed: list transactions in given statuses confirmed in [from, to), by tenant and currency
+func (m *Transaction) ListConfirmed(ctx context.Context, statuses []string, from, to time.Time) ([]*Transaction, error) {
+	var transactions []*Transaction
+	err := runtime.IDB(ctx).NewSelect().Model(&transactions).
+		Where("status IN (?)", bun.In(statuses)).
+		Where("confirmed_at >= ?", from).
+		Where("confirmed_at < ?", to).
+		Order("tenant ASC", "currency ASC", "confirmed_at ASC", "id ASC").
+		Scan(ctx)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return transactions, nil
+		}
+
+		runtime.Logger.Errorf("list confirmed transactions failed : %s", err)
+
+		return nil, err
+	}
+
+	return transactions, nil
+}
+
+// List: list transaction by given conditions and filter, total counts all pages
+func (m *Transaction) List(ctx context.Context, filter *TransactionFilter) ([]*Transaction, int, error) {
+	var transactions []*Transaction
+	sq := m.filter(runtime.IDB(ctx).NewSelect().Model(&transactions), filter)
+	total, err := sq.Count(ctx)
+	if err != nil {
+		runtime.Logger.Errorf("count transactions failed : %s", err)
+
+		return nil, 0, err
+	}
+
+	sortBy := TransactionSortCreatedAt
+	if filter.SortBy == TransactionSortAmount {
+		sortBy = TransactionSortAmount
+	}
+
+	op, dir := ">", "ASC"
+	if filter.Desc {
+		op, dir = "<", "DESC"
+	}
+
+	if filter.After != nil {
+		var value interface{} = filter.After.CreatedAt
+		if sortBy == TransactionSortAmount {
+			value = filter.After.Amount
+		}
+
+		sq = sq.Where("(?, id) "+op+" (?, ?)", bun.Ident(sortBy), value, filter.After.ID)
+	}
+
+	sq = sq.OrderExpr("? "+dir+", id "+dir, bun.Ident(sortBy))
+	if filter.Limit > 0 {
+		sq = sq.Limit(filter.Limit)
+	}
+
+	err = sq.Scan(ctx)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return transactions, total, nil
+		}
+
+		runtime.Logger.Errorf("list transactions failed : %s", err)
+
+		return nil, 0, err
+	}
+
+	return transactions, total, nil
+}
+
+func (m *Transaction) filter(sq *bun.SelectQuery, filter *TransactionFilter) *bun.SelectQuery {
+	if m.Client != "" {
+		sq = sq.Where("client = ?", m.Client)
+	}
+
+	if m.Tenant != "" {
+		sq = sq.Where("tenant = ?", m.Tenant)
+	}
+
+	if m.Status != "" {
+		sq = sq.Where("status = ?", m.Status)
+	}
+
+	if len(filter.Statuses) > 0 {
+		sq = sq.Where("status IN (?)", bun.In(filter.Statuses))
+	}
+
+	if filter.Currency != "" {
+		sq = sq.Where("currency = ?", filter.Currency)
+	}
+
+	if filter.MinAmount > 0 {
+		sq = sq.Where("amount >= ?", filter.MinAmount)
+	}
+
+	if filter.MaxAmount > 0 {
+		sq = sq.Where("amount <= ?", filter.MaxAmount)
+	}
+
+	if !filter.CreatedFrom.IsZero() {
+		sq = sq.Where("created_at >= ?", filter.CreatedFrom)
+	}
+
+	if !filter.CreatedTo.IsZero() {
+		sq = sq.Where("created_at < ?", filter.CreatedTo)
+	}
+
+	if filter.Search != "" {
+		sq = sq.Where("detail ILIKE ? ESCAPE '\\'", "%"+likeEscaper.Replace(filter.Search)+"%")
+	}
+
+	return sq
+}
+
 // Debug
 func (m *Transaction) Debug() string {
 	b, _ := json.MarshalIndent(m, "", "  ")
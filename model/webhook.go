@@ -0,0 +1,174 @@
+/*
+ * Copyright (C) HereweTech, Inc - All Rights Reserved
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+/**
+ * @file webhook.go
+ * @package model
+ * @author Dr.NP <np@herewe.tech>
+ * @since 10/18/2026
+ */
+
+package model
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"icepay-svc/runtime"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+type Webhook struct {
+	bun.BaseModel `bun:"table:webhook"`
+	ID            string `bun:"id,pk" json:"id"`
+	Tenant        string `bun:"tenant,notnull" json:"tenant"`
+	URL           string `bun:"url,notnull" json:"url"`
+	Secret        string `bun:"secret,notnull" json:"secret"`
+	Description   string `bun:"description" json:"description"`
+	Enabled       bool   `bun:"enabled,notnull" json:"enabled"`
+
+	CreatedAt time.Time `bun:"created_at,nullzero,notnull,default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt time.Time `bun:"updated_at,nullzero,notnull,default:CURRENT_TIMESTAMP" json:"updated_at"`
+	DeletedAt time.Time `bun:"deleted_at,soft_delete,nullzero" json:"-"`
+}
+
+var (
+	ErrWebhookDoesNotExists = errors.New("Webhook does not exists")
+)
+
+/* {{{ [Actions] - Definitions */
+
+// Create
+func (m *Webhook) Create(ctx context.Context) error {
+	if m.ID == "" {
+		m.ID = uuid.NewString()
+	}
+
+	_, err := runtime.IDB(ctx).NewInsert().Model(m).Returning("").Exec(ctx)
+	if err == nil {
+		runtime.Logger.Infof("webhook [%s] created", m.ID)
+	} else {
+		runtime.Logger.Errorf("create webhook failed : %s", err)
+	}
+
+	return err
+}
+
+// Get
+func (m *Webhook) Get(ctx context.Context) error {
+	sq := runtime.IDB(ctx).NewSelect().Model(m).Where("id = ?", m.ID)
+	if m.Tenant != "" {
+		sq = sq.Where("tenant = ?", m.Tenant)
+	}
+
+	err := sq.Limit(1).Scan(ctx)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			runtime.Logger.Warnf("webhook does not exists")
+		} else {
+			runtime.Logger.Errorf("get webhook failed : %s", err)
+		}
+	}
+
+	return err
+}
+
+// List: list webhooks of tenant
+func (m *Webhook) List(ctx context.Context) ([]*Webhook, error) {
+	var webhooks []*Webhook
+	err := runtime.IDB(ctx).NewSelect().Model(&webhooks).
+		Where("tenant = ?", m.Tenant).
+		Order("created_at ASC").
+		Scan(ctx)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return webhooks, nil
+		}
+
+		return nil, err
+	}
+
+	return webhooks, nil
+}
+
+// Update: updates url, description, secret and enabled flag
+func (m *Webhook) Update(ctx context.Context) error {
+	uq := runtime.IDB(ctx).NewUpdate().Model(m).
+		Set("enabled = ?", m.Enabled).
+		Set("updated_at = CURRENT_TIMESTAMP").
+		Where("id = ?", m.ID).
+		Where("tenant = ?", m.Tenant)
+	if m.URL != "" {
+		uq = uq.Set("url = ?", m.URL)
+	}
+
+	if m.Description != "" {
+		uq = uq.Set("description = ?", m.Description)
+	}
+
+	if m.Secret != "" {
+		uq = uq.Set("secret = ?", m.Secret)
+	}
+
+	res, err := uq.Returning("").Exec(ctx)
+	if err != nil {
+		runtime.Logger.Errorf("update webhook [%s] failed : %s", m.ID, err)
+
+		return err
+	}
+
+	n, _ := res.RowsAffected()
+	if n == 0 {
+		return ErrWebhookDoesNotExists
+	}
+
+	return nil
+}
+
+// Delete: delete(soft) webhook
+func (m *Webhook) Delete(ctx context.Context) error {
+	res, err := runtime.IDB(ctx).NewDelete().
+		Model(m).
+		Where("id = ?", m.ID).
+		Where("tenant = ?", m.Tenant).
+		Exec(ctx)
+	if err == nil {
+		n, _ := res.RowsAffected()
+		if n == 0 {
+			runtime.Logger.Warnf("webhook [%s] does not exists", m.ID)
+
+			return ErrWebhookDoesNotExists
+		} else {
+			runtime.Logger.Infof("webhook [%s] deleted", m.ID)
+		}
+	} else {
+		runtime.Logger.Errorf("webhook [%s] delete failed : %s", m.ID, err)
+	}
+
+	return err
+}
+
+// Debug
+func (m *Webhook) Debug() string {
+	b, _ := json.MarshalIndent(m, "", "  ")
+
+	return string(b)
+}
+
+/* }}} */
+
+/*
+ * Local variables:
+ * tab-width: 4
+ * c-basic-offset: 4
+ * End:
+ * vim600: sw=4 ts=4 fdm=marker
+ * vim<600: sw=4 ts=4
+ */
@@ -0,0 +1,177 @@
+/*
+ * Copyright (C) HereweTech, Inc - All Rights Reserved
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+/**
+ * @file webhook_delivery.go
+ * @package model
+ * @author Dr.NP <np@herewe.tech>
+ * @since 10/18/2026
+ */
+
+package model
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"icepay-svc/runtime"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/uptrace/bun"
+)
+
+type WebhookDelivery struct {
+	bun.BaseModel  `bun:"table:webhook_delivery"`
+	ID             string    `bun:"id,pk" json:"id"`
+	Webhook        string    `bun:"webhook,notnull" json:"webhook"`
+	Tenant         string    `bun:"tenant,notnull" json:"tenant"`
+	Transaction    string    `bun:"transaction" json:"transaction"`
+	Event          string    `bun:"event,notnull" json:"event"`
+	Payload        string    `bun:"payload,notnull" json:"payload"`
+	Status         string    `bun:"status,notnull" json:"status"`
+	Attempts       int       `bun:"attempts,notnull,default:0" json:"attempts"`
+	NextAttemptAt  time.Time `bun:"next_attempt_at,nullzero" json:"next_attempt_at"`
+	LastStatusCode int       `bun:"last_status_code,notnull,default:0" json:"last_status_code"`
+	LastError      string    `bun:"last_error" json:"last_error"`
+
+	CreatedAt time.Time `bun:"created_at,nullzero,notnull,default:CURRENT_TIMESTAMP" json:"created_at"`
+	UpdatedAt time.Time `bun:"updated_at,nullzero,notnull,default:CURRENT_TIMESTAMP" json:"updated_at"`
+}
+
+/* {{{ [Actions] - Definitions */
+
+// Create
+func (m *WebhookDelivery) Create(ctx context.Context) error {
+	if m.ID == "" {
+		m.ID = uuid.NewString()
+	}
+
+	_, err := runtime.IDB(ctx).NewInsert().Model(m).Returning("").Exec(ctx)
+	if err != nil {
+		runtime.Logger.Errorf("create webhook delivery failed : %s", err)
+	}
+
+	return err
+}
+
+// Get
+func (m *WebhookDelivery) Get(ctx context.Context) error {
+	sq := runtime.IDB(ctx).NewSelect().Model(m).Where("id = ?", m.ID)
+	if m.Webhook != "" {
+		sq = sq.Where("webhook = ?", m.Webhook)
+	}
+
+	if m.Tenant != "" {
+		sq = sq.Where("tenant = ?", m.Tenant)
+	}
+
+	err := sq.Limit(1).Scan(ctx)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		runtime.Logger.Errorf("get webhook delivery failed : %s", err)
+	}
+
+	return err
+}
+
+// Lock: gets delivery and locks the row, returns sql.ErrNoRows if locked by others
+func (m *WebhookDelivery) Lock(ctx context.Context) error {
+	err := runtime.IDB(ctx).NewSelect().Model(m).
+		Where("id = ?", m.ID).
+		For("UPDATE SKIP LOCKED").
+		Limit(1).
+		Scan(ctx)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		runtime.Logger.Errorf("lock webhook delivery [%s] failed : %s", m.ID, err)
+	}
+
+	return err
+}
+
+// LockDue: gets pending deliveries due before now and locks them, rows locked by others are skipped
+func (m *WebhookDelivery) LockDue(ctx context.Context, status string, now time.Time, limit int) ([]*WebhookDelivery, error) {
+	var deliveries []*WebhookDelivery
+	err := runtime.IDB(ctx).NewSelect().Model(&deliveries).
+		Where("status = ?", status).
+		Where("next_attempt_at <= ?", now).
+		Order("next_attempt_at ASC").
+		Limit(limit).
+		For("UPDATE SKIP LOCKED").
+		Scan(ctx)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return deliveries, nil
+		}
+
+		runtime.Logger.Errorf("lock due webhook deliveries failed : %s", err)
+
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+// List: list deliveries of webhook, latest first
+func (m *WebhookDelivery) List(ctx context.Context, limit int) ([]*WebhookDelivery, error) {
+	var deliveries []*WebhookDelivery
+	sq := runtime.IDB(ctx).NewSelect().Model(&deliveries).Where("webhook = ?", m.Webhook)
+	if m.Tenant != "" {
+		sq = sq.Where("tenant = ?", m.Tenant)
+	}
+
+	if m.Status != "" {
+		sq = sq.Where("status = ?", m.Status)
+	}
+
+	err := sq.Order("created_at DESC").Limit(limit).Scan(ctx)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return deliveries, nil
+		}
+
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+// Update: records result of delivery attempt
+func (m *WebhookDelivery) Update(ctx context.Context) error {
+	_, err := runtime.IDB(ctx).NewUpdate().Model(m).
+		Set("status = ?", m.Status).
+		Set("attempts = ?", m.Attempts).
+		Set("next_attempt_at = ?", m.NextAttemptAt).
+		Set("last_status_code = ?", m.LastStatusCode).
+		Set("last_error = ?", m.LastError).
+		Set("updated_at = CURRENT_TIMESTAMP").
+		Where("id = ?", m.ID).
+		Returning("").
+		Exec(ctx)
+	if err != nil {
+		runtime.Logger.Errorf("update webhook delivery [%s] failed : %s", m.ID, err)
+	}
+
+	return err
+}
+
+// Debug
+func (m *WebhookDelivery) Debug() string {
+	b, _ := json.MarshalIndent(m, "", "  ")
+
+	return string(b)
+}
+
+/* }}} */
+
+/*
+ * Local variables:
+ * tab-width: 4
+ * c-basic-offset: 4
+ * End:
+ * vim600: sw=4 ts=4 fdm=marker
+ * vim<600: sw=4 ts=4
+ */
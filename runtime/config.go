@@ -27,26 +27,68 @@ const (
 
 type mainConfig struct {
 	HTTP struct {
-		ListenAddr         string `json:"listen_addr" mapstructure:"listen_addr"`
-		Prefork            bool   `json:"prefork" mapstructure:"prefork"`
-		LongPollingTimeout int64  `json:"long_polling_timeout" mapstructure:"long_polling_timeout"` // In second
+		ListenAddr             string   `json:"listen_addr" mapstructure:"listen_addr"`
+		Prefork                bool     `json:"prefork" mapstructure:"prefork"`
+		LongPollingTimeout     int64    `json:"long_polling_timeout" mapstructure:"long_polling_timeout"`         // In second
+		IdempotencyKeyLifetime int64    `json:"idempotency_key_lifetime" mapstructure:"idempotency_key_lifetime"` // In hour
+		IdempotencyLease       int64    `json:"idempotency_lease" mapstructure:"idempotency_lease"`               // In second, key of request never finished claimable again after it
+		EventHeartbeat         int64    `json:"event_heartbeat" mapstructure:"event_heartbeat"`                   // In second
+		ProxyHeader            string   `json:"proxy_header" mapstructure:"proxy_header"`                         // Client IP header appended by reverse proxy, X-Forwarded-For or X-Real-IP e.g.
+		TrustedProxies         []string `json:"trusted_proxies" mapstructure:"trusted_proxies"`                   // IPs or CIDRs of proxies, required by proxy_header
 	} `json:"http" mapstructure:"http"`
 	Database struct {
 		DSN string `json:"dsn" mapstructure:"dsn"`
 	} `json:"database" mapstructure:"database"`
 	Nats struct {
-		URL string `json:"url" mapstructure:"url"`
+		URL              string `json:"url" mapstructure:"url"`
+		Stream           string `json:"stream" mapstructure:"stream"`
+		StreamMaxAge     int64  `json:"stream_max_age" mapstructure:"stream_max_age"`       // In hour
+		ConsumerInactive int64  `json:"consumer_inactive" mapstructure:"consumer_inactive"` // In hour
+		MaxDevices       int    `json:"max_devices" mapstructure:"max_devices"`             // Durable consumers of waiting devices per subscriber
 	} `json:"nats" mapstructure:"nats"`
 	Auth struct {
-		JWTAccessSecret  string `json:"jwt_access_secret" mapstructure:"jwt_access_secret"`
-		JWTRefreshSecret string `json:"jwt_refresh_secret" mapstructure:"jwt_refresh_secret"`
-		JWTAccessExpiry  int64  `json:"jwt_access_expiry" mapstructure:"jwt_access_expiry"`   // In minute
-		JWTRefreshExpiry int64  `json:"jwt_refresh_expiry" mapstructure:"jwt_refresh_expiry"` // In minute
+		JWTAccessSecret  string            `json:"jwt_access_secret" mapstructure:"jwt_access_secret"`
+		JWTRefreshSecret string            `json:"jwt_refresh_secret" mapstructure:"jwt_refresh_secret"`
+		JWTAccessExpiry  int64             `json:"jwt_access_expiry" mapstructure:"jwt_access_expiry"`   // In minute
+		JWTRefreshExpiry int64             `json:"jwt_refresh_expiry" mapstructure:"jwt_refresh_expiry"` // In minute
+		JWTSigningKeys   map[string]string `json:"jwt_signing_keys" mapstructure:"jwt_signing_keys"`     // RSA or Ed25519 private key (PEM or path) by key ID, HS256 by access secret if empty
+		JWTSigningKeyID  string            `json:"jwt_signing_key_id" mapstructure:"jwt_signing_key_id"` // Active key ID, for new access tokens
+		SessionRequired  bool              `json:"session_required" mapstructure:"session_required"`     // Access tokens without session rejected
 	} `json:"auth" mapstructure:"auth"`
 	Security struct {
-		CredentialLifetime int64  `json:"credential_lifetime" mapstructure:"credential_lifetime"` // In minute
-		AESKey             string `json:"aes_key" mapstructure:"aes_key"`
+		CredentialLifetime         int64             `json:"credential_lifetime" mapstructure:"credential_lifetime"` // In minute
+		AESKey                     string            `json:"aes_key" mapstructure:"aes_key"`
+		PaymentPasswordMaxFailures int64             `json:"payment_password_max_failures" mapstructure:"payment_password_max_failures"`
+		PaymentPasswordLockTime    int64             `json:"payment_password_lock_time" mapstructure:"payment_password_lock_time"` // In minute
+		VaultKeys                  map[string]string `json:"vault_keys" mapstructure:"vault_keys"`                                 // AES key (16, 24 or 32 bytes) by key ID
+		VaultKeyID                 string            `json:"vault_key_id" mapstructure:"vault_key_id"`                             // Active key ID, for new cards
+		VaultHMACKey               string            `json:"vault_hmac_key" mapstructure:"vault_hmac_key"`                         // Card number fingerprint
+		CredentialKeys             map[string]string `json:"credential_keys" mapstructure:"credential_keys"`                       // AES key (16, 24 or 32 bytes) by key ID
+		CredentialKeyID            string            `json:"credential_key_id" mapstructure:"credential_key_id"`                   // Active key ID, for new credentials
+		CredentialAcceptV1         bool              `json:"credential_accept_v1" mapstructure:"credential_accept_v1"`             // Legacy AES-CBC credentials, enable only while clients of v1 still roll out
+		TOTPStep                   int64             `json:"totp_step" mapstructure:"totp_step"`                                   // In second, time step of offline codes
+		TOTPSkew                   int64             `json:"totp_skew" mapstructure:"totp_skew"`                                   // Steps accepted before and after the current one, for clock drift of offline apps
+		TOTPMaxFailures            int64             `json:"totp_max_failures" mapstructure:"totp_max_failures"`                   // Wrong offline codes of one index before it is locked
+		TOTPLockTime               int64             `json:"totp_lock_time" mapstructure:"totp_lock_time"`                         // In minute
+		StaffInviteLifetime        int64             `json:"staff_invite_lifetime" mapstructure:"staff_invite_lifetime"`           // In hour, invitation codes of staff
 	} `json:"security" mapstructure:"security"`
+	Payment struct {
+		TransactionTTL  int64  `json:"transaction_ttl" mapstructure:"transaction_ttl"`   // In minute
+		SweepInterval   int64  `json:"sweep_interval" mapstructure:"sweep_interval"`     // In second
+		DefaultCurrency string `json:"default_currency" mapstructure:"default_currency"` // ISO 4217, of tenants accepting no configured currencies and cards added without one
+	} `json:"payment" mapstructure:"payment"`
+	Settlement struct {
+		FeeRate  int64            `json:"fee_rate" mapstructure:"fee_rate"`   // In basis point of payment amount
+		FeeFixed map[string]int64 `json:"fee_fixed" mapstructure:"fee_fixed"` // In minor unit of currency, per payment, by currency
+		Timezone string           `json:"timezone" mapstructure:"timezone"`   // Day boundary of batches, IANA name
+	} `json:"settlement" mapstructure:"settlement"`
+	Webhook struct {
+		Timeout          int64 `json:"timeout" mapstructure:"timeout"`                     // In second
+		MaxAttempts      int64 `json:"max_attempts" mapstructure:"max_attempts"`           // Before delivery marked failed
+		RetryBase        int64 `json:"retry_base" mapstructure:"retry_base"`               // In second, doubled every attempt
+		DeliveryInterval int64 `json:"delivery_interval" mapstructure:"delivery_interval"` // In second
+		AllowPrivate     bool  `json:"allow_private" mapstructure:"allow_private"`         // Endpoints on loopback, link-local or private addresses, for local testing only
+	} `json:"webhook" mapstructure:"webhook"`
 	Firebase struct {
 		Credentials struct {
 			Type                    string `json:"id" mapstructure:"id"`
@@ -71,14 +113,50 @@ var defaultConfigs = map[string]interface{}{
 	"http.listen_addr":                                 ":9900",
 	"http.prefork":                                     false,
 	"http.long_polling_timeout":                        30,
+	"http.idempotency_key_lifetime":                    24,
+	"http.idempotency_lease":                           60,
+	"http.event_heartbeat":                             15,
+	"http.proxy_header":                                "",
+	"http.trusted_proxies":                             []string{},
 	"database.dsn":                                     "postgres://icepay@localhost:5432/icepay?sslmode=disable",
 	"nats.url":                                         nats.DefaultURL,
+	"nats.stream":                                      "PAYMENT",
+	"nats.stream_max_age":                              72,
+	"nats.consumer_inactive":                           24,
+	"nats.max_devices":                                 16,
 	"auth.jwt_access_secret":                           "access_secret",
 	"auth.jwt_refresh_secret":                          "refresh_secret",
 	"auth.jwt_access_expiry":                           10,
 	"auth.jwt_refresh_expiry":                          43200,
+	"auth.jwt_signing_keys":                            map[string]string{},
+	"auth.jwt_signing_key_id":                          "",
+	"auth.session_required":                            true,
 	"security.aes_key":                                 "icepay@@20130920",
 	"security.credential_lifetime":                     5,
+	"security.payment_password_max_failures":           5,
+	"security.payment_password_lock_time":              30,
+	"security.vault_keys":                              map[string]string{"dev": "icepay-vault-dev-key-change-me!!"},
+	"security.vault_key_id":                            "dev",
+	"security.vault_hmac_key":                          "icepay-vault-fingerprint",
+	"security.credential_keys":                         map[string]string{"dev": "icepay-cred-dev-key-change-me!!!"},
+	"security.credential_key_id":                       "dev",
+	"security.credential_accept_v1":                    false,
+	"security.totp_step":                               30,
+	"security.totp_skew":                               1,
+	"security.totp_max_failures":                       5,
+	"security.totp_lock_time":                          30,
+	"security.staff_invite_lifetime":                   72,
+	"payment.transaction_ttl":                          10,
+	"payment.sweep_interval":                           30,
+	"payment.default_currency":                         "CNY",
+	"settlement.fee_rate":                              0,
+	"settlement.fee_fixed":                             map[string]int64{},
+	"settlement.timezone":                              "UTC",
+	"webhook.timeout":                                  10,
+	"webhook.max_attempts":                             8,
+	"webhook.retry_base":                               30,
+	"webhook.delivery_interval":                        5,
+	"webhook.allow_private":                            false,
 	"firebase.credentials.type":                        "service_account",
 	"firebase.credentials.auth_url":                    "https://accounts.google.com/o/oauth2/auth",
 	"firebase.credentials.token_url":                   "https://oauth2.googleapis.com/token",
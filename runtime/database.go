@@ -14,6 +14,7 @@
 package runtime
 
 import (
+	"context"
 	"database/sql"
 
 	"github.com/uptrace/bun"
@@ -34,6 +35,34 @@ func InitDB() error {
 	return err
 }
 
+type txKey struct{}
+
+// IDB returns database transaction bound to ctx by RunInTx, or DB if there is no one
+func IDB(ctx context.Context) bun.IDB {
+	if tx, ok := ctx.Value(txKey{}).(bun.Tx); ok {
+		return tx
+	}
+
+	return DB
+}
+
+// WithoutTx returns ctx not bound to the database transaction of RunInTx, writes by it kept even if that rolls back
+func WithoutTx(ctx context.Context) context.Context {
+	return context.WithValue(ctx, txKey{}, nil)
+}
+
+// RunInTx runs fn in a database transaction, models called with the given ctx share it.
+// Nested calls join the outer transaction
+func RunInTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txKey{}).(bun.Tx); ok {
+		return fn(ctx)
+	}
+
+	return DB.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		return fn(context.WithValue(ctx, txKey{}, tx))
+	})
+}
+
 /*
  * Local variables:
  * tab-width: 4
@@ -13,9 +13,18 @@
 
 package runtime
 
-import "github.com/nats-io/nats.go"
+import (
+	"errors"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// PaymentSubjects : subjects of payment events, captured by stream
+const PaymentSubjects = "pay.>"
 
 var Nats *nats.Conn
+var JetStream nats.JetStreamContext
 
 func InitNats() error {
 	nc, err := nats.Connect(Config.Nats.URL)
@@ -25,9 +34,43 @@ func InitNats() error {
 
 	Nats = nc
 
+	err = InitJetStream()
+	if err != nil {
+		Logger.Fatalf("jetstream initialize failed : %s", err)
+	}
+
 	return err
 }
 
+// InitJetStream : creates stream of payment events, or updates it if configuration changed
+func InitJetStream() error {
+	js, err := Nats.JetStream()
+	if err != nil {
+		return err
+	}
+
+	cfg := &nats.StreamConfig{
+		Name:     Config.Nats.Stream,
+		Subjects: []string{PaymentSubjects},
+		Storage:  nats.FileStorage,
+		MaxAge:   time.Duration(Config.Nats.StreamMaxAge) * time.Hour,
+	}
+	_, err = js.StreamInfo(cfg.Name)
+	if errors.Is(err, nats.ErrStreamNotFound) {
+		_, err = js.AddStream(cfg)
+	} else if err == nil {
+		_, err = js.UpdateStream(cfg)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	JetStream = js
+
+	return nil
+}
+
 /*
  * Local variables:
  * tab-width: 4
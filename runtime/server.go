@@ -14,7 +14,10 @@
 package runtime
 
 import (
+	"context"
+	"net"
 	"os"
+	"strings"
 
 	"github.com/gofiber/contrib/fiberzap"
 	"github.com/gofiber/fiber/v2"
@@ -26,13 +29,42 @@ import (
 
 var Server *fiber.App
 
+// Proxies trusted to set Config.HTTP.ProxyHeader
+var trustedProxies []*net.IPNet
+
 func InitServer() error {
+	if Config.HTTP.ProxyHeader != "" && len(Config.HTTP.TrustedProxies) == 0 {
+		// Header of anyone would be taken
+		Logger.Fatalf("http.trusted_proxies required by http.proxy_header [%s]", Config.HTTP.ProxyHeader)
+	}
+
+	for _, proxy := range Config.HTTP.TrustedProxies {
+		if !strings.Contains(proxy, "/") {
+			if strings.Contains(proxy, ":") {
+				proxy += "/128"
+			} else {
+				proxy += "/32"
+			}
+		}
+
+		_, ipNet, err := net.ParseCIDR(proxy)
+		if err != nil {
+			Logger.Fatalf("trusted proxy [%s] invalid : %s", proxy, err)
+		}
+
+		trustedProxies = append(trustedProxies, ipNet)
+	}
+
 	app := fiber.New(fiber.Config{
-		ServerHeader:          AppName,
-		DisableKeepalive:      false,
-		AppName:               AppName,
-		Prefork:               Config.HTTP.Prefork,
-		DisableStartupMessage: true,
+		ServerHeader:            AppName,
+		DisableKeepalive:        false,
+		AppName:                 AppName,
+		Prefork:                 Config.HTTP.Prefork,
+		DisableStartupMessage:   true,
+		ProxyHeader:             Config.HTTP.ProxyHeader,
+		EnableTrustedProxyCheck: len(Config.HTTP.TrustedProxies) > 0,
+		TrustedProxies:          Config.HTTP.TrustedProxies,
+		EnableIPValidation:      true,
 	})
 	app.Use(fiberzap.New(fiberzap.Config{
 		Logger: LoggerRaw,
@@ -52,11 +84,55 @@ func Serve() error {
 		return errors.New("Server not initialized")
 	}
 
+	if !fiber.IsChild() {
+		// Only once per instance in prefork mode
+		startWorkers(context.Background())
+	}
+
 	Logger.Infof("starting HTTP server on [%s]", Config.HTTP.ListenAddr)
 
 	return Server.Listen(Config.HTTP.ListenAddr)
 }
 
+// ClientIP : address of client, the rightmost hop of proxy header not a trusted proxy.
+// Hops left of it are given by the client itself, never taken
+func ClientIP(c *fiber.Ctx) string {
+	client := c.Context().RemoteIP()
+	if Config.HTTP.ProxyHeader == "" || !proxyTrusted(client) {
+		return client.String()
+	}
+
+	var hops []string
+	for _, v := range c.Request().Header.PeekAll(Config.HTTP.ProxyHeader) {
+		hops = append(hops, strings.Split(string(v), ",")...)
+	}
+
+	for i := len(hops) - 1; i >= 0; i-- {
+		ip := net.ParseIP(strings.TrimSpace(hops[i]))
+		if ip == nil {
+			// Malformed, the proxy appending after it taken as client
+			break
+		}
+
+		client = ip
+		if !proxyTrusted(ip) {
+			break
+		}
+	}
+
+	return client.String()
+}
+
+func proxyTrusted(ip net.IP) bool {
+	for _, ipNet := range trustedProxies {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
 func Exit() {
 	// TODO: Pure runtime
 	os.Exit(-1)
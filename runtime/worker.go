@@ -0,0 +1,69 @@
+/*
+ * Copyright (C) HereweTech, Inc - All Rights Reserved
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+/**
+ * @file worker.go
+ * @package runtime
+ * @author Dr.NP <np@herewe.tech>
+ * @since 10/18/2026
+ */
+
+package runtime
+
+import (
+	"context"
+	"time"
+)
+
+// Worker : background job runs periodically along with HTTP server
+type Worker struct {
+	Name     string
+	Interval time.Duration
+	Run      func(ctx context.Context) error
+}
+
+var workers []*Worker
+
+// RegisterWorker adds worker, started by Serve
+func RegisterWorker(w *Worker) {
+	if w.Interval <= 0 {
+		Logger.Infof("worker [%s] disabled", w.Name)
+
+		return
+	}
+
+	workers = append(workers, w)
+}
+
+func startWorkers(ctx context.Context) {
+	for _, w := range workers {
+		go func(w *Worker) {
+			Logger.Infof("starting worker [%s] every %s", w.Name, w.Interval)
+			ticker := time.NewTicker(w.Interval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+					err := w.Run(ctx)
+					if err != nil {
+						Logger.Errorf("worker [%s] failed : %s", w.Name, err)
+					}
+				}
+			}
+		}(w)
+	}
+}
+
+/*
+ * Local variables:
+ * tab-width: 4
+ * c-basic-offset: 4
+ * End:
+ * vim600: sw=4 ts=4 fdm=marker
+ * vim<600: sw=4 ts=4
+ */
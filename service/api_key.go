@@ -0,0 +1,230 @@
+/*
+ * Copyright (C) HereweTech, Inc - All Rights Reserved
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+/**
+ * @file api_key.go
+ * @package service
+ * @author Dr.NP <np@herewe.tech>
+ * @since 10/18/2026
+ */
+
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"icepay-svc/model"
+	"icepay-svc/utils"
+	"net"
+	"strings"
+	"time"
+)
+
+const (
+	// API keys are prefixed, telling them apart from other secrets when leaked
+	apiKeyPrefix = "tak_"
+	apiKeyLength = 40
+
+	// Time of use recorded at most once in interval, not a write on every call
+	apiKeyTouchInterval = time.Minute
+)
+
+var (
+	ErrAPIKeyInvalid   = errors.New("Invalid or revoked API key")
+	ErrAPIKeyIPDenied  = errors.New("IP not allowed for API key")
+	ErrAPIKeyInvalidIP = errors.New("Invalid IP or CIDR")
+)
+
+type APIKey struct{}
+
+func NewAPIKey() *APIKey {
+	s := new(APIKey)
+
+	return s
+}
+
+/* {{{ [Methods] */
+
+// Create : creates API key of tenant, the secret returned only once
+func (s *APIKey) Create(ctx context.Context, input *model.APIKey) (*model.APIKey, string, error) {
+	allowed, err := allowedIPs(input.AllowedIPs)
+	if err != nil {
+		return nil, "", err
+	}
+
+	key := apiKeyPrefix + utils.SecureRandomString(apiKeyLength)
+	apiKey := &model.APIKey{
+		Tenant:     input.Tenant,
+		Label:      strings.TrimSpace(input.Label),
+		KeyHash:    utils.HashSecret(key),
+		KeyPrefix:  key[:len(apiKeyPrefix)+4],
+		AllowedIPs: allowed,
+	}
+
+	err = apiKey.Create(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return apiKey, key, nil
+}
+
+// Update : updates label, allowed IPs replaced if not nil
+func (s *APIKey) Update(ctx context.Context, input *model.APIKey) (*model.APIKey, error) {
+	apiKey := &model.APIKey{
+		ID:     input.ID,
+		Tenant: input.Tenant,
+		Label:  strings.TrimSpace(input.Label),
+	}
+
+	if input.AllowedIPs != nil {
+		allowed, err := allowedIPs(input.AllowedIPs)
+		if err != nil {
+			return nil, err
+		}
+
+		apiKey.AllowedIPs = allowed
+	}
+
+	err := apiKey.Update(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.Get(ctx, input)
+}
+
+// Get
+func (s *APIKey) Get(ctx context.Context, input *model.APIKey) (*model.APIKey, error) {
+	apiKey := &model.APIKey{
+		ID:     input.ID,
+		Tenant: input.Tenant,
+	}
+
+	err := apiKey.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return apiKey, nil
+}
+
+// List
+func (s *APIKey) List(ctx context.Context, input *model.APIKey) ([]*model.APIKey, error) {
+	apiKey := &model.APIKey{
+		Tenant: input.Tenant,
+	}
+
+	return apiKey.List(ctx)
+}
+
+// Revoke
+func (s *APIKey) Revoke(ctx context.Context, input *model.APIKey) (*model.APIKey, error) {
+	apiKey := &model.APIKey{
+		ID:     input.ID,
+		Tenant: input.Tenant,
+	}
+
+	err := apiKey.Revoke(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.Get(ctx, input)
+}
+
+// Authenticate : API key not revoked, called from an allowed IP. The time of use recorded
+func (s *APIKey) Authenticate(ctx context.Context, key, ip string) (*model.APIKey, error) {
+	if !strings.HasPrefix(key, apiKeyPrefix) {
+		return nil, ErrAPIKeyInvalid
+	}
+
+	apiKey := &model.APIKey{
+		KeyHash: utils.HashSecret(key),
+	}
+
+	err := apiKey.Get(ctx)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && !apiKey.RevokedAt.IsZero()) {
+		return nil, ErrAPIKeyInvalid
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !ipAllowed(apiKey.AllowedIPs, ip) {
+		return nil, ErrAPIKeyIPDenied
+	}
+
+	err = apiKey.Touch(ctx, apiKeyTouchInterval)
+	if err != nil {
+		return nil, err
+	}
+
+	return apiKey, nil
+}
+
+/* }}} */
+
+// allowedIPs : IPs or CIDRs => CIDRs, single IPs as host networks
+func allowedIPs(ips []string) ([]string, error) {
+	allowed := make([]string, 0, len(ips))
+	for _, v := range ips {
+		v = strings.TrimSpace(v)
+		if !strings.Contains(v, "/") {
+			ip := net.ParseIP(v)
+			if ip == nil {
+				return nil, ErrAPIKeyInvalidIP
+			}
+
+			if ip.To4() != nil {
+				v += "/32"
+			} else {
+				v += "/128"
+			}
+		}
+
+		_, network, err := net.ParseCIDR(v)
+		if err != nil {
+			return nil, ErrAPIKeyInvalidIP
+		}
+
+		allowed = append(allowed, network.String())
+	}
+
+	return allowed, nil
+}
+
+// ipAllowed tells whether ip in any of allowed CIDRs, any IP allowed if none
+func ipAllowed(allowed []string, ip string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return false
+	}
+
+	for _, v := range allowed {
+		_, network, err := net.ParseCIDR(v)
+		if err == nil && network.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+/*
+ * Local variables:
+ * tab-width: 4
+ * c-basic-offset: 4
+ * End:
+ * vim600: sw=4 ts=4 fdm=marker
+ * vim<600: sw=4 ts=4
+ */
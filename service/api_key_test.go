@@ -0,0 +1,71 @@
+/*
+ * Copyright (C) HereweTech, Inc - All Rights Reserved
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+/**
+ * @file api_key_test.go
+ * @package service
+ * @author Dr.NP <np@herewe.tech>
+ * @since 10/18/2026
+ */
+
+package service
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestAPIKeyAllowedIPs(t *testing.T) {
+	allowed, err := allowedIPs([]string{"203.0.113.7", " 10.1.2.3/16 ", "2001:db8::1"})
+	if err != nil {
+		t.Fatalf("parse allowed IPs failed : %s", err)
+	}
+
+	want := []string{"203.0.113.7/32", "10.1.0.0/16", "2001:db8::1/128"}
+	if !reflect.DeepEqual(allowed, want) {
+		t.Errorf("allowed IPs = %v, want %v", allowed, want)
+	}
+
+	for _, invalid := range []string{"203.0.113", "10.0.0.0/33", "example.com", ""} {
+		_, err = allowedIPs([]string{invalid})
+		if !errors.Is(err, ErrAPIKeyInvalidIP) {
+			t.Errorf("allowed IP %q = %v, want %v", invalid, err, ErrAPIKeyInvalidIP)
+		}
+	}
+
+	cases := []struct {
+		ip   string
+		want bool
+	}{
+		{"203.0.113.7", true},
+		{"203.0.113.8", false},
+		{"10.1.255.254", true},
+		{"10.2.0.1", false},
+		{"2001:db8::1", true},
+		{"2001:db8::2", false},
+		{"", false},
+	}
+	for _, c := range cases {
+		if got := ipAllowed(allowed, c.ip); got != c.want {
+			t.Errorf("ip %q allowed = %v, want %v", c.ip, got, c.want)
+		}
+	}
+
+	// Any IP allowed without allow-list
+	if !ipAllowed(nil, "198.51.100.1") {
+		t.Error("IP rejected by empty allow-list")
+	}
+}
+
+/*
+ * Local variables:
+ * tab-width: 4
+ * c-basic-offset: 4
+ * End:
+ * vim600: sw=4 ts=4 fdm=marker
+ * vim<600: sw=4 ts=4
+ */
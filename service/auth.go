@@ -15,20 +15,49 @@ package service
 
 import (
 	"context"
+	"database/sql"
+	"errors"
 	"fmt"
+	"icepay-svc/model"
 	"icepay-svc/runtime"
+	"icepay-svc/utils"
+	"strings"
 	"time"
 
 	firebase "firebase.google.com/go/v4"
 	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/uuid"
 	"google.golang.org/api/option"
 )
 
+const (
+	// Type of refresh token is the subject type with the suffix, signed by the refresh key
+	TokenTypeRefreshSuffix = "::refresh"
+
+	SessionRevokedLogout   = "logout"
+	SessionRevokedReuse    = "reuse"
+	SessionRevokedPassword = "password"
+	SessionRevokedDisabled = "disabled"
+)
+
+var (
+	ErrPaymentPasswordLocked = errors.New("Payment password locked")
+	ErrTokenType             = errors.New("Unexpected token type")
+	ErrSessionInvalid        = errors.New("Session revoked or expired")
+	ErrSessionReused         = errors.New("Rotated refresh token reused")
+	ErrSigningMethod         = errors.New("Unexpected signing method")
+)
+
 type Sign struct {
 	Issuer    string
 	Sub       string
 	Name      string
 	Type      string
+	Role      string // Scopes of role granted to access token
+	Staff     string // Staff of tenant signed in
+	Terminal  string // Terminal of tenant signed in
+	ID        string // jti
+	Session   string // sid
 	ExpiresIn time.Duration
 }
 
@@ -37,8 +66,15 @@ type JWT struct {
 	Expiry int64
 }
 
+// Tokens : access and refresh tokens of one session
+type Tokens struct {
+	Access  *JWT
+	Refresh *JWT
+}
+
 type Auth struct {
 	firebaseApp *firebase.App
+	signingKeys *utils.SigningKeyring
 }
 
 func NewAuth() *Auth {
@@ -50,13 +86,20 @@ func NewAuth() *Auth {
 	}
 
 	s.firebaseApp = fa
+	if len(runtime.Config.Auth.JWTSigningKeys) > 0 {
+		s.signingKeys, err = utils.NewSigningKeyring(runtime.Config.Auth.JWTSigningKeys, runtime.Config.Auth.JWTSigningKeyID)
+		if err != nil {
+			runtime.Logger.Fatalf("load JWT signing keys failed : %s", err)
+		}
+	}
 
 	return s
 }
 
 /* {{{ [Methods] */
 
-// Sign JWT token
+// Sign JWT token, refresh tokens signed by the refresh key.
+// Access tokens signed by the active asymmetric key if configured, verifiable by JWKS
 func (s *Auth) JWTSign(sign *Sign) (*JWT, error) {
 	now := time.Now()
 	exp := now.Add(sign.ExpiresIn).Unix()
@@ -72,8 +115,39 @@ func (s *Auth) JWTSign(sign *Sign) (*JWT, error) {
 		"exp":    exp,
 		"type":   sign.Type,
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	ts, err := token.SignedString([]byte(runtime.Config.Auth.JWTAccessSecret))
+	if sign.ID != "" {
+		claims["jti"] = sign.ID
+	}
+
+	if sign.Session != "" {
+		claims["sid"] = sign.Session
+	}
+
+	if sign.Staff != "" {
+		claims["staff"] = sign.Staff
+	}
+
+	if sign.Terminal != "" {
+		claims["terminal"] = sign.Terminal
+	}
+
+	if sign.Role != "" {
+		claims["role"] = sign.Role
+		claims["scope"] = strings.Join(RoleScopes(sign.Role), " ")
+	}
+
+	var (
+		ts  string
+		err error
+	)
+	if strings.HasSuffix(sign.Type, TokenTypeRefreshSuffix) {
+		ts, err = jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(runtime.Config.Auth.JWTRefreshSecret))
+	} else if s.signingKeys != nil {
+		ts, err = s.signingKeys.Sign(claims)
+	} else {
+		ts, err = jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(runtime.Config.Auth.JWTAccessSecret))
+	}
+
 	if err != nil {
 		runtime.Logger.Errorf("sign JWT token failed : %s", err)
 
@@ -86,8 +160,8 @@ func (s *Auth) JWTSign(sign *Sign) (*JWT, error) {
 	}, nil
 }
 
-// Valid JWT token
-func (s *Auth) JWTValid(ts string) (jwt.MapClaims, error) {
+// Valid refresh token of the given type
+func (s *Auth) JWTValid(ts, tokenType string) (jwt.MapClaims, error) {
 	token, err := jwt.Parse(ts, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			// Error
@@ -101,11 +175,156 @@ func (s *Auth) JWTValid(ts string) (jwt.MapClaims, error) {
 		return nil, err
 	}
 
-	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-		return claims, nil
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid {
+		return nil, fmt.Errorf("Invalid claims format")
+	}
+
+	if err := claimsType(claims, tokenType); err != nil {
+		return nil, err
+	}
+
+	return claims, nil
+}
+
+// KeyFunc : verification key of access token, by kid in its header
+func (s *Auth) KeyFunc(token *jwt.Token) (interface{}, error) {
+	if s.signingKeys != nil {
+		return s.signingKeys.Verifier(token)
+	}
+
+	if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+		return nil, ErrSigningMethod
+	}
+
+	return []byte(runtime.Config.Auth.JWTAccessSecret), nil
+}
+
+// JWKS : public keys verifying access tokens, empty if signed by shared secret
+func (s *Auth) JWKS() *utils.JWKS {
+	if s.signingKeys == nil {
+		return &utils.JWKS{
+			Keys: []*utils.JWK{},
+		}
+	}
+
+	return s.signingKeys.JWKS()
+}
+
+// Login : opens session of subject acting as role, signs its first refresh token along with the access token.
+// Staff and terminals of tenant sign in as the tenant, recorded by the session
+func (s *Auth) Login(ctx context.Context, input *model.Session, name string) (*Tokens, error) {
+	session := &model.Session{
+		Subject:     input.Subject,
+		SubjectType: input.SubjectType,
+		Role:        input.Role,
+		Staff:       input.Staff,
+		Terminal:    input.Terminal,
+		TokenID:     uuid.NewString(),
+		ExpiresAt:   time.Now().Add(time.Duration(runtime.Config.Auth.JWTRefreshExpiry) * time.Minute),
+	}
+
+	err := session.Create(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.sessionTokens(session, name)
+}
+
+// Refresh : rotates refresh token given by claims, the old one not usable anymore.
+// Presenting a rotated one revokes the whole session, as the token family may be stolen
+func (s *Auth) Refresh(ctx context.Context, claims jwt.MapClaims) (*Tokens, error) {
+	sub, _ := claims["sub"].(string)
+	name, _ := claims["name"].(string)
+	sid, _ := claims["sid"].(string)
+	jti, _ := claims["jti"].(string)
+	if sid == "" || jti == "" {
+		return nil, ErrSessionInvalid
 	}
 
-	return nil, fmt.Errorf("Invalid claims format")
+	var (
+		tokens *Tokens
+		reused bool
+	)
+	session := &model.Session{
+		ID: sid,
+	}
+	err := runtime.RunInTx(ctx, func(ctx context.Context) error {
+		err := session.Lock(ctx)
+		if err != nil {
+			return err
+		}
+
+		err = rotateSession(session, sub, jti, time.Now())
+		if errors.Is(err, ErrSessionReused) {
+			// Revocation committed
+			reused = true
+
+			return session.Revoke(ctx)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		err = session.Rotate(ctx)
+		if err != nil {
+			return err
+		}
+
+		tokens, err = s.sessionTokens(session, name)
+
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if reused {
+		runtime.Logger.Warnf("rotated refresh token [%s] of session [%s] reused, session revoked", jti, sid)
+
+		return nil, ErrSessionReused
+	}
+
+	return tokens, nil
+}
+
+// Logout : revokes session of refresh token given by claims
+func (s *Auth) Logout(ctx context.Context, claims jwt.MapClaims) error {
+	sub, _ := claims["sub"].(string)
+	sid, _ := claims["sid"].(string)
+	if sid == "" {
+		return ErrSessionInvalid
+	}
+
+	return runtime.RunInTx(ctx, func(ctx context.Context) error {
+		session := &model.Session{
+			ID: sid,
+		}
+
+		err := session.Lock(ctx)
+		if err != nil {
+			return err
+		}
+
+		err = logoutSession(session, sub)
+		if err != nil {
+			return err
+		}
+
+		return session.Revoke(ctx)
+	})
+}
+
+// Purge : removes expired sessions
+func (s *Auth) Purge(ctx context.Context) error {
+	n, err := new(model.Session).Purge(ctx, time.Now())
+	if n > 0 {
+		runtime.Logger.Infof("%d expired sessions purged", n)
+	}
+
+	return err
 }
 
 // Authenticate with firebase admin, idToken => claims
@@ -128,10 +347,174 @@ func (s *Auth) CheckPassword(target, targetType, password string) (bool, error)
 	return true, nil
 }
 
-func (s *Auth) CheckPaymentPassword(client, password string) (bool, error) {
+// Check payment password of client, too many consecutive failures lock the confirmation for a while
+func (s *Auth) CheckPaymentPassword(ctx context.Context, client, password string) (bool, error) {
+	if client == "" {
+		return false, nil
+	}
+
+	clt := &model.Client{
+		ID: client,
+	}
+	err := clt.Get(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	if PaymentLocked(clt) {
+		return false, ErrPaymentPasswordLocked
+	}
+
+	check := utils.EncryptPaymentPassword(password, paymentSalt(clt), clt.Email)
+	if check != clt.PaymentPassword {
+		limit := runtime.Config.Security.PaymentPasswordMaxFailures
+		if limit > 0 {
+			lockUntil := time.Now().Add(time.Duration(runtime.Config.Security.PaymentPasswordLockTime) * time.Minute)
+			err = clt.FailPaymentPassword(ctx, int(limit), lockUntil)
+			if err != nil {
+				return false, err
+			}
+
+			if PaymentLocked(clt) {
+				runtime.Logger.Warnf("payment of client [%s] locked until %s", clt.ID, clt.PaymentLockedUntil)
+
+				return false, ErrPaymentPasswordLocked
+			}
+		}
+
+		return false, nil
+	}
+
+	if clt.PaymentFailures > 0 {
+		err = clt.ResetPaymentFailures(ctx)
+		if err != nil {
+			return false, err
+		}
+	}
+
 	return true, nil
 }
 
+// sessionTokens signs access token and the current refresh token of session
+func (s *Auth) sessionTokens(session *model.Session, name string) (*Tokens, error) {
+	access, err := s.JWTSign(&Sign{
+		Sub:       session.Subject,
+		Name:      name,
+		Type:      session.SubjectType,
+		Role:      session.Role,
+		Staff:     session.Staff,
+		Terminal:  session.Terminal,
+		Session:   session.ID,
+		ExpiresIn: time.Duration(runtime.Config.Auth.JWTAccessExpiry) * time.Minute,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	refresh, err := s.JWTSign(&Sign{
+		Sub:       session.Subject,
+		Name:      name,
+		Type:      session.SubjectType + TokenTypeRefreshSuffix,
+		ID:        session.TokenID,
+		Staff:     session.Staff,
+		Terminal:  session.Terminal,
+		Session:   session.ID,
+		ExpiresIn: time.Until(session.ExpiresAt),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &Tokens{
+		Access:  access,
+		Refresh: refresh,
+	}, nil
+}
+
+// rotateSession : moves live session of subject to a new refresh token if jti is the current one.
+// Otherwise the rotated one is reused, session marked to be revoked and ErrSessionReused returned
+func rotateSession(session *model.Session, sub, jti string, now time.Time) error {
+	if session.Subject != sub || !session.RevokedAt.IsZero() || session.ExpiresAt.Before(now) {
+		return ErrSessionInvalid
+	}
+
+	if session.TokenID != jti {
+		session.RevokedReason = SessionRevokedReuse
+
+		return ErrSessionReused
+	}
+
+	session.TokenID = uuid.NewString()
+	session.ExpiresAt = now.Add(time.Duration(runtime.Config.Auth.JWTRefreshExpiry) * time.Minute)
+
+	return nil
+}
+
+// logoutSession : marks session of subject to be revoked by logout
+func logoutSession(session *model.Session, sub string) error {
+	if session.Subject != sub {
+		return ErrSessionInvalid
+	}
+
+	session.RevokedReason = SessionRevokedLogout
+
+	return nil
+}
+
+// RevokeSessions : revokes all sessions of subject signed in by itself, for reason
+func RevokeSessions(ctx context.Context, subject, subjectType, reason string) error {
+	session := &model.Session{
+		Subject:       subject,
+		SubjectType:   subjectType,
+		RevokedReason: reason,
+	}
+
+	return session.RevokeAll(ctx)
+}
+
+// AccessValid : checks type of access token claims, refresh tokens are never accepted as access ones
+func AccessValid(claims jwt.MapClaims) error {
+	return claimsType(claims, "client", "tenant")
+}
+
+func claimsType(claims jwt.MapClaims, tokenTypes ...string) error {
+	t, _ := claims["type"].(string)
+	for _, tokenType := range tokenTypes {
+		if t == tokenType {
+			return nil
+		}
+	}
+
+	return ErrTokenType
+}
+
+// SessionLive : tells whether session of access token is neither revoked nor expired.
+// Access tokens signed before sessions carry none, live until they expire unless auth.session_required
+func SessionLive(ctx context.Context, sid string) (bool, error) {
+	if sid == "" {
+		return !runtime.Config.Auth.SessionRequired, nil
+	}
+
+	session := &model.Session{
+		ID: sid,
+	}
+	err := session.Get(ctx)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	return session.RevokedAt.IsZero() && session.ExpiresAt.After(time.Now()), nil
+}
+
+// PaymentLocked tells whether payment confirmation of client is locked
+func PaymentLocked(clt *model.Client) bool {
+	return clt.PaymentLockedUntil.After(time.Now())
+}
+
 /* }}} */
 
 /*
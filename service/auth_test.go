@@ -0,0 +1,303 @@
+/*
+ * Copyright (C) HereweTech, Inc - All Rights Reserved
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+/**
+ * @file auth_test.go
+ * @package service
+ * @author Dr.NP <np@herewe.tech>
+ * @since 10/18/2026
+ */
+
+package service
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"icepay-svc/model"
+	"icepay-svc/runtime"
+	"icepay-svc/utils"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+func TestRefreshTokenSigning(t *testing.T) {
+	runtime.Config.Auth.JWTAccessSecret = "access_secret"
+	runtime.Config.Auth.JWTRefreshSecret = "refresh_secret"
+	s := new(Auth)
+
+	refresh, err := s.JWTSign(&Sign{
+		Sub:       "c1",
+		Type:      "client" + TokenTypeRefreshSuffix,
+		ID:        "j1",
+		Session:   "s1",
+		ExpiresIn: time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("sign failed : %s", err)
+	}
+
+	claims, err := s.JWTValid(refresh.Token, "client"+TokenTypeRefreshSuffix)
+	if err != nil {
+		t.Fatalf("valid failed : %s", err)
+	}
+
+	if claims["jti"] != "j1" || claims["sid"] != "s1" || claims["sub"] != "c1" {
+		t.Errorf("unexpected claims %v", claims)
+	}
+
+	// Refresh token of tenant is not one of client
+	_, err = s.JWTValid(refresh.Token, "tenant"+TokenTypeRefreshSuffix)
+	if !errors.Is(err, ErrTokenType) {
+		t.Errorf("valid as tenant = %v, want %v", err, ErrTokenType)
+	}
+
+	// Access tokens signed by the other key
+	access, err := s.JWTSign(&Sign{
+		Sub:       "c1",
+		Type:      "client",
+		ExpiresIn: time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("sign failed : %s", err)
+	}
+
+	_, err = s.JWTValid(access.Token, "client")
+	if err == nil {
+		t.Error("access token accepted as refresh token")
+	}
+}
+
+func TestAccessTokenSigningKeys(t *testing.T) {
+	keys := make(map[string]string)
+	for _, kid := range []string{"k1", "k2"} {
+		_, priv, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		der, err := x509.MarshalPKCS8PrivateKey(priv)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		keys[kid] = string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+	}
+
+	signed := make(map[string]string)
+	for _, active := range []string{"k1", "k2"} {
+		keyring, err := utils.NewSigningKeyring(keys, active)
+		if err != nil {
+			t.Fatalf("load keys failed : %s", err)
+		}
+
+		s := &Auth{
+			signingKeys: keyring,
+		}
+		access, err := s.JWTSign(&Sign{
+			Sub:       "c1",
+			Type:      "client",
+			ExpiresIn: time.Minute,
+		})
+		if err != nil {
+			t.Fatalf("sign failed : %s", err)
+		}
+
+		signed[active] = access.Token
+	}
+
+	// Rotated to k2, tokens of k1 still valid
+	keyring, _ := utils.NewSigningKeyring(keys, "k2")
+	s := &Auth{
+		signingKeys: keyring,
+	}
+	for kid, ts := range signed {
+		token, err := jwt.Parse(ts, s.KeyFunc)
+		if err != nil {
+			t.Fatalf("parse token of [%s] failed : %s", kid, err)
+		}
+
+		if token.Header["kid"] != kid || token.Method != jwt.SigningMethodEdDSA {
+			t.Errorf("unexpected header %v", token.Header)
+		}
+	}
+
+	if n := len(s.JWKS().Keys); n != 2 {
+		t.Errorf("%d keys in JWKS, want 2", n)
+	}
+
+	// Shared secret not accepted for kid
+	forged, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"sub": "c1"}).SignedString([]byte("k1"))
+	_, err := jwt.Parse(forged, s.KeyFunc)
+	if err == nil {
+		t.Error("HS256 token accepted by asymmetric keys")
+	}
+}
+
+func TestRefreshRotation(t *testing.T) {
+	runtime.Config.Auth.JWTRefreshExpiry = 60
+	now := time.Now()
+	session := &model.Session{
+		ID:        "s1",
+		Subject:   "c1",
+		TokenID:   "j1",
+		ExpiresAt: now.Add(time.Minute),
+	}
+
+	err := rotateSession(session, "c1", "j1", now)
+	if err != nil {
+		t.Fatalf("rotate failed : %s", err)
+	}
+
+	if session.TokenID == "j1" || session.TokenID == "" {
+		t.Errorf("token id not rotated : %s", session.TokenID)
+	}
+
+	if !session.ExpiresAt.Equal(now.Add(time.Hour)) {
+		t.Errorf("session expires at %s, want %s", session.ExpiresAt, now.Add(time.Hour))
+	}
+
+	if session.RevokedReason != "" {
+		t.Errorf("rotated session to be revoked by [%s]", session.RevokedReason)
+	}
+
+	// The new one rotates again
+	err = rotateSession(session, "c1", session.TokenID, now)
+	if err != nil {
+		t.Errorf("rotate again failed : %s", err)
+	}
+}
+
+func TestRefreshReuseRevokes(t *testing.T) {
+	runtime.Config.Auth.JWTRefreshExpiry = 60
+	now := time.Now()
+	session := &model.Session{
+		ID:        "s1",
+		Subject:   "c1",
+		TokenID:   "j1",
+		ExpiresAt: now.Add(time.Minute),
+	}
+
+	err := rotateSession(session, "c1", "j1", now)
+	if err != nil {
+		t.Fatalf("rotate failed : %s", err)
+	}
+
+	// Rotated token presented
+	current := session.TokenID
+	err = rotateSession(session, "c1", "j1", now)
+	if !errors.Is(err, ErrSessionReused) {
+		t.Fatalf("reuse = %v, want %v", err, ErrSessionReused)
+	}
+
+	if session.RevokedReason != SessionRevokedReuse {
+		t.Errorf("session to be revoked by [%s], want [%s]", session.RevokedReason, SessionRevokedReuse)
+	}
+
+	if session.TokenID != current {
+		t.Errorf("reuse rotated token id to %s", session.TokenID)
+	}
+
+	// Revoked session refreshes no more, not even by the current token
+	session.RevokedAt = now
+	err = rotateSession(session, "c1", current, now)
+	if !errors.Is(err, ErrSessionInvalid) {
+		t.Errorf("refresh of revoked session = %v, want %v", err, ErrSessionInvalid)
+	}
+}
+
+func TestRefreshSessionInvalid(t *testing.T) {
+	now := time.Now()
+	cases := map[string]*model.Session{
+		"other subject": {ID: "s1", Subject: "c2", TokenID: "j1", ExpiresAt: now.Add(time.Minute)},
+		"expired":       {ID: "s1", Subject: "c1", TokenID: "j1", ExpiresAt: now.Add(-time.Second)},
+		"revoked":       {ID: "s1", Subject: "c1", TokenID: "j1", ExpiresAt: now.Add(time.Minute), RevokedAt: now},
+	}
+	for name, session := range cases {
+		err := rotateSession(session, "c1", "j1", now)
+		if !errors.Is(err, ErrSessionInvalid) {
+			t.Errorf("refresh of %s session = %v, want %v", name, err, ErrSessionInvalid)
+		}
+
+		if session.TokenID != "j1" || session.RevokedReason != "" {
+			t.Errorf("%s session changed : token id %s, revoked by [%s]", name, session.TokenID, session.RevokedReason)
+		}
+	}
+}
+
+func TestLogoutSession(t *testing.T) {
+	session := &model.Session{
+		ID:      "s1",
+		Subject: "c1",
+		TokenID: "j1",
+	}
+
+	err := logoutSession(session, "c2")
+	if !errors.Is(err, ErrSessionInvalid) {
+		t.Errorf("logout by other subject = %v, want %v", err, ErrSessionInvalid)
+	}
+
+	if session.RevokedReason != "" {
+		t.Errorf("session to be revoked by [%s] of other subject", session.RevokedReason)
+	}
+
+	err = logoutSession(session, "c1")
+	if err != nil {
+		t.Fatalf("logout failed : %s", err)
+	}
+
+	if session.RevokedReason != SessionRevokedLogout {
+		t.Errorf("session to be revoked by [%s], want [%s]", session.RevokedReason, SessionRevokedLogout)
+	}
+}
+
+func TestAccessValid(t *testing.T) {
+	for _, tokenType := range []string{"client", "tenant"} {
+		err := AccessValid(jwt.MapClaims{"type": tokenType})
+		if err != nil {
+			t.Errorf("access token of [%s] rejected : %s", tokenType, err)
+		}
+	}
+
+	for _, tokenType := range []string{"", "client" + TokenTypeRefreshSuffix, "tenant" + TokenTypeRefreshSuffix} {
+		err := AccessValid(jwt.MapClaims{"type": tokenType})
+		if !errors.Is(err, ErrTokenType) {
+			t.Errorf("access token of [%s] = %v, want %v", tokenType, err, ErrTokenType)
+		}
+	}
+}
+
+func TestSessionLiveWithoutSession(t *testing.T) {
+	defer func() {
+		runtime.Config.Auth.SessionRequired = false
+	}()
+
+	runtime.Config.Auth.SessionRequired = true
+	live, err := SessionLive(context.Background(), "")
+	if err != nil || live {
+		t.Errorf("session required, token without session live = %v (%v)", live, err)
+	}
+
+	runtime.Config.Auth.SessionRequired = false
+	live, err = SessionLive(context.Background(), "")
+	if err != nil || !live {
+		t.Errorf("session not required, token without session live = %v (%v)", live, err)
+	}
+}
+
+/*
+ * Local variables:
+ * tab-width: 4
+ * c-basic-offset: 4
+ * End:
+ * vim600: sw=4 ts=4 fdm=marker
+ * vim<600: sw=4 ts=4
+ */
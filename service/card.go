@@ -15,40 +15,80 @@ package service
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"icepay-svc/model"
 	"icepay-svc/utils"
+	"strconv"
 	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrCardInvalidNumber     = errors.New("Invalid card number")
+	ErrCardExists            = errors.New("Card already added")
+	ErrCardInvalidExpiration = errors.New("Invalid or past card expiration")
+	ErrCardInvalidCVV        = errors.New("Invalid card CVV")
 )
 
-type Card struct{}
+type Card struct {
+	svcVault *Vault
+}
 
 func NewCard() *Card {
 	s := new(Card)
+	s.svcVault = NewVault()
 
 	return s
 }
 
 /* {{{ [Methods] */
 
-// Create
-func (s *Card) Create(ctx context.Context, input *model.Card) (*model.Card, error) {
+// Create : tokenizes card, the number only kept sealed in vault. Settles the default currency if not given
+func (s *Card) Create(ctx context.Context, input *model.Card, number string) (*model.Card, error) {
+	currency, err := currencyCode(input.Currency)
+	if err != nil {
+		return nil, err
+	}
+
 	// Valid card number
-	number := strings.TrimSpace(input.Number)
+	number = strings.TrimSpace(number)
 	number = strings.ReplaceAll(number, " ", "")
 	c := utils.ValidCardNumber(number)
 	if c == utils.CardInvalid {
-		return nil, errors.New("Invalid card number")
+		return nil, ErrCardInvalidNumber
+	}
+
+	existing := &model.Card{
+		OwnerID:     input.OwnerID,
+		OwnerType:   input.OwnerType,
+		Fingerprint: s.svcVault.Fingerprint(number),
+	}
+	err = existing.Get(ctx)
+	if err == nil {
+		return nil, ErrCardExists
+	}
+
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
 	}
 
 	card := &model.Card{
+		ID:        uuid.NewString(),
 		OwnerID:   input.OwnerID,
 		OwnerType: input.OwnerType,
-		Number:    number,
+		Token:     newCardToken(),
 		CardType:  c,
+		Currency:  currency,
+	}
+	err = s.svcVault.Seal(card, number)
+	if err != nil {
+		return nil, err
 	}
 
-	err := card.Create(ctx)
+	err = card.Create(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -67,25 +107,35 @@ func (s *Card) Delete(ctx context.Context, input *model.Card) error {
 	return card.Delete(ctx)
 }
 
-// Update
-func (s *Card) Update(ctx context.Context, input *model.Card) (*model.Card, error) {
-	card := &model.Card{
-		ID:         input.ID,
-		OwnerID:    input.OwnerID,
-		OwnerType:  input.OwnerType,
-		Number:     input.Number,
-		CardType:   input.CardType,
-		CVV:        input.CVV,
-		Expiration: input.Expiration,
-		Holder:     input.Holder,
+// Update : sets holder and expiration of (credit) card, verified with CVV. CVV is checked only, never stored
+func (s *Card) Update(ctx context.Context, input *model.Card, cvv string) (*model.Card, error) {
+	card, err := s.Get(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	if !validExpiration(input.Expiration, time.Now()) {
+		return nil, ErrCardInvalidExpiration
 	}
 
-	err := card.Update(ctx)
+	if !validCVV(cvv, card.CardType) {
+		return nil, ErrCardInvalidCVV
+	}
+
+	update := &model.Card{
+		ID:         card.ID,
+		OwnerID:    card.OwnerID,
+		OwnerType:  card.OwnerType,
+		Holder:     strings.TrimSpace(input.Holder),
+		Expiration: input.Expiration,
+		VerifiedAt: time.Now(),
+	}
+	err = update.Update(ctx)
 	if err != nil {
-		return nil, nil
+		return nil, err
 	}
 
-	return card, nil
+	return s.Get(ctx, card)
 }
 
 // Get
@@ -121,6 +171,49 @@ func (s *Card) List(ctx context.Context, input *model.Card) ([]*model.Card, erro
 
 /* }}} */
 
+// validExpiration checks MM/YY, card valid through the last day of the month
+func validExpiration(expiration string, now time.Time) bool {
+	parts := strings.Split(expiration, "/")
+	if len(parts) != 2 || len(parts[0]) != 2 || len(parts[1]) != 2 {
+		return false
+	}
+
+	month, err := strconv.Atoi(parts[0])
+	if err != nil || month < 1 || month > 12 {
+		return false
+	}
+
+	year, err := strconv.Atoi(parts[1])
+	if err != nil || year < 0 {
+		return false
+	}
+
+	// First day of the month after expiration
+	end := time.Date(2000+year, time.Month(month)+1, 1, 0, 0, 0, 0, time.UTC)
+
+	return now.Before(end)
+}
+
+// validCVV : 4 digits for American Express, 3 for others
+func validCVV(cvv, cardType string) bool {
+	length := 3
+	if cardType == utils.CardAmericanExpress {
+		length = 4
+	}
+
+	if len(cvv) != length {
+		return false
+	}
+
+	for _, c := range cvv {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+
+	return true
+}
+
 /*
  * Local variables:
  * tab-width: 4
@@ -0,0 +1,177 @@
+/*
+ * Copyright (C) HereweTech, Inc - All Rights Reserved
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+/**
+ * @file client.go
+ * @package service
+ * @author Dr.NP <np@herewe.tech>
+ * @since 10/18/2026
+ */
+
+package service
+
+import (
+	"context"
+	"errors"
+	"icepay-svc/model"
+	"icepay-svc/runtime"
+	"icepay-svc/utils"
+	"strings"
+	"time"
+	"unicode"
+)
+
+var (
+	ErrClientWrongPassword          = errors.New("Wrong password")
+	ErrClientInvalidPassword        = errors.New("Invalid password")
+	ErrClientInvalidPaymentPassword = errors.New("Payment password must be 6 digits")
+)
+
+type Client struct{}
+
+func NewClient() *Client {
+	s := new(Client)
+
+	return s
+}
+
+/* {{{ [Methods] */
+
+// Get
+func (s *Client) Get(ctx context.Context, input *model.Client) (*model.Client, error) {
+	clt := &model.Client{
+		ID: input.ID,
+	}
+
+	err := clt.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return clt, nil
+}
+
+// Update profile (name & phone)
+func (s *Client) Update(ctx context.Context, input *model.Client) (*model.Client, error) {
+	clt := &model.Client{
+		ID:    input.ID,
+		Name:  strings.TrimSpace(input.Name),
+		Phone: strings.TrimSpace(input.Phone),
+	}
+
+	if clt.Name != "" || clt.Phone != "" {
+		err := clt.Update(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return s.Get(ctx, input)
+}
+
+// ChangePassword : checks old login password, re-salts and stores the new one
+func (s *Client) ChangePassword(ctx context.Context, id, oldPassword, newPassword string) error {
+	if newPassword == "" {
+		return ErrClientInvalidPassword
+	}
+
+	clt, err := s.Get(ctx, &model.Client{ID: id})
+	if err != nil {
+		return err
+	}
+
+	if clt.Password != model.PasswordNotSet && utils.EncryptPassword(oldPassword, clt.Salt, clt.Email) != clt.Password {
+		return ErrClientWrongPassword
+	}
+
+	upd := &model.Client{
+		ID:                clt.ID,
+		Email:             clt.Email,
+		Salt:              utils.RandomString(32),
+		Password:          newPassword,
+		PasswordChangedAt: time.Now(),
+	}
+	if clt.PaymentSalt == "" {
+		// Legacy client shares salt between passwords, keep the payment one verifiable
+		upd.PaymentSalt = clt.Salt
+	}
+
+	return runtime.RunInTx(ctx, func(ctx context.Context) error {
+		err := upd.Update(ctx)
+		if err != nil {
+			return err
+		}
+
+		return RevokeSessions(ctx, upd.ID, "client", SessionRevokedPassword)
+	})
+}
+
+// ChangePaymentPassword : checks old payment password, re-salts and stores the new one
+func (s *Client) ChangePaymentPassword(ctx context.Context, id, oldPassword, newPassword string) error {
+	if !validPaymentPassword(newPassword) {
+		return ErrClientInvalidPaymentPassword
+	}
+
+	clt, err := s.Get(ctx, &model.Client{ID: id})
+	if err != nil {
+		return err
+	}
+
+	if clt.PaymentPassword != model.PasswordNotSet && utils.EncryptPaymentPassword(oldPassword, paymentSalt(clt), clt.Email) != clt.PaymentPassword {
+		return ErrClientWrongPassword
+	}
+
+	upd := &model.Client{
+		ID:                clt.ID,
+		Email:             clt.Email,
+		PaymentSalt:       utils.RandomString(32),
+		PaymentPassword:   newPassword,
+		PasswordChangedAt: time.Now(),
+	}
+
+	return runtime.RunInTx(ctx, func(ctx context.Context) error {
+		err := upd.Update(ctx)
+		if err != nil {
+			return err
+		}
+
+		return RevokeSessions(ctx, upd.ID, "client", SessionRevokedPassword)
+	})
+}
+
+/* }}} */
+
+// Salt of payment password, legacy clients use the login one
+func paymentSalt(clt *model.Client) string {
+	if clt.PaymentSalt != "" {
+		return clt.PaymentSalt
+	}
+
+	return clt.Salt
+}
+
+func validPaymentPassword(password string) bool {
+	if len(password) != 6 {
+		return false
+	}
+
+	for _, c := range password {
+		if !unicode.IsDigit(c) {
+			return false
+		}
+	}
+
+	return true
+}
+
+/*
+ * Local variables:
+ * tab-width: 4
+ * c-basic-offset: 4
+ * End:
+ * vim600: sw=4 ts=4 fdm=marker
+ * vim<600: sw=4 ts=4
+ */
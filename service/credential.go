@@ -14,71 +14,498 @@
 package service
 
 import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
 	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"icepay-svc/model"
 	"icepay-svc/runtime"
 	"icepay-svc/utils"
+	"math/big"
 	"regexp"
 	"strconv"
 	"strings"
 	"time"
 )
 
+const (
+	credentialSchema   = "icepay://"
+	credentialPrefixV2 = credentialSchema + "v2/"
+
+	// Payment request presented by tenant, scanned by client
+	requestPrefix = credentialSchema + "req/"
+)
+
+// Offline code : 9<index of 8 digits><TOTP of 6 digits>, typed by cashier or rendered as QR by client app
+const (
+	totpCodePrefix  = "9"
+	totpIndexLength = 8
+	totpDigits      = 6
+	totpCodeLength  = len(totpCodePrefix) + totpIndexLength + totpDigits
+	totpSeedLength  = 20
+
+	// Index collisions tolerated on provisioning
+	totpIndexAttempts = 5
+)
+
+var (
+	ErrCredentialFormat   = errors.New("Wrong credential format")
+	ErrCredentialInvalid  = errors.New("Invalid credential")
+	ErrCredentialExpired  = errors.New("Credential expires")
+	ErrCredentialV1       = errors.New("Legacy credential no longer accepted")
+	ErrCredentialUsed     = errors.New("Credential used")
+	ErrTOTPIndexExhausted = errors.New("No free TOTP index")
+	ErrTOTPLocked         = errors.New("Offline code locked after too many failures")
+)
+
+// Legacy payload : <id>@@<expiry>, zero padded by AES-CBC
+var reCredentialV1 = regexp.MustCompile(`^(.+)@@(\d+)\x00*$`)
+
 type CredentialSource struct {
 	ID     string
 	Expiry time.Time
 }
-type Credential struct{}
+
+// credentialPayload : sealed part of v2 credential
+type credentialPayload struct {
+	ID     string `json:"id"`
+	Expiry int64  `json:"exp"`
+	Nonce  string `json:"jti"`
+}
+
+// requestPayload : sealed part of payment request
+type requestPayload struct {
+	ID     string `json:"id"`
+	Tenant string `json:"tnt"`
+	Expiry int64  `json:"exp"`
+}
+
+// TOTPProvision : what client app needs to generate offline codes, prefix + index + TOTP of seed
+type TOTPProvision struct {
+	Index  string
+	Seed   []byte
+	Prefix string
+	Digits int
+	Step   int64
+}
+
+// CredentialUsed : event to client, consumed credential presented again
+type CredentialUsed struct {
+	Tenant    string    `json:"tenant"`
+	ExpiresAt time.Time `json:"expires_at"`
+	At        time.Time `json:"at"`
+}
+
+type Credential struct {
+	keyring  *utils.Keyring
+	acceptV1 bool
+}
 
 func NewCredential() *Credential {
 	s := new(Credential)
+	keyring, err := utils.NewKeyring(runtime.Config.Security.CredentialKeys, runtime.Config.Security.CredentialKeyID)
+	if err != nil {
+		runtime.Logger.Fatalf("credential keyring initialize failed : %s", err)
+	}
+
+	s.keyring = keyring
+	s.acceptV1 = runtime.Config.Security.CredentialAcceptV1
 
 	return s
 }
 
 /* {{{ [Methods] */
+
+// Encode : icepay://v2/<key id>.<base64url of AES-GCM nonce and sealed payload>
 func (s *Credential) Encode(id string) (string, error) {
-	sourceStr := fmt.Sprintf("%s@@%d", id, time.Now().Add(time.Duration(runtime.Config.Security.CredentialLifetime)*time.Minute).Unix())
-	// AES encrypt
-	cipher, err := utils.AESCrypt([]byte(sourceStr), []byte(runtime.Config.Security.AESKey))
+	return s.encode(id, time.Now().Add(time.Duration(runtime.Config.Security.CredentialLifetime)*time.Minute))
+}
+
+// Decode returns client ID of credential or offline code, v1 credentials accepted during transition
+func (s *Credential) Decode(ctx context.Context, credential string) (string, error) {
+	payload, err := s.parse(ctx, credential)
 	if err != nil {
 		return "", err
 	}
 
-	cipherText := base64.StdEncoding.EncodeToString(cipher)
-	str := fmt.Sprintf("icepay://%s", cipherText)
+	return payload.ID, nil
+}
+
+// Consume decodes credential and consumes its nonce, a credential is usable only once.
+// Client ID returned along with ErrCredentialUsed, and the client notified
+func (s *Credential) Consume(ctx context.Context, credential, tenant string) (string, error) {
+	payload, err := s.parse(ctx, credential)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := &model.CredentialNonce{
+		Nonce:     payload.Nonce,
+		Client:    payload.ID,
+		Tenant:    tenant,
+		ExpiresAt: time.Unix(payload.Expiry, 0),
+	}
+	err = nonce.Create(ctx)
+	if errors.Is(err, model.ErrCredentialNonceUsed) {
+		runtime.Logger.Warnf("credential of client [%s] replayed by tenant [%s]", payload.ID, tenant)
+		b, _ := json.Marshal(&CredentialUsed{
+			Tenant:    tenant,
+			ExpiresAt: nonce.ExpiresAt,
+			At:        time.Now(),
+		})
+		perr := publishEvent(subject("client", payload.ID), EventCredentialUsed, b)
+		if perr != nil {
+			runtime.Logger.Errorf("notify credential used to client [%s] failed : %s", payload.ID, perr)
+		}
+
+		return payload.ID, ErrCredentialUsed
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	return payload.ID, nil
+}
+
+// Purge removes nonces of expired credentials, for the background worker
+func (s *Credential) Purge(ctx context.Context) error {
+	n, err := new(model.CredentialNonce).Purge(ctx, time.Now())
+	if n > 0 {
+		runtime.Logger.Infof("%d nonces of expired credentials purged", n)
+	}
+
+	return err
+}
+
+// EncodeRequest : icepay://req/<key id>.<sealed payload>, payment request of tenant valid until expiry
+func (s *Credential) EncodeRequest(transaction *model.Transaction, expiry time.Time) (string, error) {
+	return s.seal(requestPrefix, &requestPayload{
+		ID:     transaction.ID,
+		Tenant: transaction.Tenant,
+		Expiry: expiry.Unix(),
+	})
+}
+
+// DecodeRequest returns transaction of payment request, only ID and tenant set
+func (s *Credential) DecodeRequest(request string) (*model.Transaction, error) {
+	if !strings.HasPrefix(request, requestPrefix) {
+		return nil, ErrCredentialFormat
+	}
+
+	var payload requestPayload
+	err := s.open(requestPrefix, request, &payload)
+	if err != nil {
+		return nil, err
+	}
+
+	if payload.ID == "" || payload.Tenant == "" {
+		return nil, ErrCredentialInvalid
+	}
+
+	if time.Now().Unix() >= payload.Expiry {
+		return nil, ErrCredentialExpired
+	}
+
+	return &model.Transaction{
+		ID:     payload.ID,
+		Tenant: payload.Tenant,
+	}, nil
+}
+
+// Provision generates TOTP seed of client for offline codes.
+// The raw seed is shown to the client only once, provisioning again replaces it
+func (s *Credential) Provision(ctx context.Context, id string) (*TOTPProvision, error) {
+	seed := make([]byte, totpSeedLength)
+	_, err := rand.Read(seed)
+	if err != nil {
+		return nil, err
+	}
+
+	kid, sealed, err := s.keyring.Seal(seed, totpAdditional(id))
+	if err != nil {
+		return nil, err
+	}
+
+	for i := 0; i < totpIndexAttempts; i++ {
+		index, err := newTOTPIndex()
+		if err != nil {
+			return nil, err
+		}
+
+		existing := &model.TOTPSeed{Index: index}
+		err = existing.Get(ctx)
+		if err == nil {
+			continue
+		}
+
+		if !errors.Is(err, model.ErrTOTPSeedDoesNotExists) {
+			return nil, err
+		}
+
+		err = (&model.TOTPSeed{
+			Client: id,
+			Index:  index,
+			Seed:   sealed,
+			KeyID:  kid,
+		}).Create(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		return &TOTPProvision{
+			Index:  index,
+			Seed:   seed,
+			Prefix: totpCodePrefix,
+			Digits: totpDigits,
+			Step:   runtime.Config.Security.TOTPStep,
+		}, nil
+	}
+
+	return nil, ErrTOTPIndexExhausted
+}
+
+// Revoke removes TOTP seed of client, offline codes no longer accepted
+func (s *Credential) Revoke(ctx context.Context, id string) error {
+	return (&model.TOTPSeed{Client: id}).Delete(ctx)
+}
+
+func (s *Credential) parse(ctx context.Context, credential string) (*credentialPayload, error) {
+	if code, ok := totpCode(credential); ok {
+		return s.decodeTOTP(ctx, code)
+	}
+
+	if strings.HasPrefix(credential, credentialPrefixV2) {
+		return s.decode(credential)
+	}
+
+	if !strings.HasPrefix(credential, credentialSchema) {
+		return nil, ErrCredentialFormat
+	}
+
+	if !s.acceptV1 {
+		return nil, ErrCredentialV1
+	}
 
-	return str, nil
+	return decodeV1(credential)
 }
 
-func (s *Credential) Decode(credential string) (string, error) {
-	if !strings.HasPrefix(credential, "icepay://") {
-		return "", errors.New("Wrong credential format")
+func (s *Credential) encode(id string, expiry time.Time) (string, error) {
+	return s.seal(credentialPrefixV2, &credentialPayload{
+		ID:     id,
+		Expiry: expiry.Unix(),
+		Nonce:  utils.SecureRandomString(24),
+	})
+}
+
+func (s *Credential) decode(credential string) (*credentialPayload, error) {
+	var payload credentialPayload
+	err := s.open(credentialPrefixV2, credential, &payload)
+	if err != nil {
+		return nil, err
 	}
 
-	stream, err := base64.StdEncoding.DecodeString(credential[9:])
+	if payload.ID == "" || payload.Nonce == "" {
+		return nil, ErrCredentialInvalid
+	}
+
+	if time.Now().Unix() >= payload.Expiry {
+		return nil, ErrCredentialExpired
+	}
+
+	return &payload, nil
+}
+
+// seal : <prefix><key id>.<base64url of AES-GCM nonce and sealed JSON of v>
+func (s *Credential) seal(prefix string, v interface{}) (string, error) {
+	b, _ := json.Marshal(v)
+	kid := s.keyring.Active()
+	_, sealed, err := s.keyring.Seal(b, []byte(prefix+kid))
 	if err != nil {
 		return "", err
 	}
 
+	return fmt.Sprintf("%s%s.%s", prefix, kid, base64.RawURLEncoding.EncodeToString(sealed)), nil
+}
+
+func (s *Credential) open(prefix, code string, v interface{}) error {
+	kid, encoded, found := strings.Cut(code[len(prefix):], ".")
+	if !found || kid == "" {
+		return ErrCredentialFormat
+	}
+
+	sealed, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return ErrCredentialFormat
+	}
+
+	// Key ID and version authenticated along with payload
+	b, err := s.keyring.Open(kid, sealed, []byte(prefix+kid))
+	if err != nil {
+		return ErrCredentialInvalid
+	}
+
+	err = json.Unmarshal(b, v)
+	if err != nil {
+		return ErrCredentialInvalid
+	}
+
+	return nil
+}
+
+// decodeTOTP : resolves offline code to client by index, then checks TOTP within time step window
+func (s *Credential) decodeTOTP(ctx context.Context, code string) (*credentialPayload, error) {
+	seed := &model.TOTPSeed{Index: code[len(totpCodePrefix) : len(totpCodePrefix)+totpIndexLength]}
+	err := seed.Get(ctx)
+	if errors.Is(err, model.ErrTOTPSeedDoesNotExists) {
+		return nil, ErrCredentialInvalid
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	// Index is the public part of codes, guessing the rest limited per index
+	now := time.Now()
+	if seed.LockedUntil.After(now) {
+		return nil, ErrTOTPLocked
+	}
+
+	payload, err := s.verifyTOTP(seed, code[len(code)-totpDigits:], now)
+	if errors.Is(err, ErrCredentialInvalid) {
+		limit := runtime.Config.Security.TOTPMaxFailures
+		if limit > 0 {
+			// Counted even if the payment rolls back
+			lockUntil := now.Add(time.Duration(runtime.Config.Security.TOTPLockTime) * time.Minute)
+			ferr := seed.Fail(runtime.WithoutTx(ctx), int(limit), lockUntil)
+			if ferr != nil {
+				return nil, ferr
+			}
+
+			if seed.LockedUntil.After(now) {
+				runtime.Logger.Warnf("offline codes of client [%s] locked until %s", seed.Client, seed.LockedUntil)
+
+				return nil, ErrTOTPLocked
+			}
+		}
+
+		return nil, err
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if seed.Failures > 0 {
+		err = seed.ResetFailures(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return payload, nil
+}
+
+func (s *Credential) verifyTOTP(seed *model.TOTPSeed, otp string, now time.Time) (*credentialPayload, error) {
+	key, err := s.keyring.Open(seed.KeyID, seed.Seed, totpAdditional(seed.Client))
+	if err != nil {
+		runtime.Logger.Errorf("open TOTP seed of client [%s] failed : %s", seed.Client, err)
+
+		return nil, ErrCredentialInvalid
+	}
+
+	step := runtime.Config.Security.TOTPStep
+	skew := runtime.Config.Security.TOTPSkew
+	current := now.Unix() / step
+	for counter := current - skew; counter <= current+skew; counter++ {
+		if counter < 0 {
+			continue
+		}
+
+		if subtle.ConstantTimeCompare([]byte(utils.HOTP(key, uint64(counter), totpDigits)), []byte(otp)) == 1 {
+			return &credentialPayload{
+				ID: seed.Client,
+				// Accepted until the window passes the step
+				Expiry: (counter + skew + 1) * step,
+				Nonce:  fmt.Sprintf("totp:%s:%d", seed.Index, counter),
+			}, nil
+		}
+	}
+
+	return nil, ErrCredentialInvalid
+}
+
+func decodeV1(credential string) (*credentialPayload, error) {
+	stream, err := base64.StdEncoding.DecodeString(credential[len(credentialSchema):])
+	if err != nil {
+		return nil, ErrCredentialFormat
+	}
+
 	// AES decrypt
 	source, err := utils.AESDecrypt(stream, []byte(runtime.Config.Security.AESKey))
-	reSource := regexp.MustCompile(`(.+)@@(\d+)`)
-	matches := reSource.FindSubmatch(source)
-	if len(matches) == 3 {
-		expiryUnixStamp, _ := strconv.ParseInt(string(matches[2]), 10, 63)
-		if time.Now().Unix() < expiryUnixStamp {
-			return string(matches[1]), nil
-		} else {
-			return "", errors.New("Credential expires")
+	if err != nil {
+		return nil, ErrCredentialInvalid
+	}
+
+	matches := reCredentialV1.FindSubmatch(source)
+	if len(matches) != 3 {
+		return nil, ErrCredentialInvalid
+	}
+
+	expiryUnixStamp, _ := strconv.ParseInt(string(matches[2]), 10, 64)
+	if time.Now().Unix() >= expiryUnixStamp {
+		return nil, ErrCredentialExpired
+	}
+
+	// No nonce inside, deterministic in the same second, replay of the same string still detected
+	hash := sha256.Sum256([]byte(credential))
+
+	return &credentialPayload{
+		ID:     string(matches[1]),
+		Expiry: expiryUnixStamp,
+		Nonce:  "v1:" + hex.EncodeToString(hash[:]),
+	}, nil
+}
+
+// totpCode : offline code with separators typed by cashier removed, ok if credential is one
+func totpCode(credential string) (string, bool) {
+	code := strings.Map(func(r rune) rune {
+		if r == ' ' || r == '-' {
+			return -1
+		}
+
+		return r
+	}, credential)
+	if len(code) != totpCodeLength || !strings.HasPrefix(code, totpCodePrefix) {
+		return "", false
+	}
+
+	for _, c := range code {
+		if c < '0' || c > '9' {
+			return "", false
 		}
 	}
 
-	return "", errors.New("Invalid credential")
+	return code, true
+}
+
+func totpAdditional(id string) []byte {
+	return []byte("totp:" + id)
 }
 
-/* }}} */
+func newTOTPIndex() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(100000000))
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%0*d", totpIndexLength, n.Int64()), nil
+}
 
 /*
  * Local variables:
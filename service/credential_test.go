@@ -0,0 +1,316 @@
+/*
+ * Copyright (C) HereweTech, Inc - All Rights Reserved
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+/**
+ * @file credential_test.go
+ * @package service
+ * @author Dr.NP <np@herewe.tech>
+ * @since 10/18/2026
+ */
+
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"icepay-svc/model"
+	"icepay-svc/runtime"
+	"icepay-svc/utils"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testClientID = "5b0c7a4e-8f7d-4b8e-9a65-0d1c2e3f4a5b"
+
+func newTestCredential(t *testing.T, keys map[string]string, active string, acceptV1 bool) *Credential {
+	t.Helper()
+	runtime.Config.Security.CredentialKeys = keys
+	runtime.Config.Security.CredentialKeyID = active
+	runtime.Config.Security.CredentialAcceptV1 = acceptV1
+	runtime.Config.Security.CredentialLifetime = 5
+	runtime.Config.Security.AESKey = "icepay@@20130920"
+
+	return NewCredential()
+}
+
+func TestCredentialRoundTrip(t *testing.T) {
+	s := newTestCredential(t, map[string]string{"k1": "0123456789abcdef0123456789abcdef"}, "k1", false)
+	credential, err := s.Encode(testClientID)
+	if err != nil {
+		t.Fatalf("encode failed : %s", err)
+	}
+
+	if !strings.HasPrefix(credential, "icepay://v2/k1.") {
+		t.Errorf("credential %s without v2 prefix and key ID", credential)
+	}
+
+	id, err := s.Decode(context.Background(), credential)
+	if err != nil {
+		t.Fatalf("decode failed : %s", err)
+	}
+
+	if id != testClientID {
+		t.Errorf("decoded %s, want %s", id, testClientID)
+	}
+
+	// Random nonce
+	again, _ := s.Encode(testClientID)
+	if again == credential {
+		t.Error("credentials of the same client should differ")
+	}
+}
+
+func TestCredentialTampering(t *testing.T) {
+	s := newTestCredential(t, map[string]string{
+		"k1": "0123456789abcdef0123456789abcdef",
+		"k2": "fedcba9876543210fedcba9876543210",
+	}, "k1", false)
+	credential, _ := s.Encode(testClientID)
+	kid, encoded, _ := strings.Cut(strings.TrimPrefix(credential, credentialPrefixV2), ".")
+	sealed, _ := base64.RawURLEncoding.DecodeString(encoded)
+
+	flipped := make([]byte, len(sealed))
+	copy(flipped, sealed)
+	flipped[len(flipped)/2] ^= 0x01
+
+	cases := map[string]struct {
+		credential string
+		err        error
+	}{
+		"flipped byte":   {credentialPrefixV2 + kid + "." + base64.RawURLEncoding.EncodeToString(flipped), ErrCredentialInvalid},
+		"truncated":      {credentialPrefixV2 + kid + "." + base64.RawURLEncoding.EncodeToString(sealed[:len(sealed)-1]), ErrCredentialInvalid},
+		"nonce only":     {credentialPrefixV2 + kid + "." + base64.RawURLEncoding.EncodeToString(sealed[:8]), ErrCredentialInvalid},
+		"other key ID":   {credentialPrefixV2 + "k2." + encoded, ErrCredentialInvalid},
+		"unknown key ID": {credentialPrefixV2 + "k9." + encoded, ErrCredentialInvalid},
+		"missing key ID": {credentialPrefixV2 + encoded, ErrCredentialFormat},
+		"bad encoding":   {credentialPrefixV2 + kid + ".!!!", ErrCredentialFormat},
+		"wrong schema":   {"https://" + encoded, ErrCredentialFormat},
+		"empty":          {"", ErrCredentialFormat},
+	}
+	for name, c := range cases {
+		_, err := s.Decode(context.Background(), c.credential)
+		if !errors.Is(err, c.err) {
+			t.Errorf("%s : got %v, want %v", name, err, c.err)
+		}
+	}
+}
+
+func TestCredentialExpiry(t *testing.T) {
+	s := newTestCredential(t, map[string]string{"k1": "0123456789abcdef0123456789abcdef"}, "k1", true)
+	credential, err := s.encode(testClientID, time.Now().Add(-time.Second))
+	if err != nil {
+		t.Fatalf("encode failed : %s", err)
+	}
+
+	_, err = s.Decode(context.Background(), credential)
+	if !errors.Is(err, ErrCredentialExpired) {
+		t.Errorf("got %v, want %v", err, ErrCredentialExpired)
+	}
+
+	legacy := encodeV1(t, testClientID, time.Now().Add(-time.Second))
+	_, err = s.Decode(context.Background(), legacy)
+	if !errors.Is(err, ErrCredentialExpired) {
+		t.Errorf("v1 : got %v, want %v", err, ErrCredentialExpired)
+	}
+}
+
+func TestCredentialKeyRotation(t *testing.T) {
+	old := newTestCredential(t, map[string]string{"k1": "0123456789abcdef0123456789abcdef"}, "k1", false)
+	issued, _ := old.Encode(testClientID)
+
+	// k2 introduced, k1 kept for credentials already issued
+	rotated := newTestCredential(t, map[string]string{
+		"k1": "0123456789abcdef0123456789abcdef",
+		"k2": "fedcba9876543210fedcba9876543210",
+	}, "k2", false)
+	id, err := rotated.Decode(context.Background(), issued)
+	if err != nil || id != testClientID {
+		t.Fatalf("decode credential of previous key : %s, %v", id, err)
+	}
+
+	credential, _ := rotated.Encode(testClientID)
+	if !strings.HasPrefix(credential, credentialPrefixV2+"k2.") {
+		t.Errorf("credential %s not sealed by active key", credential)
+	}
+
+	// k1 retired
+	retired := newTestCredential(t, map[string]string{"k2": "fedcba9876543210fedcba9876543210"}, "k2", false)
+	_, err = retired.Decode(context.Background(), issued)
+	if !errors.Is(err, ErrCredentialInvalid) {
+		t.Errorf("credential of retired key : got %v, want %v", err, ErrCredentialInvalid)
+	}
+
+	id, err = retired.Decode(context.Background(), credential)
+	if err != nil || id != testClientID {
+		t.Errorf("decode credential of active key : %s, %v", id, err)
+	}
+}
+
+func TestCredentialV1Transition(t *testing.T) {
+	s := newTestCredential(t, map[string]string{"k1": "0123456789abcdef0123456789abcdef"}, "k1", true)
+	legacy := encodeV1(t, testClientID, time.Now().Add(time.Minute))
+	id, err := s.Decode(context.Background(), legacy)
+	if err != nil || id != testClientID {
+		t.Fatalf("decode v1 credential : %s, %v", id, err)
+	}
+
+	// Misaligned ciphertext
+	_, err = s.Decode(context.Background(), "icepay://"+base64.StdEncoding.EncodeToString([]byte("short")))
+	if !errors.Is(err, ErrCredentialInvalid) {
+		t.Errorf("misaligned v1 : got %v, want %v", err, ErrCredentialInvalid)
+	}
+
+	s = newTestCredential(t, map[string]string{"k1": "0123456789abcdef0123456789abcdef"}, "k1", false)
+	_, err = s.Decode(context.Background(), legacy)
+	if !errors.Is(err, ErrCredentialV1) {
+		t.Errorf("v1 after transition : got %v, want %v", err, ErrCredentialV1)
+	}
+}
+
+func TestHOTPVectors(t *testing.T) {
+	// RFC 6238 appendix B, SHA1, 8 digits
+	key := []byte("12345678901234567890")
+	vectors := map[int64]string{
+		59:         "94287082",
+		1111111109: "07081804",
+		1111111111: "14050471",
+		1234567890: "89005924",
+		2000000000: "69279037",
+	}
+	for at, want := range vectors {
+		got := utils.HOTP(key, uint64(at/30), 8)
+		if got != want {
+			t.Errorf("HOTP of counter %d = %s, want %s", at/30, got, want)
+		}
+	}
+}
+
+func TestTOTPCode(t *testing.T) {
+	s := newTestCredential(t, map[string]string{"k1": "0123456789abcdef0123456789abcdef"}, "k1", false)
+	runtime.Config.Security.TOTPStep = 30
+	runtime.Config.Security.TOTPSkew = 1
+
+	key := []byte("12345678901234567890")
+	kid, sealed, err := s.keyring.Seal(key, totpAdditional(testClientID))
+	if err != nil {
+		t.Fatalf("seal seed failed : %s", err)
+	}
+
+	seed := &model.TOTPSeed{Client: testClientID, Index: "00012345", Seed: sealed, KeyID: kid}
+	now := time.Unix(1111111111, 0)
+	otp := utils.HOTP(key, uint64(now.Unix()/30), totpDigits)
+
+	code, ok := totpCode("9 0001 2345 " + otp[:3] + "-" + otp[3:])
+	if !ok || code != "900012345"+otp {
+		t.Fatalf("typed code parsed as %s, %v", code, ok)
+	}
+
+	for _, v := range []string{"900012345" + otp[:5], "800012345" + otp, "90001234a" + otp, "icepay://v2/k1.x"} {
+		if _, ok := totpCode(v); ok {
+			t.Errorf("%s parsed as offline code", v)
+		}
+	}
+
+	payload, err := s.verifyTOTP(seed, otp, now)
+	if err != nil {
+		t.Fatalf("verify failed : %s", err)
+	}
+
+	if payload.ID != testClientID {
+		t.Errorf("resolved %s, want %s", payload.ID, testClientID)
+	}
+
+	// Clock drift of one step
+	drifted, err := s.verifyTOTP(seed, otp, now.Add(30*time.Second))
+	if err != nil {
+		t.Fatalf("verify next step failed : %s", err)
+	}
+
+	// Same code same nonce, consumed once
+	if drifted.Nonce != payload.Nonce {
+		t.Errorf("nonce %s of drifted verification, want %s", drifted.Nonce, payload.Nonce)
+	}
+
+	if payload.Expiry <= now.Unix() {
+		t.Errorf("expiry %d not after %d", payload.Expiry, now.Unix())
+	}
+
+	_, err = s.verifyTOTP(seed, otp, now.Add(90*time.Second))
+	if !errors.Is(err, ErrCredentialInvalid) {
+		t.Errorf("verify out of window = %v, want %v", err, ErrCredentialInvalid)
+	}
+
+	// Seed bound to client
+	seed.Client = "another"
+	_, err = s.verifyTOTP(seed, otp, now)
+	if !errors.Is(err, ErrCredentialInvalid) {
+		t.Errorf("verify of moved seed = %v, want %v", err, ErrCredentialInvalid)
+	}
+}
+
+func TestPaymentRequest(t *testing.T) {
+	s := newTestCredential(t, map[string]string{"k1": "0123456789abcdef0123456789abcdef"}, "k1", false)
+	request, err := s.EncodeRequest(&model.Transaction{ID: "t1", Tenant: "m1"}, time.Now().Add(time.Minute))
+	if err != nil {
+		t.Fatalf("encode failed : %s", err)
+	}
+
+	transaction, err := s.DecodeRequest(request)
+	if err != nil {
+		t.Fatalf("decode failed : %s", err)
+	}
+
+	if transaction.ID != "t1" || transaction.Tenant != "m1" {
+		t.Errorf("decoded [%s] of [%s], want [t1] of [m1]", transaction.ID, transaction.Tenant)
+	}
+
+	// Not interchangeable with client credentials
+	credential, _ := s.Encode(testClientID)
+	_, err = s.DecodeRequest(credential)
+	if !errors.Is(err, ErrCredentialFormat) {
+		t.Errorf("decode credential as request = %v, want %v", err, ErrCredentialFormat)
+	}
+
+	_, err = s.Decode(context.Background(), request)
+	if err == nil {
+		t.Error("request accepted as credential")
+	}
+
+	forged := strings.Replace(credential, credentialPrefixV2, requestPrefix, 1)
+	_, err = s.DecodeRequest(forged)
+	if !errors.Is(err, ErrCredentialInvalid) {
+		t.Errorf("decode forged request = %v, want %v", err, ErrCredentialInvalid)
+	}
+
+	expired, _ := s.EncodeRequest(&model.Transaction{ID: "t1", Tenant: "m1"}, time.Now().Add(-time.Second))
+	_, err = s.DecodeRequest(expired)
+	if !errors.Is(err, ErrCredentialExpired) {
+		t.Errorf("decode expired request = %v, want %v", err, ErrCredentialExpired)
+	}
+}
+
+// encodeV1 : credential in the legacy format
+func encodeV1(t *testing.T, id string, expiry time.Time) string {
+	t.Helper()
+	cipher, err := utils.AESCrypt([]byte(fmt.Sprintf("%s@@%d", id, expiry.Unix())), []byte(runtime.Config.Security.AESKey))
+	if err != nil {
+		t.Fatalf("encode v1 failed : %s", err)
+	}
+
+	return "icepay://" + base64.StdEncoding.EncodeToString(cipher)
+}
+
+/*
+ * Local variables:
+ * tab-width: 4
+ * c-basic-offset: 4
+ * End:
+ * vim600: sw=4 ts=4 fdm=marker
+ * vim<600: sw=4 ts=4
+ */
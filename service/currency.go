@@ -0,0 +1,114 @@
+/*
+ * Copyright (C) HereweTech, Inc - All Rights Reserved
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+/**
+ * @file currency.go
+ * @package service
+ * @author Dr.NP <np@herewe.tech>
+ * @since 10/18/2026
+ */
+
+package service
+
+import (
+	"context"
+	"errors"
+	"icepay-svc/model"
+	"icepay-svc/runtime"
+	"icepay-svc/utils"
+)
+
+var (
+	ErrCurrencyInvalid     = errors.New("Invalid currency")
+	ErrCurrencyNotAccepted = errors.New("Currency not accepted by tenant")
+	ErrCurrencyUnsettled   = errors.New("No card settles the currency")
+)
+
+// currencyCode : normalized ISO 4217 code, the default currency if empty
+func currencyCode(code string) (string, error) {
+	if code == "" {
+		return runtime.Config.Payment.DefaultCurrency, nil
+	}
+
+	c, ok := utils.LookupCurrency(code)
+	if !ok {
+		return "", ErrCurrencyInvalid
+	}
+
+	return c.Code, nil
+}
+
+// currencyCodes : normalized and deduplicated codes, in given order
+func currencyCodes(codes []string) ([]string, error) {
+	ret := make([]string, 0, len(codes))
+	seen := make(map[string]bool)
+	for _, code := range codes {
+		c, ok := utils.LookupCurrency(code)
+		if !ok {
+			return nil, ErrCurrencyInvalid
+		}
+
+		if !seen[c.Code] {
+			seen[c.Code] = true
+			ret = append(ret, c.Code)
+		}
+	}
+
+	return ret, nil
+}
+
+// acceptCurrency : checks currency against the ones configured by tenant
+func acceptCurrency(ctx context.Context, tenant, currency string) error {
+	tnt := &model.Tenant{
+		ID: tenant,
+	}
+
+	err := tnt.Get(ctx)
+	if err != nil {
+		return err
+	}
+
+	accepted := tnt.Currencies
+	if len(accepted) == 0 {
+		accepted = []string{runtime.Config.Payment.DefaultCurrency}
+	}
+
+	for _, code := range accepted {
+		if code == currency {
+			return nil
+		}
+	}
+
+	return ErrCurrencyNotAccepted
+}
+
+// settleCurrency : checks if client has any card settling currency
+func settleCurrency(ctx context.Context, client, currency string) error {
+	card := &model.Card{
+		OwnerID:   client,
+		OwnerType: "client",
+	}
+
+	settles, err := card.Settles(ctx, currency, runtime.Config.Payment.DefaultCurrency)
+	if err != nil {
+		return err
+	}
+
+	if !settles {
+		return ErrCurrencyUnsettled
+	}
+
+	return nil
+}
+
+/*
+ * Local variables:
+ * tab-width: 4
+ * c-basic-offset: 4
+ * End:
+ * vim600: sw=4 ts=4 fdm=marker
+ * vim<600: sw=4 ts=4
+ */
@@ -0,0 +1,49 @@
+/*
+ * Copyright (C) HereweTech, Inc - All Rights Reserved
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+/**
+ * @file currency_test.go
+ * @package service
+ * @author Dr.NP <np@herewe.tech>
+ * @since 10/18/2026
+ */
+
+package service
+
+import (
+	"errors"
+	"icepay-svc/runtime"
+	"testing"
+)
+
+func TestCurrencyCodes(t *testing.T) {
+	runtime.Config.Payment.DefaultCurrency = "CNY"
+	code, err := currencyCode("")
+	if err != nil || code != "CNY" {
+		t.Errorf("empty currency = %s, %v, want default CNY", code, err)
+	}
+
+	codes, err := currencyCodes([]string{"usd", " EUR", "USD"})
+	if err != nil || len(codes) != 2 || codes[0] != "USD" || codes[1] != "EUR" {
+		t.Errorf("currencies = %v, %v, want [USD EUR]", codes, err)
+	}
+
+	for _, v := range []string{"US", "ABC", "XAU"} {
+		_, err = currencyCode(v)
+		if !errors.Is(err, ErrCurrencyInvalid) {
+			t.Errorf("currency %s = %v, want %v", v, err, ErrCurrencyInvalid)
+		}
+	}
+}
+
+/*
+ * Local variables:
+ * tab-width: 4
+ * c-basic-offset: 4
+ * End:
+ * vim600: sw=4 ts=4 fdm=marker
+ * vim<600: sw=4 ts=4
+ */
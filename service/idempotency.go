@@ -0,0 +1,150 @@
+/*
+ * Copyright (C) HereweTech, Inc - All Rights Reserved
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+/**
+ * @file idempotency.go
+ * @package service
+ * @author Dr.NP <np@herewe.tech>
+ * @since 10/18/2026
+ */
+
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"icepay-svc/model"
+	"icepay-svc/runtime"
+	"time"
+)
+
+var (
+	ErrIdempotencyKeyMismatch = errors.New("Idempotency key reused with a different request")
+	ErrIdempotencyInProgress  = errors.New("Request of the idempotency key still in progress")
+)
+
+type Idempotency struct{}
+
+func NewIdempotency() *Idempotency {
+	s := new(Idempotency)
+
+	return s
+}
+
+/* {{{ [Methods] */
+
+// Begin claims idempotency key for request. If the key was used by the same request before, the stored
+// record is returned with replay flag set. Keys in progress are leased, claimable again after the lease
+func (s *Idempotency) Begin(ctx context.Context, ownerID, ownerType, key string, request ...[]byte) (*model.Idempotency, bool, error) {
+	now := time.Now()
+	rec := &model.Idempotency{
+		OwnerID:     ownerID,
+		OwnerType:   ownerType,
+		Key:         key,
+		RequestHash: hashRequest(request...),
+		LeasedUntil: now.Add(time.Duration(runtime.Config.HTTP.IdempotencyLease) * time.Second),
+	}
+
+	err := rec.Create(ctx)
+	if err == nil {
+		return rec, false, nil
+	}
+
+	if !errors.Is(err, model.ErrIdempotencyKeyExists) {
+		return nil, false, err
+	}
+
+	existing := &model.Idempotency{
+		OwnerID:   ownerID,
+		OwnerType: ownerType,
+		Key:       key,
+	}
+	err = existing.Get(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+
+	lifetime := time.Duration(runtime.Config.HTTP.IdempotencyKeyLifetime) * time.Hour
+	if existing.CreatedAt.Add(lifetime).Before(now) {
+		// Expired, claim it again
+		err = existing.Delete(ctx)
+		if err != nil {
+			return nil, false, err
+		}
+
+		rec.ID = ""
+		err = rec.Create(ctx)
+		if errors.Is(err, model.ErrIdempotencyKeyExists) {
+			return nil, false, ErrIdempotencyInProgress
+		}
+
+		return rec, false, err
+	}
+
+	if existing.RequestHash != rec.RequestHash {
+		return nil, false, ErrIdempotencyKeyMismatch
+	}
+
+	if existing.StatusCode == 0 {
+		// Request crashed or never finished, the key is claimable again once its lease is over
+		released, err := existing.Release(ctx, now)
+		if err != nil {
+			return nil, false, err
+		}
+
+		if !released {
+			return nil, false, ErrIdempotencyInProgress
+		}
+
+		runtime.Logger.Warnf("idempotency key [%s] of %s [%s] left in progress, claimed again", key, ownerType, ownerID)
+		rec.ID = ""
+		err = rec.Create(ctx)
+		if errors.Is(err, model.ErrIdempotencyKeyExists) {
+			return nil, false, ErrIdempotencyInProgress
+		}
+
+		return rec, false, err
+	}
+
+	return existing, true, nil
+}
+
+// Finish stores response of the request claimed by Begin, replayed with the same status and content type
+func (s *Idempotency) Finish(ctx context.Context, rec *model.Idempotency, status int, contentType string, body []byte) error {
+	rec.StatusCode = status
+	rec.ContentType = contentType
+	rec.Response = body
+
+	return rec.Update(ctx)
+}
+
+// Abort releases the key, so the request could be retried
+func (s *Idempotency) Abort(ctx context.Context, rec *model.Idempotency) error {
+	return rec.Delete(ctx)
+}
+
+/* }}} */
+
+func hashRequest(parts ...[]byte) string {
+	hash := sha256.New()
+	for _, part := range parts {
+		hash.Write(part)
+		hash.Write([]byte{0})
+	}
+
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+/*
+ * Local variables:
+ * tab-width: 4
+ * c-basic-offset: 4
+ * End:
+ * vim600: sw=4 ts=4 fdm=marker
+ * vim<600: sw=4 ts=4
+ */
@@ -0,0 +1,163 @@
+/*
+ * Copyright (C) HereweTech, Inc - All Rights Reserved
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+/**
+ * @file ledger.go
+ * @package service
+ * @author Dr.NP <np@herewe.tech>
+ * @since 10/18/2026
+ */
+
+package service
+
+import (
+	"context"
+	"icepay-svc/model"
+)
+
+const (
+	// Paid out by client on confirmation, paid back by refunds
+	LedgerAccountClientFunding = "CLIENT_FUNDING"
+
+	// Owed to tenant, net of fees
+	LedgerAccountTenantReceivable = "TENANT_RECEIVABLE"
+
+	// Platform fees, owner is empty
+	LedgerAccountFees = "FEES"
+
+	// Refunded by tenant, deducted from settlement
+	LedgerAccountRefunds = "REFUNDS"
+)
+
+// ledgerLine : posting before account opened
+type ledgerLine struct {
+	kind   string
+	owner  string
+	debit  int64
+	credit int64
+}
+
+// LedgerReport : result of invariant check, balanced if both Unbalanced and Mismatched are empty
+type LedgerReport struct {
+	Totals     []*model.LedgerTotal
+	Unbalanced []*model.LedgerTotal
+	Mismatched []*model.LedgerTotal
+}
+
+// Balanced
+func (r *LedgerReport) Balanced() bool {
+	return len(r.Unbalanced) == 0 && len(r.Mismatched) == 0
+}
+
+type Ledger struct{}
+
+func NewLedger() *Ledger {
+	return new(Ledger)
+}
+
+/* {{{ [Methods] */
+
+// Record : journals status change of transaction from the given status, refund set if the change made by it.
+// Must run in the database transaction of the change
+func (s *Ledger) Record(ctx context.Context, transaction *model.Transaction, from string, refund *model.Refund) error {
+	entry := &model.LedgerEntry{
+		Transaction: transaction.ID,
+		FromStatus:  from,
+		ToStatus:    transaction.Status,
+		Currency:    transaction.Currency,
+	}
+	if refund != nil {
+		entry.Refund = refund.ID
+	}
+
+	for _, line := range ledgerLines(transaction, refund) {
+		account := &model.LedgerAccount{
+			Kind:     line.kind,
+			Owner:    line.owner,
+			Currency: transaction.Currency,
+		}
+
+		err := account.Open(ctx)
+		if err != nil {
+			return err
+		}
+
+		entry.Postings = append(entry.Postings, &model.LedgerPosting{
+			Account: account.ID,
+			Debit:   line.debit,
+			Credit:  line.credit,
+		})
+	}
+
+	return entry.Create(ctx)
+}
+
+// Check : verifies debits equal credits of every entry and of every currency
+func (s *Ledger) Check(ctx context.Context) (*LedgerReport, error) {
+	posting := new(model.LedgerPosting)
+	unbalanced, err := posting.Unbalanced(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	totals, err := posting.Totals(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &LedgerReport{
+		Totals:     totals,
+		Unbalanced: unbalanced,
+	}
+	for _, total := range totals {
+		if total.Debit != total.Credit {
+			report.Mismatched = append(report.Mismatched, total)
+		}
+	}
+
+	return report, nil
+}
+
+/* }}} */
+
+// Postings of status change, money moves on confirmation and refunds only
+func ledgerLines(transaction *model.Transaction, refund *model.Refund) []*ledgerLine {
+	if refund != nil {
+		return []*ledgerLine{
+			{kind: LedgerAccountRefunds, owner: transaction.Tenant, debit: refund.Amount},
+			{kind: LedgerAccountClientFunding, owner: transaction.Client, credit: refund.Amount},
+		}
+	}
+
+	if transaction.Status != TransactionStatusComfirmed {
+		return nil
+	}
+
+	lines := []*ledgerLine{
+		{kind: LedgerAccountClientFunding, owner: transaction.Client, debit: transaction.Amount},
+		{kind: LedgerAccountTenantReceivable, owner: transaction.Tenant, credit: transaction.Amount},
+	}
+
+	// Fee charged at confirmation, the same one settled
+	fee := transaction.Fee
+	if fee > 0 {
+		lines = append(lines,
+			&ledgerLine{kind: LedgerAccountTenantReceivable, owner: transaction.Tenant, debit: fee},
+			&ledgerLine{kind: LedgerAccountFees, credit: fee},
+		)
+	}
+
+	return lines
+}
+
+/*
+ * Local variables:
+ * tab-width: 4
+ * c-basic-offset: 4
+ * End:
+ * vim600: sw=4 ts=4 fdm=marker
+ * vim<600: sw=4 ts=4
+ */
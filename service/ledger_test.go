@@ -0,0 +1,109 @@
+/*
+ * Copyright (C) HereweTech, Inc - All Rights Reserved
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+/**
+ * @file ledger_test.go
+ * @package service
+ * @author Dr.NP <np@herewe.tech>
+ * @since 10/18/2026
+ */
+
+package service
+
+import (
+	"icepay-svc/model"
+	"icepay-svc/runtime"
+	"testing"
+)
+
+func TestLedgerLinesBalanced(t *testing.T) {
+	runtime.Config.Settlement.FeeRate = 60
+	runtime.Config.Settlement.FeeFixed = map[string]int64{"cny": 10, "jpy": 1}
+
+	transaction := &model.Transaction{
+		ID:       "t1",
+		Client:   "c1",
+		Tenant:   "m1",
+		Amount:   10000,
+		Currency: "CNY",
+	}
+	transaction.Fee = Fee(transaction.Amount, transaction.Currency)
+
+	// Fees changed after confirmation, the stored one still posted
+	runtime.Config.Settlement.FeeRate = 0
+
+	// Balances by kind over the whole lifecycle
+	balances := make(map[string]int64)
+	post := func(status string, refund *model.Refund) {
+		transaction.Status = status
+		var debit, credit int64
+		for _, line := range ledgerLines(transaction, refund) {
+			debit += line.debit
+			credit += line.credit
+			balances[line.kind] += line.debit - line.credit
+		}
+
+		if debit != credit {
+			t.Fatalf("entry to [%s] unbalanced : debit %d, credit %d", status, debit, credit)
+		}
+	}
+
+	post(TransactionStatusCreated, nil)
+	if len(balances) != 0 {
+		t.Fatalf("created transaction moved money : %v", balances)
+	}
+
+	post(TransactionStatusComfirmed, nil)
+	post(TransactionStatusPartiallyRefunded, &model.Refund{Amount: 3000})
+	post(TransactionStatusRefunded, &model.Refund{Amount: 7000})
+
+	fee := transaction.Fee
+	if fee != 70 {
+		t.Fatalf("fee mismatch : %d", fee)
+	}
+
+	want := map[string]int64{
+		LedgerAccountClientFunding:    0,
+		LedgerAccountTenantReceivable: -(transaction.Amount - fee),
+		LedgerAccountFees:             -fee,
+		LedgerAccountRefunds:          transaction.Amount,
+	}
+	for kind, balance := range want {
+		if balances[kind] != balance {
+			t.Fatalf("balance of [%s] mismatch : got %d, want %d", kind, balances[kind], balance)
+		}
+	}
+}
+
+func TestFeeByCurrency(t *testing.T) {
+	runtime.Config.Settlement.FeeRate = 60
+	runtime.Config.Settlement.FeeFixed = map[string]int64{"usd": 10, "jpy": 10}
+	cases := []struct {
+		amount   int64
+		currency string
+		fee      int64
+	}{
+		{10000, "USD", 70},
+		{10000, "JPY", 70},
+		{10000, "CNY", 60},
+		{83, "USD", 10},
+	}
+	for _, c := range cases {
+		fee := Fee(c.amount, c.currency)
+		if fee != c.fee {
+			t.Errorf("fee of %d %s = %d, want %d", c.amount, c.currency, fee, c.fee)
+		}
+	}
+}
+
+/*
+ * Local variables:
+ * tab-width: 4
+ * c-basic-offset: 4
+ * End:
+ * vim600: sw=4 ts=4 fdm=marker
+ * vim<600: sw=4 ts=4
+ */
@@ -0,0 +1,151 @@
+/*
+ * Copyright (C) HereweTech, Inc - All Rights Reserved
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+/**
+ * @file rbac.go
+ * @package service
+ * @author Dr.NP <np@herewe.tech>
+ * @since 10/18/2026
+ */
+
+package service
+
+import "strings"
+
+const (
+	RoleClient         = "client"
+	RoleTenantOwner    = "tenant-owner"
+	RoleTenantCashier  = "tenant-cashier"
+	RoleTenantReadonly = "tenant-readonly"
+	RoleTenantAPI      = "tenant-api" // API keys of tenant, for server-to-server calls
+)
+
+const (
+	ScopeAccountRead     = "account:read"
+	ScopeAccountWrite    = "account:write"
+	ScopeCredentialIssue = "credential:issue"
+	ScopeCardRead        = "card:read"
+	ScopeCardWrite       = "card:write"
+	ScopePaymentCreate   = "payment:create"
+	ScopePaymentPay      = "payment:pay"
+	ScopePaymentRead     = "payment:read"
+	ScopePaymentRefund   = "payment:refund"
+	ScopeWebhookRead     = "webhook:read"
+	ScopeWebhookWrite    = "webhook:write"
+	ScopeSettlementRead  = "settlement:read"
+	ScopeStaffRead       = "staff:read"
+	ScopeStaffWrite      = "staff:write"
+	ScopeTerminalRead    = "terminal:read"
+	ScopeTerminalWrite   = "terminal:write"
+	ScopeAPIKeyRead      = "apikey:read"
+	ScopeAPIKeyWrite     = "apikey:write"
+)
+
+// Scopes granted to roles
+var roleScopes = map[string][]string{
+	RoleClient: {
+		ScopeAccountRead,
+		ScopeAccountWrite,
+		ScopeCredentialIssue,
+		ScopeCardRead,
+		ScopeCardWrite,
+		ScopePaymentPay,
+		ScopePaymentRead,
+	},
+	RoleTenantOwner: {
+		ScopeAccountRead,
+		ScopeAccountWrite,
+		ScopeCardRead,
+		ScopeCardWrite,
+		ScopePaymentCreate,
+		ScopePaymentRead,
+		ScopePaymentRefund,
+		ScopeWebhookRead,
+		ScopeWebhookWrite,
+		ScopeSettlementRead,
+		ScopeStaffRead,
+		ScopeStaffWrite,
+		ScopeTerminalRead,
+		ScopeTerminalWrite,
+		ScopeAPIKeyRead,
+		ScopeAPIKeyWrite,
+	},
+	RoleTenantCashier: {
+		ScopeAccountRead,
+		ScopePaymentCreate,
+		ScopePaymentRead,
+	},
+	RoleTenantReadonly: {
+		ScopeAccountRead,
+		ScopeCardRead,
+		ScopePaymentRead,
+		ScopeWebhookRead,
+		ScopeSettlementRead,
+		ScopeStaffRead,
+		ScopeTerminalRead,
+		ScopeAPIKeyRead,
+	},
+	RoleTenantAPI: {
+		ScopePaymentCreate,
+		ScopePaymentRead,
+		ScopePaymentRefund,
+	},
+}
+
+/* {{{ [Methods] */
+
+// RoleScopes : scopes granted to role, nil for unknown ones
+func RoleScopes(role string) []string {
+	return roleScopes[role]
+}
+
+// DefaultRole : role of subject type, for accounts logging in by themselves and tokens issued without role
+func DefaultRole(subjectType string) string {
+	switch subjectType {
+	case "client":
+		return RoleClient
+	case "tenant":
+		return RoleTenantOwner
+	}
+
+	return ""
+}
+
+// ParseScope : space-delimited scope claim (RFC 8693) => scopes
+func ParseScope(scope string) []string {
+	return strings.Fields(scope)
+}
+
+// HasScopes tells whether all required scopes granted
+func HasScopes(granted []string, required ...string) bool {
+	for _, r := range required {
+		found := false
+		for _, g := range granted {
+			if g == r {
+				found = true
+
+				break
+			}
+		}
+
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+/* }}} */
+
+/*
+ * Local variables:
+ * tab-width: 4
+ * c-basic-offset: 4
+ * End:
+ * vim600: sw=4 ts=4 fdm=marker
+ * vim<600: sw=4 ts=4
+ */
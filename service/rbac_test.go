@@ -0,0 +1,77 @@
+/*
+ * Copyright (C) HereweTech, Inc - All Rights Reserved
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+/**
+ * @file rbac_test.go
+ * @package service
+ * @author Dr.NP <np@herewe.tech>
+ * @since 10/18/2026
+ */
+
+package service
+
+import (
+	"icepay-svc/runtime"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+func TestRoleScopes(t *testing.T) {
+	cases := []struct {
+		role     string
+		required []string
+		want     bool
+	}{
+		{RoleTenantCashier, []string{ScopePaymentCreate}, true},
+		{RoleTenantCashier, []string{ScopeSettlementRead}, false},
+		{RoleTenantCashier, []string{ScopePaymentCreate, ScopePaymentRefund}, false},
+		{RoleTenantReadonly, []string{ScopePaymentRead, ScopeSettlementRead}, true},
+		{RoleTenantReadonly, []string{ScopeWebhookWrite}, false},
+		{RoleTenantOwner, []string{ScopePaymentPay}, false},
+		{RoleClient, []string{ScopePaymentPay, ScopeCredentialIssue}, true},
+		{"unknown", []string{ScopeAccountRead}, false},
+	}
+	for _, c := range cases {
+		if got := HasScopes(RoleScopes(c.role), c.required...); got != c.want {
+			t.Errorf("%s has %v = %v, want %v", c.role, c.required, got, c.want)
+		}
+	}
+
+	// Scopes of role embedded in access token
+	runtime.Config.Auth.JWTAccessSecret = "access_secret"
+	s := new(Auth)
+	access, err := s.JWTSign(&Sign{
+		Sub:       "t1",
+		Type:      "tenant",
+		Role:      RoleTenantCashier,
+		ExpiresIn: time.Minute,
+	})
+	if err != nil {
+		t.Fatalf("sign failed : %s", err)
+	}
+
+	token, err := jwt.Parse(access.Token, s.KeyFunc)
+	if err != nil {
+		t.Fatalf("parse failed : %s", err)
+	}
+
+	claims := token.Claims.(jwt.MapClaims)
+	scope, _ := claims["scope"].(string)
+	if claims["role"] != RoleTenantCashier || !HasScopes(ParseScope(scope), RoleScopes(RoleTenantCashier)...) {
+		t.Errorf("unexpected claims %v", claims)
+	}
+}
+
+/*
+ * Local variables:
+ * tab-width: 4
+ * c-basic-offset: 4
+ * End:
+ * vim600: sw=4 ts=4 fdm=marker
+ * vim<600: sw=4 ts=4
+ */
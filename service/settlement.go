@@ -0,0 +1,240 @@
+/*
+ * Copyright (C) HereweTech, Inc - All Rights Reserved
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+/**
+ * @file settlement.go
+ * @package service
+ * @author Dr.NP <np@herewe.tech>
+ * @since 10/18/2026
+ */
+
+package service
+
+import (
+	"context"
+	"errors"
+	"icepay-svc/model"
+	"icepay-svc/runtime"
+	"strings"
+	"time"
+)
+
+const (
+	SettlementItemPayment = "PAYMENT"
+	SettlementItemRefund  = "REFUND"
+
+	SettlementDateLayout = "2006-01-02"
+)
+
+var (
+	ErrSettlementDayNotEnded = errors.New("Settlement day not ended")
+)
+
+// Statuses of transactions once confirmed
+var settledStatuses = []string{
+	TransactionStatusComfirmed,
+	TransactionStatusPartiallyRefunded,
+	TransactionStatusRefunded,
+}
+
+type Settlement struct {
+	location *time.Location
+}
+
+func NewSettlement() *Settlement {
+	s := new(Settlement)
+	location, err := time.LoadLocation(runtime.Config.Settlement.Timezone)
+	if err != nil {
+		runtime.Logger.Fatalf("settlement timezone [%s] load failed : %s", runtime.Config.Settlement.Timezone, err)
+	}
+
+	s.location = location
+
+	return s
+}
+
+/* {{{ [Methods] */
+
+// Day returns start of day of date in settlement timezone
+func (s *Settlement) Day(date time.Time) time.Time {
+	y, m, d := date.In(s.location).Date()
+
+	return time.Date(y, m, d, 0, 0, 0, 0, s.location)
+}
+
+// ParseDate : YYYY-MM-DD in settlement timezone
+func (s *Settlement) ParseDate(date string) (time.Time, error) {
+	return time.ParseInLocation(SettlementDateLayout, date, s.location)
+}
+
+// Close : aggregates payments confirmed and refunds made on the day into batches per tenant per currency.
+// Batches closed before are kept as is, only the new ones returned
+func (s *Settlement) Close(ctx context.Context, date time.Time) ([]*model.Settlement, error) {
+	from := s.Day(date)
+	to := from.AddDate(0, 0, 1)
+	if time.Now().Before(to) {
+		return nil, ErrSettlementDayNotEnded
+	}
+
+	var closed []*model.Settlement
+	err := runtime.RunInTx(ctx, func(ctx context.Context) error {
+		transactions, err := new(model.Transaction).ListConfirmed(ctx, settledStatuses, from, to)
+		if err != nil {
+			return err
+		}
+
+		refunds, err := new(model.Refund).ListCreated(ctx, from, to)
+		if err != nil {
+			return err
+		}
+
+		type batchKey struct {
+			tenant   string
+			currency string
+		}
+
+		var keys []batchKey
+		batches := make(map[batchKey]*model.Settlement)
+		items := make(map[batchKey][]*model.SettlementItem)
+		batch := func(tenant, currency string) (batchKey, *model.Settlement) {
+			key := batchKey{tenant: tenant, currency: currency}
+			if batches[key] == nil {
+				keys = append(keys, key)
+				batches[key] = &model.Settlement{
+					Tenant:   tenant,
+					Currency: currency,
+					Date:     from,
+				}
+			}
+
+			return key, batches[key]
+		}
+
+		for _, transaction := range transactions {
+			key, settlement := batch(transaction.Tenant, transaction.Currency)
+			fee := transaction.Fee
+			settlement.Payments++
+			settlement.Gross += transaction.Amount
+			settlement.Fees += fee
+			items[key] = append(items[key], &model.SettlementItem{
+				Tenant:      transaction.Tenant,
+				Kind:        SettlementItemPayment,
+				Reference:   transaction.ID,
+				Transaction: transaction.ID,
+				Amount:      transaction.Amount,
+				Fee:         fee,
+				Net:         transaction.Amount - fee,
+				OccurredAt:  transaction.ConfirmedAt,
+			})
+		}
+
+		for _, refund := range refunds {
+			key, settlement := batch(refund.Tenant, refund.Currency)
+			settlement.RefundCount++
+			settlement.Refunds += refund.Amount
+			items[key] = append(items[key], &model.SettlementItem{
+				Tenant:      refund.Tenant,
+				Kind:        SettlementItemRefund,
+				Reference:   refund.ID,
+				Transaction: refund.Transaction,
+				Amount:      -refund.Amount,
+				Net:         -refund.Amount,
+				OccurredAt:  refund.CreatedAt,
+			})
+		}
+
+		for _, key := range keys {
+			settlement := batches[key]
+			settlement.Net = settlement.Gross - settlement.Refunds - settlement.Fees
+			err = settlement.Create(ctx)
+			if errors.Is(err, model.ErrSettlementExists) {
+				runtime.Logger.Warnf("settlement of tenant [%s] in [%s] on %s closed before, skipped", key.tenant, key.currency, from.Format(SettlementDateLayout))
+
+				continue
+			}
+
+			if err != nil {
+				return err
+			}
+
+			for _, item := range items[key] {
+				item.Settlement = settlement.ID
+				err = item.Create(ctx)
+				if err != nil {
+					return err
+				}
+			}
+
+			closed = append(closed, settlement)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return closed, nil
+}
+
+// Get
+func (s *Settlement) Get(ctx context.Context, input *model.Settlement) (*model.Settlement, error) {
+	settlement := &model.Settlement{
+		ID:     input.ID,
+		Tenant: input.Tenant,
+	}
+
+	err := settlement.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return settlement, nil
+}
+
+// List : batches of tenant in date range, inclusive
+func (s *Settlement) List(ctx context.Context, input *model.Settlement, from, to time.Time) ([]*model.Settlement, error) {
+	settlement := &model.Settlement{
+		Tenant:   input.Tenant,
+		Currency: input.Currency,
+	}
+
+	return settlement.List(ctx, from, to)
+}
+
+// Items : statement lines of batch
+func (s *Settlement) Items(ctx context.Context, input *model.Settlement) ([]*model.SettlementItem, error) {
+	item := &model.SettlementItem{
+		Settlement: input.ID,
+		Tenant:     input.Tenant,
+	}
+
+	return item.List(ctx)
+}
+
+/* }}} */
+
+// Fee of payment : rate in basis point rounded half up, plus the fixed part of currency
+func Fee(amount int64, currency string) int64 {
+	fee := (amount*runtime.Config.Settlement.FeeRate + 5000) / 10000
+	for k, v := range runtime.Config.Settlement.FeeFixed {
+		// Keys lowercased by config loader
+		if strings.EqualFold(k, currency) {
+			fee += v
+		}
+	}
+
+	return fee
+}
+
+/*
+ * Local variables:
+ * tab-width: 4
+ * c-basic-offset: 4
+ * End:
+ * vim600: sw=4 ts=4 fdm=marker
+ * vim<600: sw=4 ts=4
+ */
@@ -0,0 +1,206 @@
+/*
+ * Copyright (C) HereweTech, Inc - All Rights Reserved
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+/**
+ * @file staff.go
+ * @package service
+ * @author Dr.NP <np@herewe.tech>
+ * @since 10/18/2026
+ */
+
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"icepay-svc/model"
+	"icepay-svc/runtime"
+	"icepay-svc/utils"
+	"net/mail"
+	"strings"
+	"time"
+)
+
+const (
+	StaffStatusInvited  = "INVITED"
+	StaffStatusActive   = "ACTIVE"
+	StaffStatusDisabled = "DISABLED"
+)
+
+var (
+	ErrStaffInvalidEmail      = errors.New("Invalid email of staff")
+	ErrStaffInvalidRole       = errors.New("Role not grantable to staff")
+	ErrStaffInvalidPassword   = errors.New("Invalid password")
+	ErrStaffWrongPassword     = errors.New("Wrong password")
+	ErrStaffInvitationInvalid = errors.New("Invitation invalid or expired")
+)
+
+// Roles granted to staff, owners of tenant sign in as the tenant itself
+var staffRoles = map[string]bool{
+	RoleTenantCashier:  true,
+	RoleTenantReadonly: true,
+}
+
+type Staff struct{}
+
+func NewStaff() *Staff {
+	s := new(Staff)
+
+	return s
+}
+
+/* {{{ [Methods] */
+
+// Invite : creates staff of tenant with invitation code, returned only once.
+// The code is handed to the staff by tenant, and exchanged for a password by Accept
+func (s *Staff) Invite(ctx context.Context, input *model.Staff) (*model.Staff, string, error) {
+	email := strings.ToLower(strings.TrimSpace(input.Email))
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email {
+		return nil, "", ErrStaffInvalidEmail
+	}
+
+	if !staffRoles[input.Role] {
+		return nil, "", ErrStaffInvalidRole
+	}
+
+	code := utils.SecureRandomString(32)
+	staff := &model.Staff{
+		Tenant:          input.Tenant,
+		Email:           email,
+		Name:            strings.TrimSpace(input.Name),
+		Role:            input.Role,
+		Status:          StaffStatusInvited,
+		InviteHash:      utils.HashSecret(code),
+		InviteExpiresAt: time.Now().Add(time.Duration(runtime.Config.Security.StaffInviteLifetime) * time.Hour),
+	}
+
+	err = staff.Create(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return staff, code, nil
+}
+
+// Accept : sets password of staff by invitation code, staff activated
+func (s *Staff) Accept(ctx context.Context, code, password string) (*model.Staff, error) {
+	if password == "" {
+		return nil, ErrStaffInvalidPassword
+	}
+
+	staff := &model.Staff{
+		InviteHash: utils.HashSecret(code),
+		Status:     StaffStatusInvited,
+	}
+
+	err := staff.Get(ctx)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && staff.InviteExpiresAt.Before(time.Now())) {
+		return nil, ErrStaffInvitationInvalid
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	staff.Salt = utils.RandomString(32)
+	staff.Password = password
+	err = staff.Activate(ctx, StaffStatusActive)
+	if errors.Is(err, model.ErrStaffDoesNotExists) {
+		// Disabled meanwhile
+		return nil, ErrStaffInvitationInvalid
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return s.Get(ctx, &model.Staff{ID: staff.ID})
+}
+
+// Authenticate : active staff of email and password
+func (s *Staff) Authenticate(ctx context.Context, email, password string) (*model.Staff, error) {
+	staff := &model.Staff{
+		Email:  strings.ToLower(strings.TrimSpace(email)),
+		Status: StaffStatusActive,
+	}
+
+	err := staff.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if utils.EncryptPassword(password, staff.Salt, staff.Email) != staff.Password {
+		return nil, ErrStaffWrongPassword
+	}
+
+	return staff, nil
+}
+
+// Get
+func (s *Staff) Get(ctx context.Context, input *model.Staff) (*model.Staff, error) {
+	staff := &model.Staff{
+		ID:     input.ID,
+		Tenant: input.Tenant,
+	}
+
+	err := staff.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return staff, nil
+}
+
+// List
+func (s *Staff) List(ctx context.Context, input *model.Staff) ([]*model.Staff, error) {
+	staff := &model.Staff{
+		Tenant: input.Tenant,
+	}
+
+	return staff.List(ctx)
+}
+
+// Disable : disables staff of tenant, sessions of the staff revoked
+func (s *Staff) Disable(ctx context.Context, input *model.Staff) (*model.Staff, error) {
+	staff := &model.Staff{
+		ID:     input.ID,
+		Tenant: input.Tenant,
+	}
+
+	err := runtime.RunInTx(ctx, func(ctx context.Context) error {
+		err := staff.Disable(ctx, StaffStatusDisabled)
+		if err != nil {
+			return err
+		}
+
+		session := &model.Session{
+			Subject:       staff.Tenant,
+			SubjectType:   "tenant",
+			Staff:         staff.ID,
+			RevokedReason: SessionRevokedDisabled,
+		}
+
+		return session.RevokeAll(ctx)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.Get(ctx, input)
+}
+
+/* }}} */
+
+/*
+ * Local variables:
+ * tab-width: 4
+ * c-basic-offset: 4
+ * End:
+ * vim600: sw=4 ts=4 fdm=marker
+ * vim<600: sw=4 ts=4
+ */
@@ -0,0 +1,61 @@
+/*
+ * Copyright (C) HereweTech, Inc - All Rights Reserved
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+/**
+ * @file staff_test.go
+ * @package service
+ * @author Dr.NP <np@herewe.tech>
+ * @since 10/18/2026
+ */
+
+package service
+
+import (
+	"context"
+	"errors"
+	"icepay-svc/model"
+	"testing"
+)
+
+func TestStaffInviteRejected(t *testing.T) {
+	cases := []struct {
+		email string
+		role  string
+		want  error
+	}{
+		{"cashier", RoleTenantCashier, ErrStaffInvalidEmail},
+		{"Cashier <cashier@example.com>", RoleTenantCashier, ErrStaffInvalidEmail},
+		{"cashier@example.com", RoleTenantOwner, ErrStaffInvalidRole},
+		{"cashier@example.com", "platform-admin", ErrStaffInvalidRole},
+		{"cashier@example.com", "", ErrStaffInvalidRole},
+	}
+
+	s := NewStaff()
+	for _, c := range cases {
+		_, _, err := s.Invite(context.Background(), &model.Staff{
+			Tenant: "t1",
+			Email:  c.email,
+			Role:   c.role,
+		})
+		if !errors.Is(err, c.want) {
+			t.Errorf("invite %q as %q = %v, want %v", c.email, c.role, err, c.want)
+		}
+	}
+
+	// Terminals act as cashiers, not managing the tenant
+	if HasScopes(RoleScopes(RoleTerminal), ScopeStaffWrite) || !HasScopes(RoleScopes(RoleTerminal), ScopePaymentCreate) {
+		t.Errorf("unexpected scopes of terminal %v", RoleScopes(RoleTerminal))
+	}
+}
+
+/*
+ * Local variables:
+ * tab-width: 4
+ * c-basic-offset: 4
+ * End:
+ * vim600: sw=4 ts=4 fdm=marker
+ * vim<600: sw=4 ts=4
+ */
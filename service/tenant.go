@@ -0,0 +1,124 @@
+/*
+ * Copyright (C) HereweTech, Inc - All Rights Reserved
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+/**
+ * @file tenant.go
+ * @package service
+ * @author Dr.NP <np@herewe.tech>
+ * @since 10/18/2026
+ */
+
+package service
+
+import (
+	"context"
+	"errors"
+	"icepay-svc/model"
+	"icepay-svc/runtime"
+	"icepay-svc/utils"
+	"strings"
+	"time"
+)
+
+var (
+	ErrTenantWrongPassword   = errors.New("Wrong password")
+	ErrTenantInvalidPassword = errors.New("Invalid password")
+)
+
+type Tenant struct{}
+
+func NewTenant() *Tenant {
+	s := new(Tenant)
+
+	return s
+}
+
+/* {{{ [Methods] */
+
+// Get
+func (s *Tenant) Get(ctx context.Context, input *model.Tenant) (*model.Tenant, error) {
+	tnt := &model.Tenant{
+		ID: input.ID,
+	}
+
+	err := tnt.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return tnt, nil
+}
+
+// Update profile (name, phone & accepted currencies). Currencies replaced if not nil, empty ones accept the default currency
+func (s *Tenant) Update(ctx context.Context, input *model.Tenant) (*model.Tenant, error) {
+	tnt := &model.Tenant{
+		ID:    input.ID,
+		Name:  strings.TrimSpace(input.Name),
+		Phone: strings.TrimSpace(input.Phone),
+	}
+
+	if input.Currencies != nil {
+		currencies, err := currencyCodes(input.Currencies)
+		if err != nil {
+			return nil, err
+		}
+
+		tnt.Currencies = currencies
+	}
+
+	if tnt.Name != "" || tnt.Phone != "" || tnt.Currencies != nil {
+		err := tnt.Update(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return s.Get(ctx, input)
+}
+
+// ChangePassword : checks old password, re-salts and stores the new one. Sessions issued before are revoked
+func (s *Tenant) ChangePassword(ctx context.Context, id, oldPassword, newPassword string) error {
+	if newPassword == "" {
+		return ErrTenantInvalidPassword
+	}
+
+	tnt, err := s.Get(ctx, &model.Tenant{ID: id})
+	if err != nil {
+		return err
+	}
+
+	if tnt.Password != model.PasswordNotSet && utils.EncryptPassword(oldPassword, tnt.Salt, tnt.Email) != tnt.Password {
+		return ErrTenantWrongPassword
+	}
+
+	upd := &model.Tenant{
+		ID:                tnt.ID,
+		Email:             tnt.Email,
+		Salt:              utils.RandomString(32),
+		Password:          newPassword,
+		PasswordChangedAt: time.Now(),
+	}
+
+	return runtime.RunInTx(ctx, func(ctx context.Context) error {
+		err := upd.Update(ctx)
+		if err != nil {
+			return err
+		}
+
+		return RevokeSessions(ctx, upd.ID, "tenant", SessionRevokedPassword)
+	})
+}
+
+/* }}} */
+
+/*
+ * Local variables:
+ * tab-width: 4
+ * c-basic-offset: 4
+ * End:
+ * vim600: sw=4 ts=4 fdm=marker
+ * vim<600: sw=4 ts=4
+ */
@@ -0,0 +1,156 @@
+/*
+ * Copyright (C) HereweTech, Inc - All Rights Reserved
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+/**
+ * @file terminal.go
+ * @package service
+ * @author Dr.NP <np@herewe.tech>
+ * @since 10/18/2026
+ */
+
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"icepay-svc/model"
+	"icepay-svc/runtime"
+	"icepay-svc/utils"
+	"strings"
+)
+
+const (
+	// Device keys are prefixed, telling them apart from other secrets when leaked
+	terminalKeyPrefix = "tmk_"
+	terminalKeyLength = 40
+
+	// Terminals create and view payments, acting as cashiers
+	RoleTerminal = RoleTenantCashier
+)
+
+var (
+	ErrTerminalKeyInvalid = errors.New("Invalid or disabled device key")
+)
+
+type Terminal struct{}
+
+func NewTerminal() *Terminal {
+	s := new(Terminal)
+
+	return s
+}
+
+/* {{{ [Methods] */
+
+// Register : registers POS terminal of tenant with a new device key, returned only once
+func (s *Terminal) Register(ctx context.Context, input *model.Terminal) (*model.Terminal, string, error) {
+	key := terminalKeyPrefix + utils.SecureRandomString(terminalKeyLength)
+	terminal := &model.Terminal{
+		Tenant:    input.Tenant,
+		Label:     strings.TrimSpace(input.Label),
+		KeyHash:   utils.HashSecret(key),
+		KeyPrefix: key[:len(terminalKeyPrefix)+4],
+	}
+
+	err := terminal.Create(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return terminal, key, nil
+}
+
+// Authenticate : enabled terminal of device key, the time of use recorded
+func (s *Terminal) Authenticate(ctx context.Context, key string) (*model.Terminal, error) {
+	if !strings.HasPrefix(key, terminalKeyPrefix) {
+		return nil, ErrTerminalKeyInvalid
+	}
+
+	terminal := &model.Terminal{
+		KeyHash: utils.HashSecret(key),
+	}
+
+	err := terminal.Get(ctx)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && !terminal.DisabledAt.IsZero()) {
+		return nil, ErrTerminalKeyInvalid
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = terminal.Touch(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return terminal, nil
+}
+
+// Get
+func (s *Terminal) Get(ctx context.Context, input *model.Terminal) (*model.Terminal, error) {
+	terminal := &model.Terminal{
+		ID:     input.ID,
+		Tenant: input.Tenant,
+	}
+
+	err := terminal.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return terminal, nil
+}
+
+// List
+func (s *Terminal) List(ctx context.Context, input *model.Terminal) ([]*model.Terminal, error) {
+	terminal := &model.Terminal{
+		Tenant: input.Tenant,
+	}
+
+	return terminal.List(ctx)
+}
+
+// Disable : disables terminal of tenant, sessions of the terminal revoked
+func (s *Terminal) Disable(ctx context.Context, input *model.Terminal) (*model.Terminal, error) {
+	terminal := &model.Terminal{
+		ID:     input.ID,
+		Tenant: input.Tenant,
+	}
+
+	err := runtime.RunInTx(ctx, func(ctx context.Context) error {
+		err := terminal.Disable(ctx)
+		if err != nil {
+			return err
+		}
+
+		session := &model.Session{
+			Subject:       terminal.Tenant,
+			SubjectType:   "tenant",
+			Terminal:      terminal.ID,
+			RevokedReason: SessionRevokedDisabled,
+		}
+
+		return session.RevokeAll(ctx)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.Get(ctx, input)
+}
+
+/* }}} */
+
+/*
+ * Local variables:
+ * tab-width: 4
+ * c-basic-offset: 4
+ * End:
+ * vim600: sw=4 ts=4 fdm=marker
+ * vim<600: sw=4 ts=4
+ */
@@ -15,11 +15,16 @@ package service
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"icepay-svc/model"
 	"icepay-svc/runtime"
+	"strings"
 	"time"
+
+	"github.com/nats-io/nats.go"
 )
 
 const (
@@ -29,30 +34,275 @@ const (
 	TransactionStatusAborted   = "ABORTED"
 	TransactionStatusClosed    = "CLOSED"
 	TransactionStatusInvalid   = "INVALID"
+
+	TransactionStatusRefunded          = "REFUNDED"
+	TransactionStatusPartiallyRefunded = "PARTIALLY_REFUNDED"
+)
+
+var (
+	ErrRefundInvalidAmount = errors.New("Invalid refund amount")
+	ErrInvalidAmount       = errors.New("Invalid amount, positive required")
+	ErrInvalidCursor       = errors.New("Invalid cursor")
+	ErrInvalidDevice       = errors.New("Invalid device, 1 to 64 letters, digits, - or _ required")
+	ErrTooManyDevices      = errors.New("Too many devices waiting for the subscriber")
+)
+
+// Allowed status transitions, statuses not listed here are final
+var transactionTransitions = map[string][]string{
+	TransactionStatusPreCreate: {
+		TransactionStatusCreated,
+		TransactionStatusAborted,
+		TransactionStatusClosed,
+		TransactionStatusInvalid,
+	},
+	TransactionStatusCreated: {
+		TransactionStatusComfirmed,
+		TransactionStatusAborted,
+		TransactionStatusClosed,
+		TransactionStatusInvalid,
+	},
+	TransactionStatusComfirmed: {
+		TransactionStatusPartiallyRefunded,
+		TransactionStatusRefunded,
+	},
+	TransactionStatusPartiallyRefunded: {
+		TransactionStatusPartiallyRefunded,
+		TransactionStatusRefunded,
+	},
+}
+
+// TransitionError : transaction can not move from its current status to the target one
+type TransitionError struct {
+	ID   string
+	From string
+	To   string
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("transaction [%s] can not transit from [%s] to [%s]", e.ID, e.From, e.To)
+}
+
+// CanTransit checks status transition table
+func CanTransit(from, to string) bool {
+	for _, status := range transactionTransitions[from] {
+		if status == to {
+			return true
+		}
+	}
+
+	return false
+}
+
+const (
+	// Max transactions expired in one sweep
+	expireBatchSize = 100
+
+	// Max events returned by one wait
+	waitBatchSize = 32
+
+	// Events following the first one returned by the same wait if they come within it
+	waitFollowTimeout = 50 * time.Millisecond
+
+	// Durable consumers of devices idle longer removed by server, if nats.consumer_inactive not given
+	defaultConsumerInactive = 24 * time.Hour
+
+	// Page size of lists
+	defaultListLimit = 20
+	maxListLimit     = 100
+)
+
+const (
+	EventPaymentStatus  = "payment.status"
+	EventCredentialUsed = "credential.used"
+
+	// Header of stream message, payment status if absent
+	eventHeader = "Icepay-Event"
 )
 
-type Transaction struct{}
+// PaymentEvent : event read from stream, Seq is the stream sequence used as cursor.
+// Transaction set for payment status, Credential for credential used
+type PaymentEvent struct {
+	Seq         uint64
+	Event       string
+	Transaction *model.Transaction
+	Credential  *CredentialUsed
+}
+
+// EventStream : ordered events of one subscriber, never acknowledged
+type EventStream struct {
+	suber *nats.Subscription
+}
+
+// Next waits for the next event, nats.ErrTimeout returned if nothing happened in timeout
+func (e *EventStream) Next(timeout time.Duration) (*PaymentEvent, error) {
+	for {
+		msg, err := e.suber.NextMsg(timeout)
+		if err != nil {
+			return nil, err
+		}
+
+		event, err := paymentEvent(msg)
+		if err == nil {
+			return event, nil
+		}
+
+		runtime.Logger.Warnf("malformed event of subject [%s] dropped : %s", msg.Subject, err)
+	}
+}
+
+// Close removes the underlying consumer
+func (e *EventStream) Close() error {
+	return e.suber.Unsubscribe()
+}
+
+// TransactionPage : NextCursor is empty on the last page
+type TransactionPage struct {
+	List       []*model.Transaction
+	Total      int
+	NextCursor string
+}
+
+type Transaction struct {
+	svcWebhook *Webhook
+	svcLedger  *Ledger
+}
 
 func NewTransaction() *Transaction {
 	s := new(Transaction)
+	s.svcWebhook = NewWebhook()
+	s.svcLedger = NewLedger()
 
 	return s
 }
 
 /* {{{ [Methods] */
 
-// Create
+// Create : currency must be accepted by tenant and settled by any card of client. Staff and terminal of tenant creating it recorded
 func (s *Transaction) Create(ctx context.Context, input *model.Transaction) (*model.Transaction, error) {
+	// Money of others flows backwards
+	if input.Amount <= 0 {
+		return nil, ErrInvalidAmount
+	}
+
+	currency, err := currencyCode(input.Currency)
+	if err != nil {
+		return nil, err
+	}
+
 	transaction := &model.Transaction{
 		Client:   input.Client,
 		Tenant:   input.Tenant,
 		Amount:   input.Amount,
-		Currency: input.Currency,
+		Currency: currency,
 		Status:   TransactionStatusCreated,
 		Detail:   input.Detail,
+		Staff:    input.Staff,
+		Terminal: input.Terminal,
 	}
 
-	err := transaction.Create(ctx)
+	err = runtime.RunInTx(ctx, func(ctx context.Context) error {
+		err := acceptCurrency(ctx, transaction.Tenant, transaction.Currency)
+		if err != nil {
+			return err
+		}
+
+		err = settleCurrency(ctx, transaction.Client, transaction.Currency)
+		if err != nil {
+			return err
+		}
+
+		err = transaction.Create(ctx)
+		if err != nil {
+			return err
+		}
+
+		return s.svcLedger.Record(ctx, transaction, "", nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return transaction, nil
+}
+
+// Request : creates transaction of tenant without client, claimed later by the client who scans the request
+func (s *Transaction) Request(ctx context.Context, input *model.Transaction) (*model.Transaction, error) {
+	if input.Amount <= 0 {
+		return nil, ErrInvalidAmount
+	}
+
+	currency, err := currencyCode(input.Currency)
+	if err != nil {
+		return nil, err
+	}
+
+	transaction := &model.Transaction{
+		Tenant:   input.Tenant,
+		Amount:   input.Amount,
+		Currency: currency,
+		Status:   TransactionStatusPreCreate,
+		Detail:   input.Detail,
+		Staff:    input.Staff,
+		Terminal: input.Terminal,
+	}
+
+	err = runtime.RunInTx(ctx, func(ctx context.Context) error {
+		err := acceptCurrency(ctx, transaction.Tenant, transaction.Currency)
+		if err != nil {
+			return err
+		}
+
+		err = transaction.Create(ctx)
+		if err != nil {
+			return err
+		}
+
+		return s.svcLedger.Record(ctx, transaction, "", nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return transaction, nil
+}
+
+// Claim : binds requested transaction to client and moves it to CREATED, to be confirmed by the client.
+// Claiming again by the same client returns the transaction as is
+func (s *Transaction) Claim(ctx context.Context, input *model.Transaction) (*model.Transaction, error) {
+	transaction := &model.Transaction{
+		ID:     input.ID,
+		Tenant: input.Tenant,
+	}
+
+	err := runtime.RunInTx(ctx, func(ctx context.Context) error {
+		err := transaction.Lock(ctx)
+		if err != nil {
+			return err
+		}
+
+		if transaction.Client == input.Client && transaction.Status == TransactionStatusCreated {
+			return nil
+		}
+
+		from := transaction.Status
+		if transaction.Client != "" || !CanTransit(from, TransactionStatusCreated) {
+			return &TransitionError{ID: transaction.ID, From: from, To: TransactionStatusCreated}
+		}
+
+		err = settleCurrency(ctx, input.Client, transaction.Currency)
+		if err != nil {
+			return err
+		}
+
+		transaction.Client = input.Client
+		transaction.Status = TransactionStatusCreated
+		err = transaction.Claim(ctx, from)
+		if err != nil {
+			return err
+		}
+
+		return s.svcLedger.Record(ctx, transaction, from, nil)
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -62,26 +312,142 @@ func (s *Transaction) Create(ctx context.Context, input *model.Transaction) (*mo
 
 // Update
 func (s *Transaction) Update(ctx context.Context, input *model.Transaction) (*model.Transaction, error) {
+	status := input.Status
+	if status == "" {
+		status = TransactionStatusClosed
+	}
+
 	transaction := &model.Transaction{
 		ID:     input.ID,
 		Client: input.Client,
 		Tenant: input.Tenant,
-		Status: input.Status,
-		Card:   input.Card,
 	}
 
-	if transaction.Status == "" {
-		transaction.Status = TransactionStatusClosed
+	err := transaction.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	from := transaction.Status
+	if !CanTransit(from, status) {
+		return nil, &TransitionError{ID: transaction.ID, From: from, To: status}
 	}
 
-	err := transaction.Update(ctx)
+	transaction.Status = status
+	if input.Card != "" {
+		card := &model.Card{
+			ID: input.Card,
+		}
+
+		err = card.Get(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		if card.Currency == "" {
+			card.Currency = runtime.Config.Payment.DefaultCurrency
+		}
+
+		if card.Currency != transaction.Currency {
+			return nil, ErrCurrencyUnsettled
+		}
+
+		transaction.Card = input.Card
+	}
+
+	if status == TransactionStatusComfirmed {
+		// Settled by the day of confirmation, with the fee of the time
+		transaction.ConfirmedAt = time.Now()
+		transaction.Fee = Fee(transaction.Amount, transaction.Currency)
+	}
+
+	err = runtime.RunInTx(ctx, func(ctx context.Context) error {
+		err := transaction.Update(ctx, from)
+		if err != nil {
+			return err
+		}
+
+		return s.svcLedger.Record(ctx, transaction, from, nil)
+	})
 	if err != nil {
-		return nil, nil
+		if errors.Is(err, model.ErrTransactionStatusMismatch) {
+			// Status changed by someone else in the meantime
+			return nil, &TransitionError{ID: transaction.ID, From: from, To: status}
+		}
+
+		return nil, err
 	}
 
 	return transaction, nil
 }
 
+// Refund : refunds a confirmed transaction of tenant partially or fully (zero amount refunds the remaining)
+func (s *Transaction) Refund(ctx context.Context, input *model.Refund) (*model.Refund, *model.Transaction, error) {
+	var (
+		refund      *model.Refund
+		transaction *model.Transaction
+	)
+
+	err := runtime.RunInTx(ctx, func(ctx context.Context) error {
+		transaction = &model.Transaction{
+			ID:     input.Transaction,
+			Tenant: input.Tenant,
+		}
+
+		err := transaction.Lock(ctx)
+		if err != nil {
+			return err
+		}
+
+		remaining := transaction.Amount - transaction.Refunded
+		amount := input.Amount
+		if amount == 0 {
+			amount = remaining
+		}
+
+		if amount <= 0 || amount > remaining {
+			return ErrRefundInvalidAmount
+		}
+
+		status := TransactionStatusPartiallyRefunded
+		if amount == remaining {
+			status = TransactionStatusRefunded
+		}
+
+		from := transaction.Status
+		if !CanTransit(from, status) {
+			return &TransitionError{ID: transaction.ID, From: from, To: status}
+		}
+
+		refund = &model.Refund{
+			Transaction: transaction.ID,
+			Client:      transaction.Client,
+			Tenant:      transaction.Tenant,
+			Amount:      amount,
+			Currency:    transaction.Currency,
+			Reason:      input.Reason,
+		}
+		err = refund.Create(ctx)
+		if err != nil {
+			return err
+		}
+
+		transaction.Refunded += amount
+		transaction.Status = status
+		err = transaction.Update(ctx, from)
+		if err != nil {
+			return err
+		}
+
+		return s.svcLedger.Record(ctx, transaction, from, refund)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return refund, transaction, nil
+}
+
 // Get
 func (s *Transaction) Get(ctx context.Context, input *model.Transaction) (*model.Transaction, error) {
 	transaction := &model.Transaction{
@@ -99,62 +465,463 @@ func (s *Transaction) Get(ctx context.Context, input *model.Transaction) (*model
 	return transaction, nil
 }
 
-// List
-func (s *Transaction) List(ctx context.Context, input *model.Transaction) ([]*model.Transaction, error) {
+// List : one page of transactions matching filter, keyset paginated by cursor of the previous page
+func (s *Transaction) List(ctx context.Context, input *model.Transaction, filter *model.TransactionFilter, cursor string) (*TransactionPage, error) {
 	transaction := &model.Transaction{
 		Client: input.Client,
 		Tenant: input.Tenant,
 		Status: input.Status,
 	}
 
-	list, err := transaction.List(ctx)
+	if cursor != "" {
+		after, err := decodeCursor(cursor, filter.SortBy, filter.Desc)
+		if err != nil {
+			return nil, err
+		}
+
+		filter.After = after
+	}
+
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = defaultListLimit
+	}
+
+	if limit > maxListLimit {
+		limit = maxListLimit
+	}
+
+	// One more row tells if there is a next page
+	filter.Limit = limit + 1
+	list, total, err := transaction.List(ctx, filter)
 	if err != nil {
 		return nil, err
 	}
 
-	return list, nil
+	page := &TransactionPage{
+		List:  list,
+		Total: total,
+	}
+	if len(list) > limit {
+		page.List = list[:limit]
+		page.NextCursor = encodeCursor(page.List[limit-1], filter.SortBy, filter.Desc)
+	}
+
+	return page, nil
+}
+
+// Expire : closes transactions left unconfirmed longer than payment.transaction_ttl (counted from claim once claimed) and notifies both parties
+func (s *Transaction) Expire(ctx context.Context) error {
+	var expired []*model.Transaction
+	deadline := time.Now().Add(-time.Duration(runtime.Config.Payment.TransactionTTL) * time.Minute)
+	err := runtime.RunInTx(ctx, func(ctx context.Context) error {
+		list, err := new(model.Transaction).LockExpired(ctx, []string{TransactionStatusPreCreate, TransactionStatusCreated}, deadline, expireBatchSize)
+		if err != nil {
+			return err
+		}
+
+		for _, transaction := range list {
+			from := transaction.Status
+			transaction.Status = TransactionStatusClosed
+			err = transaction.Update(ctx, from)
+			if err != nil {
+				return err
+			}
+
+			err = s.svcLedger.Record(ctx, transaction, from, nil)
+			if err != nil {
+				return err
+			}
+		}
+
+		expired = list
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, transaction := range expired {
+		runtime.Logger.Infof("transaction [%s] expired", transaction.ID)
+		err = s.Notify(ctx, transaction)
+		if err != nil {
+			runtime.Logger.Errorf("notify expired transaction [%s] failed : %s", transaction.ID, err)
+		}
+	}
+
+	return nil
 }
 
-// Notify
+// Notify : publishes status change of transaction and dispatches webhooks of tenant.
+// Webhooks stored in database are dispatched even if publishing failed, error of publishing returned after
 func (s *Transaction) Notify(ctx context.Context, input *model.Transaction) error {
-	sub := ""
+	pubErr := s.publish(input)
+
+	// Tenant back-office, delivered by worker, should not fail the notification
+	err := s.svcWebhook.Dispatch(ctx, input)
+	if err != nil {
+		runtime.Logger.Errorf("dispatch webhooks of transaction [%s] failed : %s", input.ID, err)
+	}
+
+	return pubErr
+}
+
+// Wait : fetches up to limit events of subscriber in order, along with the cursor to wait since next time, the seq of
+// the last one returned, or the position waited from if timed out (nats.ErrTimeout).
+// A device identified keeps a durable consumer of its own, events missed between two waits returned even without
+// cursor, and since rewinds only that consumer. Others read after since, from the end of stream if zero, and should
+// carry the cursor returned every time not to miss events between waits
+func (s *Transaction) Wait(ctx context.Context, subscriber, subscriberType, device string, since uint64, limit int) ([]*PaymentEvent, uint64, error) {
+	if limit <= 0 || limit > waitBatchSize {
+		limit = waitBatchSize
+	}
+
+	if device == "" {
+		return s.waitOrdered(subscriber, subscriberType, since, limit)
+	}
+
+	if !validDevice(device) {
+		return nil, since, ErrInvalidDevice
+	}
+
+	durable := durableName(subscriberType, subscriber, device)
+	filter := subject(subscriberType, subscriber)
+	err := s.consumer(durable, filter, durableName(subscriberType, subscriber, ""), since)
+	if err != nil {
+		return nil, since, err
+	}
+
+	suber, err := runtime.JetStream.PullSubscribe(filter, durable, nats.Bind(runtime.Config.Nats.Stream, durable))
+	if err != nil {
+		return nil, since, err
+	}
+
+	// Consumer bound, not deleted by unsubscribe
+	defer suber.Unsubscribe()
+	msgs, err := suber.Fetch(limit, nats.MaxWait(time.Duration(runtime.Config.HTTP.LongPollingTimeout)*time.Second))
+	if errors.Is(err, nats.ErrTimeout) {
+		// Position of consumer, everything acknowledged
+		cursor := since
+		info, ierr := suber.ConsumerInfo()
+		if ierr == nil && info.AckFloor.Stream > cursor {
+			cursor = info.AckFloor.Stream
+		}
+
+		return nil, cursor, err
+	}
+
+	if err != nil {
+		return nil, since, err
+	}
+
+	cursor := since
+	events := make([]*PaymentEvent, 0, len(msgs))
+	for _, msg := range msgs {
+		event, err := paymentEvent(msg)
+		if err == nil {
+			events = append(events, event)
+			cursor = event.Seq
+		} else {
+			runtime.Logger.Warnf("malformed event of subject [%s] dropped : %s", msg.Subject, err)
+		}
+
+		// Clients missed the response could rewind by since cursor
+		err = msg.Ack()
+		if err != nil {
+			return nil, since, err
+		}
+	}
+
+	return events, cursor, nil
+}
+
+/* }}} */
+
+// Subscribe : streams events of subscriber after since (new ones only if zero), independent of the durable consumers of Wait
+func (s *Transaction) Subscribe(subscriber, subscriberType string, since uint64) (*EventStream, error) {
+	deliver := nats.DeliverNew()
+	if since > 0 {
+		deliver = nats.StartSequence(since + 1)
+	}
+
+	return subscribe(subject(subscriberType, subscriber), deliver)
+}
+
+// waitOrdered : waits for the first event after since, along with the ones following it at once.
+// Zero since taken as the end of stream, returned as cursor if nothing happened
+func (s *Transaction) waitOrdered(subscriber, subscriberType string, since uint64, limit int) ([]*PaymentEvent, uint64, error) {
+	if since == 0 {
+		info, err := runtime.JetStream.StreamInfo(runtime.Config.Nats.Stream)
+		if err != nil {
+			return nil, since, err
+		}
+
+		since = info.State.LastSeq
+	}
+
+	stream, err := subscribe(subject(subscriberType, subscriber), nats.StartSequence(since+1))
+	if err != nil {
+		return nil, since, err
+	}
+
+	defer stream.Close()
+	event, err := stream.Next(time.Duration(runtime.Config.HTTP.LongPollingTimeout) * time.Second)
+	if err != nil {
+		return nil, since, err
+	}
+
+	events := []*PaymentEvent{event}
+	for len(events) < limit {
+		event, err = stream.Next(waitFollowTimeout)
+		if errors.Is(err, nats.ErrTimeout) {
+			break
+		}
+
+		if err != nil {
+			return nil, since, err
+		}
+
+		events = append(events, event)
+	}
+
+	return events, events[len(events)-1].Seq, nil
+}
+
+// publish : writes transaction to stream, with subjects of subscribers interested in its status.
+// Every subject tried even if some failed, errors of all returned
+func (s *Transaction) publish(input *model.Transaction) error {
+	var subs, legacies []string
 	switch input.Status {
-	case TransactionStatusPreCreate, TransactionStatusCreated:
-		sub = "pay::client::" + input.Client
+	case TransactionStatusPreCreate:
+		subs = []string{subject("client", input.Client)}
+	case TransactionStatusCreated:
+		// Payment request claimed by client
+		subs = []string{subject("client", input.Client), subject("tenant", input.Tenant)}
 	case TransactionStatusComfirmed, TransactionStatusAborted:
-		sub = "pay::client::" + input.Tenant
+		subs = []string{subject("tenant", input.Tenant)}
+
+		// Client subject keyed by tenant, where core NATS subscribers have always been told
+		legacies = []string{legacySubject(subject("client", input.Tenant))}
+	case TransactionStatusClosed, TransactionStatusInvalid, TransactionStatusRefunded, TransactionStatusPartiallyRefunded:
+		// Payment requests never claimed have no client
+		if input.Client != "" {
+			subs = []string{subject("client", input.Client)}
+		}
+
+		subs = append(subs, subject("tenant", input.Tenant))
 	}
 
-	if sub == "" {
+	if len(subs) == 0 {
 		// Do nothing
 		return errors.New("No notification needed")
 	}
 
+	var errs []error
 	b, _ := json.Marshal(input)
+	for _, sub := range subs {
+		err := publishEvent(sub, EventPaymentStatus, b)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("publish to [%s] failed : %w", sub, err))
+		}
+
+		// Core NATS subscribers of the subjects before the stream
+		legacies = append(legacies, legacySubject(sub))
+	}
+
+	for _, sub := range legacies {
+		err := runtime.Nats.Publish(sub, b)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("publish to [%s] failed : %w", sub, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// consumer : makes sure durable consumer of subscriber exists. A since cursor other than the last acknowledged
+// sequence rewinds consumer to the event right after it. New ones refused if the subscriber has nats.max_devices
+// consumers of prefix already, idle ones removed by server after nats.consumer_inactive
+func (s *Transaction) consumer(durable, filter, prefix string, since uint64) error {
+	stream := runtime.Config.Nats.Stream
+	info, err := runtime.JetStream.ConsumerInfo(stream, durable)
+	if err != nil && !errors.Is(err, nats.ErrConsumerNotFound) {
+		return err
+	}
+
+	if info == nil && runtime.Config.Nats.MaxDevices > 0 {
+		n := 0
+		for name := range runtime.JetStream.ConsumerNames(stream) {
+			if strings.HasPrefix(name, prefix) {
+				n++
+			}
+		}
+
+		if n >= runtime.Config.Nats.MaxDevices {
+			return ErrTooManyDevices
+		}
+	}
+
+	inactive := time.Duration(runtime.Config.Nats.ConsumerInactive) * time.Hour
+	if inactive <= 0 {
+		inactive = defaultConsumerInactive
+	}
+
+	cfg := &nats.ConsumerConfig{
+		Durable:           durable,
+		FilterSubject:     filter,
+		AckPolicy:         nats.AckExplicitPolicy,
+		DeliverPolicy:     nats.DeliverNewPolicy,
+		InactiveThreshold: inactive,
+	}
+	if since > 0 {
+		if info != nil && info.AckFloor.Stream == since && info.NumAckPending == 0 {
+			// Up to date
+			return nil
+		}
+
+		if info != nil {
+			err = runtime.JetStream.DeleteConsumer(stream, durable)
+			if err != nil && !errors.Is(err, nats.ErrConsumerNotFound) {
+				return err
+			}
+		}
+
+		cfg.DeliverPolicy = nats.DeliverByStartSequencePolicy
+		cfg.OptStartSeq = since + 1
+	} else if info != nil {
+		return nil
+	}
 
-	return runtime.Nats.Publish(sub, b)
+	_, err = runtime.JetStream.AddConsumer(stream, cfg)
+
+	return err
 }
 
-// Wait
-func (s *Transaction) Wait(ctx context.Context, subscriber, subscriberType string) (*model.Transaction, error) {
-	sub := "pay::" + subscriberType + "::" + subscriber
-	suber, err := runtime.Nats.SubscribeSync(sub)
+// subscribe : ordered consumer of subject from deliver
+func subscribe(sub string, deliver nats.SubOpt) (*EventStream, error) {
+	suber, err := runtime.JetStream.SubscribeSync(sub, nats.OrderedConsumer(), nats.BindStream(runtime.Config.Nats.Stream), deliver)
 	if err != nil {
 		return nil, err
 	}
 
-	msg, err := suber.NextMsg(time.Duration(runtime.Config.HTTP.LongPollingTimeout) * time.Second)
+	return &EventStream{suber: suber}, nil
+}
+
+// publishEvent : writes event to stream, event type in header
+func publishEvent(sub, event string, data []byte) error {
+	msg := nats.NewMsg(sub)
+	msg.Header.Set(eventHeader, event)
+	msg.Data = data
+	_, err := runtime.JetStream.PublishMsg(msg)
+
+	return err
+}
+
+// paymentEvent : parses event from stream message
+func paymentEvent(msg *nats.Msg) (*PaymentEvent, error) {
+	meta, err := msg.Metadata()
 	if err != nil {
 		return nil, err
 	}
 
-	output := new(model.Transaction)
-	json.Unmarshal(msg.Data, &output)
+	event := &PaymentEvent{
+		Seq:   meta.Sequence.Stream,
+		Event: msg.Header.Get(eventHeader),
+	}
+	switch event.Event {
+	case EventCredentialUsed:
+		event.Credential = new(CredentialUsed)
+		err = json.Unmarshal(msg.Data, event.Credential)
+	default:
+		event.Event = EventPaymentStatus
+		event.Transaction = new(model.Transaction)
+		err = json.Unmarshal(msg.Data, event.Transaction)
+	}
+
+	if err != nil {
+		return nil, err
+	}
 
-	return output, nil
+	return event, nil
 }
 
-/* }}} */
+// encodeCursor : base64url of sort key of transaction, and of the order
+func encodeCursor(transaction *model.Transaction, sortBy string, desc bool) string {
+	cursor := &model.TransactionCursor{
+		SortBy: cursorSortBy(sortBy),
+		Desc:   desc,
+		ID:     transaction.ID,
+	}
+	if cursor.SortBy == model.TransactionSortAmount {
+		cursor.Amount = transaction.Amount
+	} else {
+		cursor.CreatedAt = transaction.CreatedAt
+	}
+
+	b, _ := json.Marshal(cursor)
+
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// decodeCursor : cursor taken in another order than sortBy and desc is invalid
+func decodeCursor(cursor string, sortBy string, desc bool) (*model.TransactionCursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var output model.TransactionCursor
+	err = json.Unmarshal(b, &output)
+	if err != nil || output.ID == "" {
+		return nil, ErrInvalidCursor
+	}
+
+	if output.SortBy != cursorSortBy(sortBy) || output.Desc != desc {
+		return nil, ErrInvalidCursor
+	}
+
+	return &output, nil
+}
+
+// cursorSortBy : list sorted by created_at unless by amount
+func cursorSortBy(sortBy string) string {
+	if sortBy == model.TransactionSortAmount {
+		return model.TransactionSortAmount
+	}
+
+	return model.TransactionSortCreatedAt
+}
+
+// subject : pay.<type>.<id>, captured by runtime.PaymentSubjects
+func subject(subscriberType, subscriber string) string {
+	return "pay." + subscriberType + "." + subscriber
+}
+
+// legacySubject : pay::<type>::<id> of subject, payment status still published to it on core NATS
+func legacySubject(sub string) string {
+	return strings.Replace(sub, ".", "::", 2)
+}
+
+// durableName : consumer name of device of subscriber, dots not allowed
+func durableName(subscriberType, subscriber, device string) string {
+	return subscriberType + "_" + subscriber + "_" + device
+}
+
+// validDevice : 1 to 64 letters, digits, - or _
+func validDevice(device string) bool {
+	if len(device) == 0 || len(device) > 64 {
+		return false
+	}
+
+	for _, c := range device {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_') {
+			return false
+		}
+	}
+
+	return true
+}
 
 /*
  * Local variables:
@@ -0,0 +1,520 @@
+/*
+ * Copyright (C) HereweTech, Inc - All Rights Reserved
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+/**
+ * @file transaction_test.go
+ * @package service
+ * @author Dr.NP <np@herewe.tech>
+ * @since 10/18/2026
+ */
+
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"icepay-svc/model"
+	"icepay-svc/runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+)
+
+// runJetStream : starts an embedded nats-server with JetStream enabled
+func runJetStream(t *testing.T) {
+	t.Helper()
+	ns, err := server.NewServer(&server.Options{
+		Host:      "127.0.0.1",
+		Port:      -1,
+		JetStream: true,
+		StoreDir:  t.TempDir(),
+	})
+	if err != nil {
+		t.Fatalf("create nats-server failed : %s", err)
+	}
+
+	go ns.Start()
+	if !ns.ReadyForConnections(5 * time.Second) {
+		t.Fatal("nats-server not ready")
+	}
+
+	t.Cleanup(ns.Shutdown)
+
+	runtime.Config.Nats.Stream = "PAYMENT_TEST"
+	runtime.Config.Nats.StreamMaxAge = 1
+	runtime.Config.Nats.ConsumerInactive = 1
+	runtime.Config.HTTP.LongPollingTimeout = 1
+
+	nc, err := nats.Connect(ns.ClientURL())
+	if err != nil {
+		t.Fatalf("connect nats-server failed : %s", err)
+	}
+
+	t.Cleanup(nc.Close)
+	runtime.Nats = nc
+	err = runtime.InitJetStream()
+	if err != nil {
+		t.Fatalf("initialize jetstream failed : %s", err)
+	}
+}
+
+func TestWaitDeliversMissedEvents(t *testing.T) {
+	runJetStream(t)
+	s := new(Transaction)
+	ctx := context.Background()
+
+	// First poll creates the durable consumer
+	_, _, err := s.Wait(ctx, "c1", "client", "pos1", 0, 0)
+	if !errors.Is(err, nats.ErrTimeout) {
+		t.Fatalf("first wait = %v, want timeout", err)
+	}
+
+	// Published while no request is pending
+	for _, status := range []string{TransactionStatusPreCreate, TransactionStatusCreated, TransactionStatusComfirmed} {
+		err = s.publish(&model.Transaction{ID: "t1", Client: "c1", Tenant: "m1", Status: status})
+		if err != nil {
+			t.Fatalf("publish [%s] failed : %s", status, err)
+		}
+	}
+
+	events, _, err := s.Wait(ctx, "c1", "client", "pos1", 0, 0)
+	if err != nil {
+		t.Fatalf("wait failed : %s", err)
+	}
+
+	// CONFIRMED on subject of tenant only
+	if len(events) != 2 {
+		t.Fatalf("got %d events, want 2", len(events))
+	}
+
+	if events[0].Transaction.Status != TransactionStatusPreCreate || events[1].Transaction.Status != TransactionStatusCreated {
+		t.Errorf("events out of order : %s, %s", events[0].Transaction.Status, events[1].Transaction.Status)
+	}
+
+	if events[0].Seq >= events[1].Seq {
+		t.Errorf("sequence not increasing : %d, %d", events[0].Seq, events[1].Seq)
+	}
+
+	// Acknowledged, not delivered again
+	_, _, err = s.Wait(ctx, "c1", "client", "pos1", 0, 0)
+	if !errors.Is(err, nats.ErrTimeout) {
+		t.Fatalf("wait after ack = %v, want timeout", err)
+	}
+
+	// Rewind to the first event
+	rewound, _, err := s.Wait(ctx, "c1", "client", "pos1", events[0].Seq, 0)
+	if err != nil {
+		t.Fatalf("wait since [%d] failed : %s", events[0].Seq, err)
+	}
+
+	if len(rewound) != 1 || rewound[0].Seq != events[1].Seq {
+		t.Fatalf("wait since [%d] got %d events, want the one of seq %d", events[0].Seq, len(rewound), events[1].Seq)
+	}
+
+	// Cursor up to date, nothing to rewind
+	_, _, err = s.Wait(ctx, "c1", "client", "pos1", events[1].Seq, 0)
+	if !errors.Is(err, nats.ErrTimeout) {
+		t.Fatalf("wait since last = %v, want timeout", err)
+	}
+
+	// Tenant told of the claim and the confirmation
+	tenant, _, err := s.Wait(ctx, "m1", "tenant", "pos1", 1, 0)
+	if err != nil {
+		t.Fatalf("tenant wait failed : %s", err)
+	}
+
+	if len(tenant) != 2 || tenant[0].Transaction.Status != TransactionStatusCreated || tenant[1].Transaction.Status != TransactionStatusComfirmed {
+		t.Errorf("tenant got %d events, want the CREATED and CONFIRMED ones", len(tenant))
+	}
+}
+
+func TestWaitConcurrentWaiters(t *testing.T) {
+	runJetStream(t)
+	s := new(Transaction)
+	ctx := context.Background()
+
+	// Cursors start after it
+	err := s.publish(&model.Transaction{ID: "t5", Client: "c5", Tenant: "m5", Status: TransactionStatusCreated})
+	if err != nil {
+		t.Fatalf("publish failed : %s", err)
+	}
+
+	// Devices of their own consumers, and ones without device
+	devices := []string{"pos1", "pos2", "", ""}
+	results := make([][]*PaymentEvent, len(devices))
+	errs := make([]error, len(devices))
+	var wg sync.WaitGroup
+	for idx, device := range devices {
+		wg.Add(1)
+		go func(idx int, device string) {
+			defer wg.Done()
+			results[idx], _, errs[idx] = s.Wait(ctx, "m5", "tenant", device, 0, 0)
+		}(idx, device)
+	}
+
+	// Waiters subscribed
+	time.Sleep(300 * time.Millisecond)
+	legacy, err := runtime.Nats.SubscribeSync("pay::client::m5")
+	if err != nil {
+		t.Fatalf("subscribe legacy subject failed : %s", err)
+	}
+
+	defer legacy.Unsubscribe()
+	err = s.publish(&model.Transaction{ID: "t5", Client: "c5", Tenant: "m5", Status: TransactionStatusComfirmed})
+	if err != nil {
+		t.Fatalf("publish failed : %s", err)
+	}
+
+	wg.Wait()
+	for idx, device := range devices {
+		if errs[idx] != nil {
+			t.Errorf("waiter %d of device [%s] failed : %s", idx, device, errs[idx])
+
+			continue
+		}
+
+		if len(results[idx]) != 1 || results[idx][0].Transaction.Status != TransactionStatusComfirmed {
+			t.Fatalf("waiter %d of device [%s] got %d events, want the CONFIRMED one", idx, device, len(results[idx]))
+		}
+	}
+
+	// Subject before the stream still told
+	msg, err := legacy.NextMsg(time.Second)
+	if err != nil {
+		t.Fatalf("legacy subject got nothing : %s", err)
+	}
+
+	var transaction model.Transaction
+	if json.Unmarshal(msg.Data, &transaction) != nil || transaction.ID != "t5" {
+		t.Errorf("legacy subject got %s, want t5", msg.Data)
+	}
+
+	// Rewinding one device leaves the other as is
+	rewound, _, err := s.Wait(ctx, "m5", "tenant", "pos1", results[0][0].Seq-1, 0)
+	if err != nil || len(rewound) != 1 {
+		t.Fatalf("rewind pos1 = %d events, %v, want 1", len(rewound), err)
+	}
+
+	_, _, err = s.Wait(ctx, "m5", "tenant", "pos2", 0, 0)
+	if !errors.Is(err, nats.ErrTimeout) {
+		t.Errorf("wait pos2 after rewind of pos1 = %v, want timeout", err)
+	}
+
+	// Cursor of waiters without device
+	resumed, _, err := s.Wait(ctx, "m5", "tenant", "", results[0][0].Seq-1, 0)
+	if err != nil || len(resumed) != 1 || resumed[0].Seq != results[0][0].Seq {
+		t.Errorf("wait since %d = %d events, %v, want the one of seq %d", results[0][0].Seq-1, len(resumed), err, results[0][0].Seq)
+	}
+
+	_, _, err = s.Wait(ctx, "m5", "tenant", "pos.1", 0, 0)
+	if !errors.Is(err, ErrInvalidDevice) {
+		t.Errorf("wait of device [pos.1] = %v, want %v", err, ErrInvalidDevice)
+	}
+}
+
+func TestWaitResumeByCursor(t *testing.T) {
+	runJetStream(t)
+	s := new(Transaction)
+	ctx := context.Background()
+	err := s.publish(&model.Transaction{ID: "t6", Client: "c6", Tenant: "m6", Status: TransactionStatusPreCreate})
+	if err != nil {
+		t.Fatalf("publish failed : %s", err)
+	}
+
+	// End of stream as cursor of the first wait
+	_, cursor, err := s.Wait(ctx, "c6", "client", "", 0, 0)
+	if !errors.Is(err, nats.ErrTimeout) {
+		t.Fatalf("first wait = %v, want timeout", err)
+	}
+
+	if cursor != 1 {
+		t.Fatalf("cursor of timeout = %d, want 1", cursor)
+	}
+
+	// Published between two waits
+	for _, status := range []string{TransactionStatusCreated, TransactionStatusComfirmed, TransactionStatusClosed} {
+		err = s.publish(&model.Transaction{ID: "t6", Client: "c6", Tenant: "m6", Status: status})
+		if err != nil {
+			t.Fatalf("publish [%s] failed : %s", status, err)
+		}
+	}
+
+	events, cursor, err := s.Wait(ctx, "c6", "client", "", cursor, 1)
+	if err != nil {
+		t.Fatalf("wait failed : %s", err)
+	}
+
+	if len(events) != 1 || events[0].Transaction.Status != TransactionStatusCreated || cursor != events[0].Seq {
+		t.Fatalf("got %d events, cursor %d, want the CREATED one and its seq", len(events), cursor)
+	}
+
+	events, cursor, err = s.Wait(ctx, "c6", "client", "", cursor, 0)
+	if err != nil {
+		t.Fatalf("wait since %d failed : %s", cursor, err)
+	}
+
+	if len(events) != 1 || events[0].Transaction.Status != TransactionStatusClosed || cursor != events[0].Seq {
+		t.Errorf("got %d events, cursor %d, want the CLOSED one and its seq", len(events), cursor)
+	}
+}
+
+func TestWaitDeviceLimit(t *testing.T) {
+	runJetStream(t)
+	runtime.Config.Nats.MaxDevices = 2
+	defer func() {
+		runtime.Config.Nats.MaxDevices = 16
+	}()
+
+	s := new(Transaction)
+	ctx := context.Background()
+	for _, device := range []string{"pos1", "pos2"} {
+		_, _, err := s.Wait(ctx, "c7", "client", device, 0, 0)
+		if !errors.Is(err, nats.ErrTimeout) {
+			t.Fatalf("wait of device [%s] = %v, want timeout", device, err)
+		}
+	}
+
+	_, _, err := s.Wait(ctx, "c7", "client", "pos3", 0, 0)
+	if !errors.Is(err, ErrTooManyDevices) {
+		t.Fatalf("wait of device [pos3] = %v, want %v", err, ErrTooManyDevices)
+	}
+
+	// Devices of other subscribers not counted
+	_, _, err = s.Wait(ctx, "c8", "client", "pos3", 0, 0)
+	if !errors.Is(err, nats.ErrTimeout) {
+		t.Fatalf("wait of device [pos3] of c8 = %v, want timeout", err)
+	}
+
+	info, err := runtime.JetStream.ConsumerInfo(runtime.Config.Nats.Stream, durableName("client", "c7", "pos1"))
+	if err != nil {
+		t.Fatalf("consumer info failed : %s", err)
+	}
+
+	if info.Config.InactiveThreshold != time.Hour {
+		t.Errorf("inactive threshold = %s, want 1h", info.Config.InactiveThreshold)
+	}
+}
+
+func TestSubscribeResume(t *testing.T) {
+	runJetStream(t)
+	s := new(Transaction)
+	for _, status := range []string{TransactionStatusPreCreate, TransactionStatusCreated} {
+		err := s.publish(&model.Transaction{ID: "t2", Client: "c2", Tenant: "m2", Status: status})
+		if err != nil {
+			t.Fatalf("publish [%s] failed : %s", status, err)
+		}
+	}
+
+	// New events only without cursor
+	stream, err := s.Subscribe("c2", "client", 0)
+	if err != nil {
+		t.Fatalf("subscribe failed : %s", err)
+	}
+
+	_, err = stream.Next(200 * time.Millisecond)
+	if !errors.Is(err, nats.ErrTimeout) {
+		t.Fatalf("next = %v, want timeout", err)
+	}
+
+	stream.Close()
+
+	// Resume after the first one
+	stream, err = s.Subscribe("c2", "client", 1)
+	if err != nil {
+		t.Fatalf("subscribe since 1 failed : %s", err)
+	}
+
+	defer stream.Close()
+	event, err := stream.Next(time.Second)
+	if err != nil {
+		t.Fatalf("next failed : %s", err)
+	}
+
+	if event.Seq != 2 || event.Transaction.Status != TransactionStatusCreated {
+		t.Errorf("got event %d [%s], want 2 [%s]", event.Seq, event.Transaction.Status, TransactionStatusCreated)
+	}
+}
+
+func TestPublishTenantEvents(t *testing.T) {
+	runJetStream(t)
+	s := new(Transaction)
+	stream, err := s.Subscribe("m9", "tenant", 0)
+	if err != nil {
+		t.Fatalf("subscribe failed : %s", err)
+	}
+
+	defer stream.Close()
+	statuses := []string{
+		TransactionStatusPreCreate,
+		TransactionStatusCreated,
+		TransactionStatusComfirmed,
+		TransactionStatusPartiallyRefunded,
+		TransactionStatusRefunded,
+	}
+	for _, status := range statuses {
+		err = s.publish(&model.Transaction{ID: "t9", Client: "c9", Tenant: "m9", Status: status})
+		if err != nil {
+			t.Fatalf("publish [%s] failed : %s", status, err)
+		}
+	}
+
+	// Tenant created the request itself, told of everything after
+	for _, status := range statuses[1:] {
+		event, err := stream.Next(time.Second)
+		if err != nil {
+			t.Fatalf("next of [%s] failed : %s", status, err)
+		}
+
+		if event.Transaction.Status != status {
+			t.Errorf("tenant got [%s], want [%s]", event.Transaction.Status, status)
+		}
+	}
+}
+
+func TestPublishTriesEverySubject(t *testing.T) {
+	runJetStream(t)
+	s := new(Transaction)
+	legacy, err := runtime.Nats.SubscribeSync("pay::client::c10")
+	if err != nil {
+		t.Fatalf("subscribe legacy subject failed : %s", err)
+	}
+
+	defer legacy.Unsubscribe()
+	err = runtime.JetStream.DeleteStream(runtime.Config.Nats.Stream)
+	if err != nil {
+		t.Fatalf("delete stream failed : %s", err)
+	}
+
+	// Stream gone, core NATS subjects still told
+	err = s.publish(&model.Transaction{ID: "t10", Client: "c10", Tenant: "m10", Status: TransactionStatusClosed})
+	if err == nil {
+		t.Fatal("publish without stream should fail")
+	}
+
+	if !strings.Contains(err.Error(), "pay.client.c10") || !strings.Contains(err.Error(), "pay.tenant.m10") {
+		t.Errorf("error = %s, want failures of both subjects", err)
+	}
+
+	_, err = legacy.NextMsg(time.Second)
+	if err != nil {
+		t.Errorf("legacy subject got nothing : %s", err)
+	}
+}
+
+func TestWaitCredentialUsedEvent(t *testing.T) {
+	runJetStream(t)
+	s := new(Transaction)
+	ctx := context.Background()
+	_, _, err := s.Wait(ctx, "c3", "client", "pos1", 0, 0)
+	if !errors.Is(err, nats.ErrTimeout) {
+		t.Fatalf("first wait = %v, want timeout", err)
+	}
+
+	err = s.publish(&model.Transaction{ID: "t3", Client: "c3", Tenant: "m3", Status: TransactionStatusCreated})
+	if err != nil {
+		t.Fatalf("publish failed : %s", err)
+	}
+
+	b, _ := json.Marshal(&CredentialUsed{Tenant: "m3", At: time.Now()})
+	err = publishEvent(subject("client", "c3"), EventCredentialUsed, b)
+	if err != nil {
+		t.Fatalf("publish credential used failed : %s", err)
+	}
+
+	events, _, err := s.Wait(ctx, "c3", "client", "pos1", 0, 0)
+	if err != nil {
+		t.Fatalf("wait failed : %s", err)
+	}
+
+	if len(events) != 2 {
+		t.Fatalf("got %d events, want 2", len(events))
+	}
+
+	if events[0].Event != EventPaymentStatus || events[0].Transaction == nil {
+		t.Errorf("first event [%s], want payment status", events[0].Event)
+	}
+
+	if events[1].Event != EventCredentialUsed || events[1].Credential == nil || events[1].Credential.Tenant != "m3" {
+		t.Errorf("second event [%s], want credential used by m3", events[1].Event)
+	}
+}
+
+func TestCreateInvalidAmount(t *testing.T) {
+	s := new(Transaction)
+	ctx := context.Background()
+	for _, amount := range []int64{0, -100} {
+		_, err := s.Create(ctx, &model.Transaction{Client: "c1", Tenant: "m1", Amount: amount})
+		if !errors.Is(err, ErrInvalidAmount) {
+			t.Errorf("create of amount %d = %v, want %v", amount, err, ErrInvalidAmount)
+		}
+
+		_, err = s.Request(ctx, &model.Transaction{Tenant: "m1", Amount: amount})
+		if !errors.Is(err, ErrInvalidAmount) {
+			t.Errorf("request of amount %d = %v, want %v", amount, err, ErrInvalidAmount)
+		}
+	}
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	at := time.Date(2026, 10, 18, 8, 30, 0, 123456000, time.UTC)
+	transaction := &model.Transaction{ID: "t4", Amount: 1200, CreatedAt: at}
+
+	cursor, err := decodeCursor(encodeCursor(transaction, model.TransactionSortCreatedAt, false), "", false)
+	if err != nil {
+		t.Fatalf("decode failed : %s", err)
+	}
+
+	if cursor.ID != "t4" || !cursor.CreatedAt.Equal(at) {
+		t.Errorf("got cursor [%s] at %s, want [t4] at %s", cursor.ID, cursor.CreatedAt, at)
+	}
+
+	cursor, err = decodeCursor(encodeCursor(transaction, model.TransactionSortAmount, true), model.TransactionSortAmount, true)
+	if err != nil {
+		t.Fatalf("decode failed : %s", err)
+	}
+
+	if cursor.ID != "t4" || cursor.Amount != 1200 {
+		t.Errorf("got cursor [%s] of amount %d, want [t4] of 1200", cursor.ID, cursor.Amount)
+	}
+
+	for _, v := range []string{"not base64!", "bnVsbA", "e30"} {
+		_, err = decodeCursor(v, "", false)
+		if !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("decode %s = %v, want %v", v, err, ErrInvalidCursor)
+		}
+	}
+
+	// Cursor of another order
+	mismatches := []struct {
+		sortBy string
+		desc   bool
+	}{
+		{model.TransactionSortCreatedAt, true},
+		{model.TransactionSortCreatedAt, false},
+		{model.TransactionSortAmount, false},
+	}
+	v := encodeCursor(transaction, model.TransactionSortAmount, true)
+	for _, m := range mismatches {
+		_, err = decodeCursor(v, m.sortBy, m.desc)
+		if !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("decode cursor of amount desc as %s desc=%t = %v, want %v", m.sortBy, m.desc, err, ErrInvalidCursor)
+		}
+	}
+}
+
+/*
+ * Local variables:
+ * tab-width: 4
+ * c-basic-offset: 4
+ * End:
+ * vim600: sw=4 ts=4 fdm=marker
+ * vim<600: sw=4 ts=4
+ */
@@ -0,0 +1,143 @@
+/*
+ * Copyright (C) HereweTech, Inc - All Rights Reserved
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+/**
+ * @file vault.go
+ * @package service
+ * @author Dr.NP <np@herewe.tech>
+ * @since 10/18/2026
+ */
+
+package service
+
+import (
+	"context"
+	"icepay-svc/model"
+	"icepay-svc/runtime"
+	"icepay-svc/utils"
+)
+
+// Max cards re-sealed in one transaction
+const rekeyBatchSize = 100
+
+// Vault : keeps card numbers (PAN) sealed by AES-GCM, only token, BIN and last4 leave it
+type Vault struct {
+	keyring *utils.Keyring
+	hmacKey []byte
+}
+
+func NewVault() *Vault {
+	s := new(Vault)
+	keyring, err := utils.NewKeyring(runtime.Config.Security.VaultKeys, runtime.Config.Security.VaultKeyID)
+	if err != nil {
+		runtime.Logger.Fatalf("vault keyring initialize failed : %s", err)
+	}
+
+	s.keyring = keyring
+	s.hmacKey = []byte(runtime.Config.Security.VaultHMACKey)
+
+	return s
+}
+
+/* {{{ [Methods] */
+
+// Seal encrypts number into card by the active key, bound to card ID
+func (s *Vault) Seal(card *model.Card, number string) error {
+	kid, sealed, err := s.keyring.Seal([]byte(number), []byte(card.ID))
+	if err != nil {
+		return err
+	}
+
+	card.PAN = sealed
+	card.KeyID = kid
+	card.Fingerprint = s.Fingerprint(number)
+	card.BIN = number[:6]
+	card.Last4 = number[len(number)-4:]
+
+	return nil
+}
+
+// Open decrypts number of card
+func (s *Vault) Open(card *model.Card) (string, error) {
+	number, err := s.keyring.Open(card.KeyID, card.PAN, []byte(card.ID))
+	if err != nil {
+		return "", err
+	}
+
+	return string(number), nil
+}
+
+// Fingerprint : keyed hash of number, for lookups without decryption
+func (s *Vault) Fingerprint(number string) string {
+	return utils.HMACSHA256([]byte(number), s.hmacKey)
+}
+
+// Rekey seals plaintext numbers left by legacy rows, and re-seals cards of retired keys by the active one.
+// Returns number of cards updated
+func (s *Vault) Rekey(ctx context.Context) (int, error) {
+	total := 0
+	for {
+		n := 0
+		err := runtime.RunInTx(ctx, func(ctx context.Context) error {
+			cards, err := new(model.Card).LockStale(ctx, s.keyring.Active(), rekeyBatchSize)
+			if err != nil {
+				return err
+			}
+
+			for _, card := range cards {
+				legacy := card.Number != ""
+				number := card.Number
+				if !legacy {
+					number, err = s.Open(card)
+					if err != nil {
+						return err
+					}
+				}
+
+				if card.Token == "" {
+					card.Token = newCardToken()
+				}
+
+				err = s.Seal(card, number)
+				if err != nil {
+					return err
+				}
+
+				err = card.UpdateVault(ctx, legacy)
+				if err != nil {
+					return err
+				}
+			}
+
+			n = len(cards)
+
+			return nil
+		})
+		if err != nil {
+			return total, err
+		}
+
+		total += n
+		if n < rekeyBatchSize {
+			return total, nil
+		}
+	}
+}
+
+/* }}} */
+
+func newCardToken() string {
+	return "card_" + utils.SecureRandomString(24)
+}
+
+/*
+ * Local variables:
+ * tab-width: 4
+ * c-basic-offset: 4
+ * End:
+ * vim600: sw=4 ts=4 fdm=marker
+ * vim<600: sw=4 ts=4
+ */
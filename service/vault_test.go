@@ -0,0 +1,85 @@
+/*
+ * Copyright (C) HereweTech, Inc - All Rights Reserved
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+/**
+ * @file vault_test.go
+ * @package service
+ * @author Dr.NP <np@herewe.tech>
+ * @since 10/18/2026
+ */
+
+package service
+
+import (
+	"context"
+	"database/sql"
+	"icepay-svc/model"
+	"icepay-svc/runtime"
+	"strings"
+	"testing"
+
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect/pgdialect"
+	"github.com/uptrace/bun/driver/pgdriver"
+)
+
+// queryRecorder : records queries sent to database, run or not
+type queryRecorder struct {
+	queries []string
+}
+
+func (r *queryRecorder) BeforeQuery(ctx context.Context, event *bun.QueryEvent) context.Context {
+	r.queries = append(r.queries, event.Query)
+
+	return ctx
+}
+
+func (r *queryRecorder) AfterQuery(ctx context.Context, event *bun.QueryEvent) {}
+
+func TestRekeyDeletedLegacyCards(t *testing.T) {
+	// Nothing listening, queries recorded before they fail
+	db := bun.NewDB(sql.OpenDB(pgdriver.NewConnector(pgdriver.WithDSN("postgres://icepay@127.0.0.1:1/icepay?sslmode=disable"))), pgdialect.New())
+	defer db.Close()
+	recorder := new(queryRecorder)
+	db.AddQueryHook(recorder)
+	saved := runtime.DB
+	runtime.DB = db
+	defer func() {
+		runtime.DB = saved
+	}()
+
+	ctx := context.Background()
+	new(model.Card).LockStale(ctx, "k2", 10)
+	card := &model.Card{ID: "card1", KeyID: "k2", PAN: []byte("sealed"), Last4: "4242"}
+	card.UpdateVault(ctx, true)
+	if len(recorder.queries) != 2 {
+		t.Fatalf("got %d queries, want 2", len(recorder.queries))
+	}
+
+	// Soft deleted legacy rows selected and rewritten as well
+	for _, query := range recorder.queries {
+		if strings.Contains(query, `"deleted_at" IS NULL`) {
+			t.Errorf("query skips deleted cards : %s", query)
+		}
+	}
+
+	if !strings.Contains(recorder.queries[0], "number IS NOT NULL") {
+		t.Errorf("stale query misses legacy cards : %s", recorder.queries[0])
+	}
+
+	if !strings.Contains(recorder.queries[1], "number = NULL") || !strings.Contains(recorder.queries[1], "cvv = NULL") {
+		t.Errorf("update keeps plaintext of legacy card : %s", recorder.queries[1])
+	}
+}
+
+/*
+ * Local variables:
+ * tab-width: 4
+ * c-basic-offset: 4
+ * End:
+ * vim600: sw=4 ts=4 fdm=marker
+ * vim<600: sw=4 ts=4
+ */
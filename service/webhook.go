@@ -0,0 +1,503 @@
+/*
+ * Copyright (C) HereweTech, Inc - All Rights Reserved
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+/**
+ * @file webhook.go
+ * @package service
+ * @author Dr.NP <np@herewe.tech>
+ * @since 10/18/2026
+ */
+
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"icepay-svc/model"
+	"icepay-svc/runtime"
+	"icepay-svc/utils"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	WebhookDeliveryPending   = "PENDING"
+	WebhookDeliverySucceeded = "SUCCEEDED"
+	WebhookDeliveryFailed    = "FAILED"
+
+	WebhookEventPaymentStatus = "payment.status"
+
+	WebhookHeaderEvent     = "X-Icepay-Event"
+	WebhookHeaderDelivery  = "X-Icepay-Delivery"
+	WebhookHeaderTimestamp = "X-Icepay-Timestamp"
+	WebhookHeaderSignature = "X-Icepay-Signature"
+)
+
+const (
+	// Max deliveries attempted in one run of worker
+	webhookBatchSize = 20
+
+	// Backoff stops doubling after this many attempts
+	maxWebhookBackoffShift = 12
+
+	// Added to timeout of request as lease of claimed deliveries
+	webhookLeaseMargin = 30 * time.Second
+)
+
+var (
+	ErrWebhookInvalidURL    = errors.New("Invalid webhook URL")
+	ErrWebhookForbiddenHost = errors.New("Webhook host resolves to a loopback, link-local, private or shared address")
+	ErrWebhookDeliveryBusy  = errors.New("Webhook delivery in progress")
+)
+
+// Shared address space of carrier-grade NAT, 100.64.0.0/10, not public either
+var webhookSharedNet = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// WebhookPayload : body POSTed to webhook endpoints
+type WebhookPayload struct {
+	ID        string      `json:"id"`
+	Event     string      `json:"event"`
+	CreatedAt time.Time   `json:"created_at"`
+	Data      interface{} `json:"data"`
+}
+
+type Webhook struct {
+	client *http.Client
+}
+
+func NewWebhook() *Webhook {
+	s := new(Webhook)
+
+	// Addresses checked once resolved, hosts rebound to internal ones after registration refused as well
+	dialer := &net.Dialer{
+		Timeout: time.Duration(runtime.Config.Webhook.Timeout) * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+
+			if !webhookIPAllowed(net.ParseIP(host)) {
+				return ErrWebhookForbiddenHost
+			}
+
+			return nil
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil // Dialed addresses are the endpoints themselves
+	transport.DialContext = dialer.DialContext
+	s.client = &http.Client{
+		Timeout:   time.Duration(runtime.Config.Webhook.Timeout) * time.Second,
+		Transport: transport,
+	}
+
+	return s
+}
+
+/* {{{ [Methods] */
+
+// Create : registers webhook endpoint of tenant with a new secret
+func (s *Webhook) Create(ctx context.Context, input *model.Webhook) (*model.Webhook, error) {
+	err := validWebhookURL(ctx, input.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	webhook := &model.Webhook{
+		Tenant:      input.Tenant,
+		URL:         input.URL,
+		Description: input.Description,
+		Secret:      newWebhookSecret(),
+		Enabled:     true,
+	}
+
+	err = webhook.Create(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return webhook, nil
+}
+
+// Update : updates webhook, enabled flag kept if not given, a new secret generated if rotate given
+func (s *Webhook) Update(ctx context.Context, input *model.Webhook, enabled *bool, rotate bool) (*model.Webhook, error) {
+	if input.URL != "" {
+		err := validWebhookURL(ctx, input.URL)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	current, err := s.Get(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	webhook := &model.Webhook{
+		ID:          current.ID,
+		Tenant:      current.Tenant,
+		URL:         input.URL,
+		Description: input.Description,
+		Enabled:     current.Enabled,
+	}
+	if enabled != nil {
+		webhook.Enabled = *enabled
+	}
+
+	if rotate {
+		webhook.Secret = newWebhookSecret()
+	}
+
+	err = webhook.Update(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.Get(ctx, input)
+}
+
+// Get
+func (s *Webhook) Get(ctx context.Context, input *model.Webhook) (*model.Webhook, error) {
+	webhook := &model.Webhook{
+		ID:     input.ID,
+		Tenant: input.Tenant,
+	}
+
+	err := webhook.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return webhook, nil
+}
+
+// List
+func (s *Webhook) List(ctx context.Context, input *model.Webhook) ([]*model.Webhook, error) {
+	webhook := &model.Webhook{
+		Tenant: input.Tenant,
+	}
+
+	return webhook.List(ctx)
+}
+
+// Delete
+func (s *Webhook) Delete(ctx context.Context, input *model.Webhook) error {
+	webhook := &model.Webhook{
+		ID:     input.ID,
+		Tenant: input.Tenant,
+	}
+
+	return webhook.Delete(ctx)
+}
+
+// Deliveries : lists latest deliveries of webhook
+func (s *Webhook) Deliveries(ctx context.Context, input *model.WebhookDelivery, limit int) ([]*model.WebhookDelivery, error) {
+	delivery := &model.WebhookDelivery{
+		Webhook: input.Webhook,
+		Tenant:  input.Tenant,
+		Status:  input.Status,
+	}
+
+	return delivery.List(ctx, limit)
+}
+
+// Dispatch : queues transaction event to every enabled webhook of its tenant
+func (s *Webhook) Dispatch(ctx context.Context, transaction *model.Transaction) error {
+	if transaction.Tenant == "" {
+		return nil
+	}
+
+	webhooks, err := s.List(ctx, &model.Webhook{Tenant: transaction.Tenant})
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, webhook := range webhooks {
+		if !webhook.Enabled {
+			continue
+		}
+
+		delivery := &model.WebhookDelivery{
+			ID:            uuid.NewString(),
+			Webhook:       webhook.ID,
+			Tenant:        webhook.Tenant,
+			Transaction:   transaction.ID,
+			Event:         WebhookEventPaymentStatus,
+			Status:        WebhookDeliveryPending,
+			NextAttemptAt: now,
+		}
+		b, _ := json.Marshal(&WebhookPayload{
+			ID:        delivery.ID,
+			Event:     delivery.Event,
+			CreatedAt: now,
+			Data:      transaction,
+		})
+		delivery.Payload = string(b)
+		err = delivery.Create(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// DeliverDue : attempts pending deliveries that are due, for the background worker.
+// Rows are leased by pushing next_attempt_at forward and committed before sending, so no database
+// transaction is held open while endpoints respond; deliveries of a crashed run are retried after the lease
+func (s *Webhook) DeliverDue(ctx context.Context) error {
+	var deliveries []*model.WebhookDelivery
+	err := runtime.RunInTx(ctx, func(ctx context.Context) error {
+		due, err := new(model.WebhookDelivery).LockDue(ctx, WebhookDeliveryPending, time.Now(), webhookBatchSize)
+		if err != nil {
+			return err
+		}
+
+		leaseUntil := time.Now().Add(webhookLease())
+		for _, delivery := range due {
+			delivery.NextAttemptAt = leaseUntil
+			err = delivery.Update(ctx)
+			if err != nil {
+				return err
+			}
+		}
+
+		deliveries = due
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	var wg sync.WaitGroup
+	for _, delivery := range deliveries {
+		wg.Add(1)
+		go func(delivery *model.WebhookDelivery) {
+			defer wg.Done()
+			err := s.attempt(ctx, delivery)
+			if err != nil {
+				runtime.Logger.Errorf("record webhook delivery [%s] failed : %s", delivery.ID, err)
+			}
+		}(delivery)
+	}
+
+	wg.Wait()
+
+	return nil
+}
+
+// Redeliver : resets delivery of tenant and attempts it right now, leased as the worker does.
+// Endpoints may receive a delivery more than once, deduplicated by its ID
+func (s *Webhook) Redeliver(ctx context.Context, input *model.WebhookDelivery) (*model.WebhookDelivery, error) {
+	delivery := &model.WebhookDelivery{
+		ID:      input.ID,
+		Webhook: input.Webhook,
+		Tenant:  input.Tenant,
+	}
+
+	err := delivery.Get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = runtime.RunInTx(ctx, func(ctx context.Context) error {
+		err := delivery.Lock(ctx)
+		if errors.Is(err, sql.ErrNoRows) {
+			// The worker is claiming it
+			return ErrWebhookDeliveryBusy
+		}
+
+		if err != nil {
+			return err
+		}
+
+		delivery.Status = WebhookDeliveryPending
+		delivery.Attempts = 0
+		delivery.NextAttemptAt = time.Now().Add(webhookLease())
+
+		return delivery.Update(ctx)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.attempt(ctx, delivery)
+	if err != nil {
+		return nil, err
+	}
+
+	return delivery, nil
+}
+
+// attempt : posts delivery to its endpoint and records the result, schedules retry with exponential backoff on failure
+func (s *Webhook) attempt(ctx context.Context, delivery *model.WebhookDelivery) error {
+	webhook := &model.Webhook{
+		ID:     delivery.Webhook,
+		Tenant: delivery.Tenant,
+	}
+
+	now := time.Now()
+	delivery.Attempts++
+	delivery.NextAttemptAt = now
+	err := webhook.Get(ctx)
+	if err != nil {
+		// Webhook removed
+		delivery.Status = WebhookDeliveryFailed
+		delivery.LastError = err.Error()
+
+		return delivery.Update(ctx)
+	}
+
+	code, err := s.send(ctx, webhook.URL, webhook.Secret, delivery.ID, delivery.Event, []byte(delivery.Payload))
+	delivery.LastStatusCode = code
+	delivery.LastError = ""
+	if err == nil {
+		delivery.Status = WebhookDeliverySucceeded
+		runtime.Logger.Infof("webhook delivery [%s] succeeded", delivery.ID)
+
+		return delivery.Update(ctx)
+	}
+
+	delivery.LastError = err.Error()
+	if int64(delivery.Attempts) >= runtime.Config.Webhook.MaxAttempts {
+		delivery.Status = WebhookDeliveryFailed
+		runtime.Logger.Warnf("webhook delivery [%s] failed after %d attempts : %s", delivery.ID, delivery.Attempts, err)
+	} else {
+		shift := delivery.Attempts - 1
+		if shift > maxWebhookBackoffShift {
+			shift = maxWebhookBackoffShift
+		}
+
+		backoff := time.Duration(runtime.Config.Webhook.RetryBase) * time.Second << shift
+		delivery.NextAttemptAt = now.Add(backoff)
+		runtime.Logger.Infof("webhook delivery [%s] failed, retry in %s : %s", delivery.ID, backoff, err)
+	}
+
+	return delivery.Update(ctx)
+}
+
+// send : signed POST of body, any 2xx response is treated as success
+func (s *Webhook) send(ctx context.Context, endpoint, secret, deliveryID, event string, body []byte) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", runtime.EnvPrefix+"-webhook")
+	req.Header.Set(WebhookHeaderEvent, event)
+	req.Header.Set(WebhookHeaderDelivery, deliveryID)
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set(WebhookHeaderTimestamp, timestamp)
+	req.Header.Set(WebhookHeaderSignature, SignWebhook(secret, timestamp, body))
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected response status %d", resp.StatusCode)
+	}
+
+	return resp.StatusCode, nil
+}
+
+/* }}} */
+
+// SignWebhook returns signature header value of body sent at timestamp : sha256=<hex HMAC-SHA256 of timestamp.body keyed by secret>.
+// Timestamp signed along, requests captured could not be replayed as fresh ones
+func SignWebhook(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// webhookLease : time a claimed delivery is kept from other workers, long enough for one attempt
+func webhookLease() time.Duration {
+	return time.Duration(runtime.Config.Webhook.Timeout)*time.Second + webhookLeaseMargin
+}
+
+func newWebhookSecret() string {
+	return "whsec_" + utils.SecureRandomString(32)
+}
+
+// validWebhookURL : http or https URL, of host resolved to public addresses only
+func validWebhookURL(ctx context.Context, raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || u.Host == "" || u.Hostname() == "" {
+		return ErrWebhookInvalidURL
+	}
+
+	scheme := strings.ToLower(u.Scheme)
+	if scheme != "http" && scheme != "https" {
+		return ErrWebhookInvalidURL
+	}
+
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, u.Hostname())
+	if err != nil || len(addrs) == 0 {
+		return ErrWebhookInvalidURL
+	}
+
+	for _, addr := range addrs {
+		if !webhookIPAllowed(addr.IP) {
+			return ErrWebhookForbiddenHost
+		}
+	}
+
+	return nil
+}
+
+// webhookIPAllowed : whether endpoint of the address could be called, internal ones only if webhook.allow_private given
+func webhookIPAllowed(ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+
+	if runtime.Config.Webhook.AllowPrivate {
+		return true
+	}
+
+	return !ip.IsLoopback() &&
+		!ip.IsLinkLocalUnicast() &&
+		!ip.IsLinkLocalMulticast() &&
+		!ip.IsInterfaceLocalMulticast() &&
+		!ip.IsMulticast() &&
+		!ip.IsPrivate() &&
+		!ip.IsUnspecified() &&
+		!webhookSharedNet.Contains(ip)
+}
+
+/*
+ * Local variables:
+ * tab-width: 4
+ * c-basic-offset: 4
+ * End:
+ * vim600: sw=4 ts=4 fdm=marker
+ * vim<600: sw=4 ts=4
+ */
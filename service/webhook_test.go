@@ -0,0 +1,179 @@
+/*
+ * Copyright (C) HereweTech, Inc - All Rights Reserved
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+/**
+ * @file webhook_test.go
+ * @package service
+ * @author Dr.NP <np@herewe.tech>
+ * @since 10/18/2026
+ */
+
+package service
+
+import (
+	"context"
+	"errors"
+	"icepay-svc/runtime"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWebhookSend(t *testing.T) {
+	const secret = "whsec_test"
+	body := []byte(`{"id":"d1","event":"payment.status","data":{"status":"CONFIRMED"}}`)
+
+	var got *http.Request
+	var gotBody []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r
+		gotBody, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	s := &Webhook{client: srv.Client()}
+	code, err := s.send(context.Background(), srv.URL, secret, "d1", WebhookEventPaymentStatus, body)
+	if err != nil {
+		t.Fatalf("send failed : %s", err)
+	}
+
+	if code != http.StatusNoContent {
+		t.Errorf("status code = %d, want %d", code, http.StatusNoContent)
+	}
+
+	if got.Method != http.MethodPost {
+		t.Errorf("method = %s, want POST", got.Method)
+	}
+
+	if string(gotBody) != string(body) {
+		t.Errorf("body = %s, want %s", gotBody, body)
+	}
+
+	timestamp := got.Header.Get(WebhookHeaderTimestamp)
+	if sig := got.Header.Get(WebhookHeaderSignature); sig != SignWebhook(secret, timestamp, gotBody) {
+		t.Errorf("signature = %s, want %s", sig, SignWebhook(secret, timestamp, gotBody))
+	}
+
+	// Signature of another timestamp differs, replays of captured requests rejected
+	if SignWebhook(secret, timestamp+"0", gotBody) == got.Header.Get(WebhookHeaderSignature) {
+		t.Error("signature does not cover timestamp")
+	}
+
+	if ev := got.Header.Get(WebhookHeaderEvent); ev != WebhookEventPaymentStatus {
+		t.Errorf("event = %s, want %s", ev, WebhookEventPaymentStatus)
+	}
+
+	if id := got.Header.Get(WebhookHeaderDelivery); id != "d1" {
+		t.Errorf("delivery = %s, want d1", id)
+	}
+
+	if got.Header.Get(WebhookHeaderTimestamp) == "" {
+		t.Error("timestamp header missing")
+	}
+}
+
+func TestWebhookSendFailure(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	s := &Webhook{client: srv.Client()}
+	code, err := s.send(context.Background(), srv.URL, "whsec_test", "d2", WebhookEventPaymentStatus, []byte(`{}`))
+	if err == nil {
+		t.Fatal("non-2xx response should fail")
+	}
+
+	if code != http.StatusServiceUnavailable {
+		t.Errorf("status code = %d, want %d", code, http.StatusServiceUnavailable)
+	}
+}
+
+func TestSignWebhook(t *testing.T) {
+	// echo -n '1700000000.hello' | openssl dgst -sha256 -hmac 'secret'
+	want := "sha256=47b1df0ab12338b2685470b0d2b37033add7c3b2bc8172f313e77413f1bb78c8"
+	if got := SignWebhook("secret", "1700000000", []byte("hello")); got != want {
+		t.Errorf("SignWebhook = %s, want %s", got, want)
+	}
+}
+
+func TestValidWebhookURL(t *testing.T) {
+	runtime.Config.Webhook.AllowPrivate = false
+	cases := map[string]error{
+		"https://93.184.216.34/hook":       nil,
+		"http://[2606:2800:220:1::]:8080/": nil,
+		"http://127.0.0.1:8080/":           ErrWebhookForbiddenHost,
+		"http://localhost/":                ErrWebhookForbiddenHost,
+		"http://169.254.169.254/latest/":   ErrWebhookForbiddenHost,
+		"http://10.0.0.1/":                 ErrWebhookForbiddenHost,
+		"https://192.168.1.1/":             ErrWebhookForbiddenHost,
+		"http://172.16.0.1/":               ErrWebhookForbiddenHost,
+		"http://100.64.0.1/":               ErrWebhookForbiddenHost,
+		"http://100.127.255.254/":          ErrWebhookForbiddenHost,
+		"http://[::ffff:100.100.1.1]/":     ErrWebhookForbiddenHost,
+		"http://100.128.0.1/":              nil,
+		"http://0.0.0.0/":                  ErrWebhookForbiddenHost,
+		"http://[::1]/":                    ErrWebhookForbiddenHost,
+		"http://[fe80::1]/":                ErrWebhookForbiddenHost,
+		"http://[::ffff:127.0.0.1]/":       ErrWebhookForbiddenHost,
+		"ftp://example.com/":               ErrWebhookInvalidURL,
+		"example.com/hook":                 ErrWebhookInvalidURL,
+		"https://":                         ErrWebhookInvalidURL,
+		"https://:8080/":                   ErrWebhookInvalidURL,
+	}
+	for raw, want := range cases {
+		err := validWebhookURL(context.Background(), raw)
+		if !errors.Is(err, want) {
+			t.Errorf("validWebhookURL(%q) = %v, want %v", raw, err, want)
+		}
+	}
+
+	runtime.Config.Webhook.AllowPrivate = true
+	defer func() {
+		runtime.Config.Webhook.AllowPrivate = false
+	}()
+
+	err := validWebhookURL(context.Background(), "http://127.0.0.1:8080/")
+	if err != nil {
+		t.Errorf("loopback URL allowed by config = %v, want nil", err)
+	}
+}
+
+func TestWebhookDialPrivate(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	// Refused when dialed, even if registered before
+	runtime.Config.Webhook.AllowPrivate = false
+	s := NewWebhook()
+	_, err := s.send(context.Background(), srv.URL, "whsec_test", "d3", WebhookEventPaymentStatus, []byte(`{}`))
+	if !errors.Is(err, ErrWebhookForbiddenHost) {
+		t.Fatalf("send to loopback = %v, want %v", err, ErrWebhookForbiddenHost)
+	}
+
+	runtime.Config.Webhook.AllowPrivate = true
+	defer func() {
+		runtime.Config.Webhook.AllowPrivate = false
+	}()
+
+	code, err := s.send(context.Background(), srv.URL, "whsec_test", "d3", WebhookEventPaymentStatus, []byte(`{}`))
+	if err != nil || code != http.StatusNoContent {
+		t.Errorf("send allowed by config = %d, %v, want %d", code, err, http.StatusNoContent)
+	}
+}
+
+/*
+ * Local variables:
+ * tab-width: 4
+ * c-basic-offset: 4
+ * End:
+ * vim600: sw=4 ts=4 fdm=marker
+ * vim<600: sw=4 ts=4
+ */
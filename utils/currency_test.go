@@ -0,0 +1,44 @@
+/*
+ * Copyright (C) HereweTech, Inc - All Rights Reserved
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+/**
+ * @file currency_test.go
+ * @package utils
+ * @author Dr.NP <np@herewe.tech>
+ * @since 10/18/2026
+ */
+
+package utils
+
+import "testing"
+
+func TestFormatAmount(t *testing.T) {
+	cases := []struct {
+		currency string
+		amount   int64
+		want     string
+	}{
+		{"JPY", 1500, "1500"},
+		{"usd", 1234, "12.34"},
+		{"USD", 5, "0.05"},
+		{"KWD", -1005, "-1.005"},
+		{"XXX", 100, "100"},
+	}
+	for _, c := range cases {
+		if got := FormatAmount(c.amount, c.currency); got != c.want {
+			t.Errorf("format %d %s = %s, want %s", c.amount, c.currency, got, c.want)
+		}
+	}
+}
+
+/*
+ * Local variables:
+ * tab-width: 4
+ * c-basic-offset: 4
+ * End:
+ * vim600: sw=4 ts=4 fdm=marker
+ * vim<600: sw=4 ts=4
+ */
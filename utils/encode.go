@@ -16,10 +16,29 @@ package utils
 import (
 	"crypto/aes"
 	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
 	"crypto/sha512"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
 	"fmt"
 )
 
+var (
+	ErrKeyNotFound     = errors.New("Key not found in keyring")
+	ErrSealedTooShort  = errors.New("Sealed data too short or not aligned")
+	ErrActiveKeyAbsent = errors.New("Active key not in keyring")
+)
+
+// Keyring : AES-GCM keys by key ID. Data sealed by the active key, other keys kept for opening data sealed before rotation
+type Keyring struct {
+	active string
+	aeads  map[string]cipher.AEAD
+}
+
 func AESCrypt(input, key []byte) ([]byte, error) {
 	block, err := aes.NewCipher(key)
 	if err != nil {
@@ -42,6 +61,10 @@ func AESDecrypt(input, key []byte) ([]byte, error) {
 		return nil, err
 	}
 
+	if len(input) == 0 || len(input)%aes.BlockSize != 0 {
+		return nil, ErrSealedTooShort
+	}
+
 	iv := make([]byte, aes.BlockSize)
 	stream := cipher.NewCBCDecrypter(block, iv)
 	output := make([]byte, len(input))
@@ -50,6 +73,92 @@ func AESDecrypt(input, key []byte) ([]byte, error) {
 	return output, nil
 }
 
+// NewKeyring creates keyring from keys (16, 24 or 32 bytes each) by key ID
+func NewKeyring(keys map[string]string, active string) (*Keyring, error) {
+	k := &Keyring{
+		active: active,
+		aeads:  make(map[string]cipher.AEAD, len(keys)),
+	}
+	for kid, key := range keys {
+		block, err := aes.NewCipher([]byte(key))
+		if err != nil {
+			return nil, fmt.Errorf("key [%s] : %w", kid, err)
+		}
+
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, fmt.Errorf("key [%s] : %w", kid, err)
+		}
+
+		k.aeads[kid] = aead
+	}
+
+	if k.aeads[active] == nil {
+		return nil, ErrActiveKeyAbsent
+	}
+
+	return k, nil
+}
+
+// Active returns ID of the key sealing new data
+func (k *Keyring) Active() string {
+	return k.active
+}
+
+// Seal encrypts input by the active key, output is nonce followed by ciphertext
+func (k *Keyring) Seal(input, additional []byte) (string, []byte, error) {
+	aead := k.aeads[k.active]
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(input)+aead.Overhead())
+	_, err := rand.Read(nonce)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return k.active, aead.Seal(nonce, nonce, input, additional), nil
+}
+
+// Open decrypts data sealed by key of kid
+func (k *Keyring) Open(kid string, sealed, additional []byte) ([]byte, error) {
+	aead := k.aeads[kid]
+	if aead == nil {
+		return nil, ErrKeyNotFound
+	}
+
+	if len(sealed) < aead.NonceSize() {
+		return nil, ErrSealedTooShort
+	}
+
+	nonce, ciphertext := sealed[:aead.NonceSize()], sealed[aead.NonceSize():]
+
+	return aead.Open(nil, nonce, ciphertext, additional)
+}
+
+// HMACSHA256 returns hex HMAC-SHA256 of input
+func HMACSHA256(input, key []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(input)
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// HOTP returns RFC 4226 one-time password of counter (HMAC-SHA1, dynamic truncation), zero padded to digits.
+// TOTP of RFC 6238 is HOTP of unix time / step
+func HOTP(key []byte, counter uint64, digits int) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], counter)
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	code := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	mod := uint32(1)
+	for i := 0; i < digits; i++ {
+		mod *= 10
+	}
+
+	return fmt.Sprintf("%0*d", digits, code%mod)
+}
+
 func EncryptPassword(plainText, salt, ident string) string {
 	hash := sha512.New()
 	hash.Write([]byte(plainText))
@@ -68,6 +177,13 @@ func EncryptPaymentPassword(plainText, salt, ident string) string {
 	return fmt.Sprintf("%02x", hash.Sum(nil))
 }
 
+// HashSecret : digest of high-entropy secret (invitation codes, API keys) stored for lookup, the secret itself never stored
+func HashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+
+	return hex.EncodeToString(sum[:])
+}
+
 /*
  * Local variables:
  * tab-width: 4
@@ -0,0 +1,211 @@
+/*
+ * Copyright (C) HereweTech, Inc - All Rights Reserved
+ * Unauthorized copying of this file, via any medium is strictly prohibited
+ * Proprietary and confidential
+ */
+
+/**
+ * @file jwk.go
+ * @package utils
+ * @author Dr.NP <np@herewe.tech>
+ * @since 10/18/2026
+ */
+
+package utils
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// Smallest RSA modulus accepted
+const minRSABits = 2048
+
+var (
+	ErrKeyFormat      = errors.New("PEM encoded PKCS#1 or PKCS#8 private key required")
+	ErrKeyUnsupported = errors.New("RSA (2048 bits at least) or Ed25519 key required")
+)
+
+// JWK : public key of JSON Web Key Set (RFC 7517), RSA or Ed25519 (OKP, RFC 8037)
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+type JWKS struct {
+	Keys []*JWK `json:"keys"`
+}
+
+// SigningKeyring : asymmetric JWT keys by key ID. Tokens signed by the active key, others kept for verifying tokens signed before rotation
+type SigningKeyring struct {
+	active string
+	keys   map[string]crypto.Signer
+}
+
+// NewSigningKeyring creates keyring from PEM encoded private keys by key ID, or paths of PEM files
+func NewSigningKeyring(keys map[string]string, active string) (*SigningKeyring, error) {
+	k := &SigningKeyring{
+		active: active,
+		keys:   make(map[string]crypto.Signer, len(keys)),
+	}
+	for kid, key := range keys {
+		signer, err := parseSigningKey(key)
+		if err != nil {
+			return nil, fmt.Errorf("key [%s] : %w", kid, err)
+		}
+
+		k.keys[kid] = signer
+	}
+
+	if k.keys[active] == nil {
+		return nil, ErrActiveKeyAbsent
+	}
+
+	return k, nil
+}
+
+// Active returns ID of the key signing new tokens
+func (k *SigningKeyring) Active() string {
+	return k.active
+}
+
+// Sign signs token by the active key, kid set in header
+func (k *SigningKeyring) Sign(claims jwt.Claims) (string, error) {
+	signer := k.keys[k.active]
+	token := jwt.NewWithClaims(signingMethod(signer), claims)
+	token.Header["kid"] = k.active
+
+	return token.SignedString(signer)
+}
+
+// Verifier returns public key of kid for verifying token, the signing method must match the key
+func (k *SigningKeyring) Verifier(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	signer := k.keys[kid]
+	if signer == nil {
+		return nil, ErrKeyNotFound
+	}
+
+	if token.Method != signingMethod(signer) {
+		return nil, fmt.Errorf("unexpected signing method %s of key [%s]", token.Method.Alg(), kid)
+	}
+
+	return signer.Public(), nil
+}
+
+// JWKS returns public keys of keyring, in order of key ID
+func (k *SigningKeyring) JWKS() *JWKS {
+	kids := make([]string, 0, len(k.keys))
+	for kid := range k.keys {
+		kids = append(kids, kid)
+	}
+
+	sort.Strings(kids)
+	set := &JWKS{
+		Keys: make([]*JWK, 0, len(kids)),
+	}
+	for _, kid := range kids {
+		jwk := &JWK{
+			Kid: kid,
+			Use: "sig",
+			Alg: signingMethod(k.keys[kid]).Alg(),
+		}
+
+		switch pub := k.keys[kid].Public().(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		}
+
+		set.Keys = append(set.Keys, jwk)
+	}
+
+	return set
+}
+
+func signingMethod(signer crypto.Signer) jwt.SigningMethod {
+	if _, ok := signer.(ed25519.PrivateKey); ok {
+		return jwt.SigningMethodEdDSA
+	}
+
+	return jwt.SigningMethodRS256
+}
+
+func parseSigningKey(key string) (crypto.Signer, error) {
+	data := []byte(key)
+	if !strings.HasPrefix(strings.TrimSpace(key), "-----BEGIN") {
+		// Path of PEM file
+		var err error
+		data, err = os.ReadFile(key)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, ErrKeyFormat
+	}
+
+	var (
+		parsed interface{}
+		err    error
+	)
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	default:
+		return nil, ErrKeyFormat
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	switch signer := parsed.(type) {
+	case *rsa.PrivateKey:
+		if signer.N.BitLen() < minRSABits {
+			return nil, ErrKeyUnsupported
+		}
+
+		return signer, nil
+	case ed25519.PrivateKey:
+		return signer, nil
+	}
+
+	return nil, ErrKeyUnsupported
+}
+
+/*
+ * Local variables:
+ * tab-width: 4
+ * c-basic-offset: 4
+ * End:
+ * vim600: sw=4 ts=4 fdm=marker
+ * vim<600: sw=4 ts=4
+ */
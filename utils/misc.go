@@ -14,6 +14,7 @@
 package utils
 
 import (
+	crand "crypto/rand"
 	"math/rand"
 	"strconv"
 	"strings"
@@ -99,6 +100,28 @@ func RandomString(length int) string {
 	return string(b)
 }
 
+// SecureRandomString generates random string from crypto source, for secrets and tokens
+func SecureRandomString(length int) string {
+	b := make([]byte, 0, length)
+	buf := make([]byte, length)
+	for len(b) < length {
+		crand.Read(buf)
+		for _, v := range buf {
+			// Drop bytes over the largest multiple of len(letterBytes), avoid modulo bias
+			if int(v) >= 256-256%len(letterBytes) {
+				continue
+			}
+
+			b = append(b, letterBytes[int(v)%len(letterBytes)])
+			if len(b) == length {
+				break
+			}
+		}
+	}
+
+	return string(b)
+}
+
 /*
  * Local variables:
  * tab-width: 4